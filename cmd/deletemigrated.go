@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/migration"
+	"github.com/spf13/cobra"
+)
+
+func NewDeleteMigratedCommand(cfg *config.GlobalConfig) *cobra.Command {
+	var deleteConfig config.DeleteMigratedConfig
+	cmd := &cobra.Command{
+		Use:   "delete-migrated",
+		Short: "Close migrated GitHub pull requests and delete their branches to migrate merge requests again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDeleteMigrated(*cfg, deleteConfig)
+		},
+	}
+
+	// Delete migrated command specific flags
+	cmd.Flags().StringVar(&deleteConfig.Subdirectory, "subdirectory", "", "Subdirectory the GitLab project was imported into")
+	cmd.Flags().StringVar(&deleteConfig.FailedTitleTag, "failed-title-tag", "[Failed]", "Tag added to titles of closed PRs so that the next migration does not treat them as migrated")
+	cmd.Flags().StringVar(&deleteConfig.StateFile, "state-file", "", "Migration state file whose entries of the closed PRs are removed (default .gitlab-2-github/state/<owner>_<repo>_<project>.json)")
+	cmd.Flags().BoolVar(&deleteConfig.Confirm, "confirm", false, "Actually close the PRs and delete the branches instead of only listing them")
+
+	return cmd
+}
+
+func runDeleteMigrated(cfg config.GlobalConfig, deleteConfig config.DeleteMigratedConfig) error {
+	problems := append(cfg.ValidateGitHubAPI(), deleteConfig.Validate()...)
+	if err := config.NewValidationError(problems); err != nil {
+		return err
+	}
+
+	log := logger.With("github_repo", fmt.Sprintf("%s/%s", cfg.GitHubOwner, cfg.GitHubRepo))
+	ctx := github.NewRetryContext(logger.NewContext(context.Background(), log), newRetryConfig(cfg))
+	githubClient := newGitHubClient(cfg)
+
+	// --resumeで移行済みとしてスキップされないよう、closeしたPRのMRの移行状態も削除する
+	// デフォルトのパスはGitLabのプロジェクトから決まるため、どちらも無い場合は移行状態を扱わない
+	stateFile := deleteConfig.StateFile
+	if stateFile == "" && cfg.GitLabProject != "" {
+		stateFile = migration.DefaultStateFile(cfg.GitLabProject, cfg.GitHubOwner, cfg.GitHubRepo)
+	}
+	var state *migration.StateStore
+	if stateFile != "" {
+		state = migration.NewStateStore(stateFile)
+		if err := state.Load(); err != nil {
+			return err
+		}
+	} else {
+		log.Warn("Migration state is not reset, pass --gitlab-project or --state-file to reset it")
+	}
+
+	opts := &migration.MigrationOptions{
+		Subdirectory:   deleteConfig.Subdirectory,
+		FailedTitleTag: deleteConfig.FailedTitleTag,
+		State:          state,
+	}
+	result, err := migration.DeleteMigrated(ctx, githubClient, cfg, opts, deleteConfig.Confirm)
+	if err != nil {
+		return fmt.Errorf("failed to delete migrated PRs: %w", err)
+	}
+	if !deleteConfig.Confirm {
+		log.Info("Re-run with --confirm to close these PRs and delete these branches", "pull_requests", len(result.PullRequests), "branches", len(result.Branches), "states", len(result.MergeRequests))
+		return nil
+	}
+	log.Info("Deleted migrated PRs and branches", "pull_requests", len(result.PullRequests), "branches", len(result.Branches), "states", len(result.MergeRequests))
+	return nil
+}
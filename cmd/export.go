@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	gitlabpkg "github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+func NewExportCommand(cfg *config.GlobalConfig) *cobra.Command {
+	var exportConfig config.ExportConfig
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export GitLab merge requests and issues to a local directory for an offline migration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(*cfg, exportConfig)
+		},
+	}
+
+	// Export command specific flags
+	cmd.Flags().StringVar(&exportConfig.OutputDir, "output-dir", "./export", "Directory to write the exported GitLab data")
+
+	return cmd
+}
+
+func runExport(cfg config.GlobalConfig, exportConfig config.ExportConfig) error {
+	if err := config.NewValidationError(append(cfg.ValidateGitLab(), exportConfig.Validate()...)); err != nil {
+		return err
+	}
+
+	gitlabClient, err := gitlabpkg.NewClient(cfg.GitLabToken, cfg.GitLabURL, cfg.HTTPTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	// migrateと同様に、実行IDやプロジェクトをgitのコマンドを含むすべてのログに付与する
+	runID, err := newRunID()
+	if err != nil {
+		return err
+	}
+	log := logger.With("run_id", runID, "gitlab_project", cfg.GitLabProject)
+
+	// 割り込まれた場合は、実行中のGitLab APIのリクエストもキャンセルする
+	ctx, stop := signal.NotifyContext(logger.NewContext(context.Background(), log), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Info("Export started...", "output_dir", exportConfig.OutputDir)
+
+	var exported int
+	for page := 1; ; page++ {
+		mrs, err := gitlabpkg.GetMergeRequests(ctx, gitlabClient, cfg.GitLabProject, page)
+		if err != nil {
+			return fmt.Errorf("failed to get merge requests: %w", err)
+		}
+		if len(mrs) == 0 {
+			break
+		}
+		for _, mr := range mrs {
+			if err := gitlabpkg.ExportMergeRequest(ctx, gitlabClient, cfg.GitLabProject, int(mr.IID), exportConfig.OutputDir); err != nil {
+				return fmt.Errorf("failed to export MR %d: %w", mr.IID, err)
+			}
+			exported++
+		}
+		log.Info("Progress", "exported", exported, "page", page)
+	}
+
+	if err := gitlabpkg.ExportIssues(ctx, gitlabClient, cfg.GitLabProject, exportConfig.OutputDir); err != nil {
+		return fmt.Errorf("failed to export issues: %w", err)
+	}
+
+	// migrate --from-exportでGitLabにアクセスせずにミラーリングやMRのブランチ作成ができるよう、リポジトリもbundleとして書き出す
+	if err := git.ExportBundle(ctx, cfg.GitLabURL, cfg.GitLabProject, cfg.GitLabToken, exportConfig.OutputDir); err != nil {
+		return fmt.Errorf("failed to export repository: %w", err)
+	}
+
+	log.Info("Export completed successfully!", "merge_requests", exported)
+	return nil
+}
@@ -2,17 +2,28 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/krrrr38/gitlab-2-github/pkg/config"
 	"github.com/krrrr38/gitlab-2-github/pkg/git"
 	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	gitlabpkg "github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/labelmap"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
 	"github.com/krrrr38/gitlab-2-github/pkg/migration"
+	"github.com/krrrr38/gitlab-2-github/pkg/teammap"
+	"github.com/krrrr38/gitlab-2-github/pkg/usermap"
+	"github.com/krrrr38/gitlab-2-github/pkg/usertokens"
 	"github.com/spf13/cobra"
-	"github.com/xanzy/go-gitlab"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func NewMigrateCommand(cfg *config.GlobalConfig) *cobra.Command {
@@ -29,20 +40,97 @@ func NewMigrateCommand(cfg *config.GlobalConfig) *cobra.Command {
 	cmd.Flags().IntSliceVar(&migrateConfig.FilterMergeReqIDs, "mr-ids", nil, "Filter specific merge request IDs to migrate")
 	cmd.Flags().IntVar(&migrateConfig.ContinueFromMRID, "continue-from", 0, "Continue migration from the specified MR ID")
 	cmd.Flags().IntVar(&migrateConfig.MaxDiscussions, "max-discussions", 0, "Max migration discussion count per merge request")
+	cmd.Flags().IntVar(&migrateConfig.CommentConcurrency, "comment-concurrency", 1, "Number of discussions of a merge request created concurrently (replies stay ordered after their first comment)")
+	cmd.Flags().StringVar(&migrateConfig.DiscussionOrder, "discussion-order", gitlabpkg.DiscussionOrderOldest, "Which discussions to keep when --max-discussions is exceeded (oldest, newest)")
+	cmd.Flags().BoolVar(&migrateConfig.ForceMirror, "force-mirror", false, "Allow mirroring into an existing non-empty GitHub repository not created by this tool")
+	cmd.Flags().BoolVar(&migrateConfig.AppendMode, "append-mode", false, "Skip mirroring and migrate merge requests into an existing GitHub repository that already contains the code")
+	cmd.Flags().StringVar(&migrateConfig.RenameDefaultBranch, "rename-default-branch", "", "Push the GitLab default branch under this name, such as main, and make it the default branch of the GitHub repository")
+	cmd.Flags().BoolVar(&migrateConfig.AssumeRepoExists, "assume-repo-exists", false, "Skip checking and creating the GitHub repository, which must be created beforehand (e.g. by IaC)")
+	cmd.Flags().StringVar(&migrateConfig.SystemCommentPrefix, "system-comment-prefix", "[system]", "Prefix of migrated GitLab system comments (empty to omit)")
+	cmd.Flags().BoolVar(&migrateConfig.SummaryComment, "summary-comment", false, "Post and pin a summary comment of the original merge request on each migrated PR")
+	cmd.Flags().StringVar(&migrateConfig.FromExport, "from-export", "", "Read GitLab merge request data from a directory written by the export command instead of the GitLab API")
+	cmd.Flags().IntVar(&migrateConfig.RefPollAttempts, "ref-poll-attempts", 5, "Number of checks that pushed branches are visible on GitHub before creating a PR (0 to disable)")
+	cmd.Flags().DurationVar(&migrateConfig.RefPollInterval, "ref-poll-interval", 1*time.Second, "Interval between branch visibility checks")
+	cmd.Flags().StringVar(&migrateConfig.Subdirectory, "subdirectory", "", "Import the GitLab project into this subdirectory of the GitHub repository to consolidate several projects into a monorepo")
+	cmd.Flags().StringVar(&migrateConfig.ReportFile, "report-file", "", "Write a JSON report of migrated merge requests and failed discussions to this file")
+	cmd.Flags().StringVar(&migrateConfig.CSVReportFile, "csv-report", "", "Write the MR to PR mapping of the run as CSV to this file, or as TSV if it ends with .tsv")
+	cmd.Flags().StringVar(&migrateConfig.ClosedTitleTag, "closed-title-tag", "[Closed]", "Tag added to titles of PRs migrated from closed merge requests (empty to omit)")
+	cmd.Flags().StringVar(&migrateConfig.FailedTitleTag, "failed-title-tag", "[Failed]", "Tag added to titles of PRs closed after a failed migration")
+	cmd.Flags().StringVar(&migrateConfig.StateFile, "state-file", "", "JSON file saving the migration state of each merge request (default .gitlab-2-github/state/<owner>_<repo>_<project>.json)")
+	cmd.Flags().StringVar(&migrateConfig.NoteMapFile, "note-map-file", "", "Append the GitHub comment URL of each migrated GitLab note to this CSV file (gitlab_mr_iid,gitlab_note_id,github_comment_url)")
+	cmd.Flags().StringVar(&migrateConfig.DebugDumpDir, "debug-dump-dir", "", "Write the GitLab data and created branches of each failed merge request (every merge request with --log-level debug) to this directory")
+	cmd.Flags().DurationVar(&migrateConfig.Timeout, "timeout", 0, "Abort the whole migration after this duration, saving the state so that it can be resumed (0 disables)")
+	cmd.Flags().IntVar(&migrateConfig.MRAttempts, "mr-attempts", 1, "Number of attempts to migrate each merge request from scratch on transient failures, closing the partially created PR before retrying")
+	cmd.Flags().BoolVar(&migrateConfig.Resume, "resume", false, "Resume an interrupted migration from the state file, skipping succeeded and retrying failed merge requests")
+	cmd.Flags().BoolVar(&migrateConfig.ResumeCommentsOnly, "resume-comments-only", false, "Only migrate the comments missing from PRs already created for merge requests, without mirroring or creating branches and PRs")
+	cmd.Flags().BoolVar(&migrateConfig.OnlyFailed, "only-failed", false, "Retry only the merge requests recorded as failed in the state file")
+	cmd.Flags().StringSliceVar(&migrateConfig.TeamReviewers, "team-reviewers", nil, "Team slugs of the GitHub organization requested as reviewers on PRs of opened merge requests")
+	cmd.Flags().BoolVar(&migrateConfig.IncludeSystemComments, "include-system-comments", false, "Migrate all GitLab system comments instead of skipping ones considered noise")
+	cmd.Flags().BoolVar(&migrateConfig.MRSystemTimeline, "include-mr-system-timeline", false, "Collect the GitLab system comments not migrated into one collapsible timeline comment per MR")
+	cmd.Flags().BoolVar(&migrateConfig.MigrateApprovalRules, "migrate-approval-rules", false, "Reflect the GitLab approval requirement as branch protection of the GitHub default branch")
+	cmd.Flags().BoolVar(&migrateConfig.MigrateCodeowners, "migrate-codeowners", false, "Commit a CODEOWNERS converted from the GitLab CODEOWNERS or approval rules, mapping usernames with --user-map")
+	cmd.Flags().StringVar(&migrateConfig.LabelMapFile, "label-map", "", "CSV file mapping GitLab labels to GitHub labels (gitlab_label,github_label); unmapped labels are kept")
+	cmd.Flags().StringVar(&migrateConfig.TeamMapFile, "team-map", "", "CSV file mapping GitLab groups to GitHub team slugs (gitlab_group,github_team); mentions of mapped groups become team mentions")
+	cmd.Flags().StringVar(&migrateConfig.UserTokensFile, "user-tokens", "", "CSV file of GitHub logins and their token files (github_login,token_file); comments of users mapped with --user-map are created as those users")
+	cmd.Flags().BoolVar(&migrateConfig.ArchiveSourceOnSuccess, "archive-source-on-success", false, "Archive the GitLab project after every merge request and discussion is migrated successfully")
+	cmd.Flags().StringVar(&migrateConfig.ConfirmArchiveSource, "confirm-archive-source", "", "GitLab project path confirming --archive-source-on-success")
+	cmd.Flags().BoolVar(&migrateConfig.ReallyMerge, "really-merge", false, "Merge PRs of merged merge requests so that GitHub shows them as merged, closing them only when the merge fails")
+	cmd.Flags().StringVar(&migrateConfig.NoDiffCommitMessage, "no-diff-commit-message", migration.DefaultNoDiffCommitMessage, "Message of the commit created on the PR branch of a merge request without diff")
+	cmd.Flags().BoolVar(&migrateConfig.NoDiffNote, "no-diff-note", false, "Commit a MIGRATION_NOTE.md explaining the missing diff to the PR branch of a merge request without diff")
+	cmd.Flags().BoolVar(&migrateConfig.PipelineStatus, "pipeline-status", false, "Create a commit status on the PR head commit from the head pipeline of each merge request, linking to the GitLab pipeline")
+	cmd.Flags().StringVar(&migrateConfig.PipelineStatusContext, "pipeline-status-context", migration.DefaultPipelineStatusContext, "Context of the commit statuses created by --pipeline-status")
+	cmd.Flags().BoolVar(&migrateConfig.LeavePRsOpen, "leave-prs-open", false, "Leave migrated PRs open with their state label instead of closing them, so they can be reviewed and closed by hand")
+	cmd.Flags().BoolVar(&migrateConfig.GraphQLReviewThreads, "graphql-review-threads", false, "Create diff discussions as GitHub review threads with the GraphQL API and resolve them like on GitLab, falling back to REST on failure")
+	cmd.Flags().StringArrayVar(&migrateConfig.CommentFilters, "comment-filter", nil, "Regex of GitLab notes not to migrate, matched against the body and author username (prefix with body: or author: to match only one)")
+	cmd.Flags().StringSliceVar(&migrateConfig.MRStates, "mr-states", migration.DefaultMRStates, "GitLab merge request states to migrate (opened, closed, merged, locked)")
+	cmd.Flags().StringSliceVar(&migrateConfig.CloseStates, "close-states", migration.DefaultCloseStates, "GitLab merge request states whose migrated PRs are closed")
+	cmd.Flags().StringVar(&migrateConfig.CommentSince, "comment-since", "", "Only migrate GitLab notes created at or after this RFC3339 timestamp")
+	cmd.Flags().StringVar(&migrateConfig.OutOfHunkStrategy, "out-of-hunk-strategy", migration.OutOfHunkIssueComment, "How to migrate diff comments on lines outside the PR diff (issue-comment, nearest-line, skip)")
+	cmd.Flags().StringVar(&migrateConfig.QuickActions, "quick-actions", migration.QuickActionsKeep, "How to handle GitLab quick actions in descriptions and comments (keep, strip, translate)")
+	cmd.Flags().StringSliceVar(&migrateConfig.MarkdownFixes, "markdown-fix", migration.MarkdownFixNames(), "Comma separated markdown fixes applied to descriptions and comments (toc, tasklist, blockquote, inline-diff, uploads, or none)")
+	cmd.Flags().StringVar(&migrateConfig.Snippets, "snippets", migration.SnippetsModeNone, "How to migrate project snippets (gist, repo, none), listed in a \"Migrated GitLab snippets\" issue that also marks them as migrated on re-runs")
 
 	return cmd
 }
 
 func runMigration(cfg config.GlobalConfig, migrateConfig config.MigrateConfig) error {
+	// ネットワークアクセスの前に、不足している設定をまとめて報告する
+	problems := append(cfg.ValidateGitLab(), cfg.ValidateGitHub()...)
+	problems = append(problems, migrateConfig.Validate()...)
+	problems = append(problems, migrateConfig.ValidateArchiveSource(cfg.GitLabProject)...)
+	if err := config.NewValidationError(problems); err != nil {
+		return err
+	}
+
+	// テキストの切り詰め方法を設定
+	cfg.Limits.Truncate.AtBoundary = cfg.SmartTruncate
+	cfg.Limits.Truncate.CloseCodeFence = cfg.SmartTruncate
+
 	// Initialize GitLab client
-	gitlabClient, err := gitlab.NewClient(cfg.GitLabToken, gitlab.WithBaseURL(cfg.GitLabURL))
+	gitlabClient, err := gitlabpkg.NewClient(cfg.GitLabToken, cfg.GitLabURL, cfg.HTTPTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to create GitLab client: %w", err)
 	}
 
+	// 複数プロジェクトの移行ログを区別できるよう、実行IDやプロジェクトをすべてのログに付与する
+	runID, err := newRunID()
+	if err != nil {
+		return err
+	}
+	log := logger.With(
+		"run_id", runID,
+		"gitlab_project", cfg.GitLabProject,
+		"github_repo", fmt.Sprintf("%s/%s", cfg.GitHubOwner, cfg.GitHubRepo))
+
 	// Initialize GitHub client with retry capability
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(github.NewRetryContext(logger.NewContext(context.Background(), log), newRetryConfig(cfg)))
 	defer cancel()
+	// 移行全体のタイムアウト。MRごとの移行状態は保存されるため、--resumeで再開できる
+	if migrateConfig.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, migrateConfig.Timeout)
+		defer cancelTimeout()
+	}
 
 	// シグナルハンドリングのセットアップ（CTRL+Cなどの割り込みを処理）
 	signalChan := make(chan os.Signal, 1)
@@ -51,7 +139,7 @@ func runMigration(cfg config.GlobalConfig, migrateConfig config.MigrateConfig) e
 	// シグナルハンドラ
 	go func() {
 		<-signalChan
-		logger.Info("Received interrupt signal, shutting down...")
+		log.Info("Received interrupt signal, shutting down...")
 
 		// コンテキストをキャンセルして実行中の処理に停止を通知
 		cancel()
@@ -61,33 +149,366 @@ func runMigration(cfg config.GlobalConfig, migrateConfig config.MigrateConfig) e
 
 	// リポジトリ設定を取得してミラーリングが必要かどうかを判断
 	g := git.NewGit(cfg.WorkingDir, cfg.GitHubOwner, cfg.GitHubRepo, cfg.GitLabURL, cfg.GitLabProject)
+	g.SetLogger(log)
+	g.SetContext(ctx)
+	g.SetSubdirectory(migrateConfig.Subdirectory)
+	g.SetAppendMode(migrateConfig.AppendMode)
+	g.SetDefaultBranch(migrateConfig.RenameDefaultBranch)
+	g.SetIdentity(cfg.GitAuthorName, cfg.GitAuthorEmail)
+	// export済みのデータから移行する場合は、リポジトリもexportされたbundleから読み込む
+	if migrateConfig.FromExport != "" {
+		bundlePath, err := filepath.Abs(filepath.Join(migrateConfig.FromExport, git.BundleFile))
+		if err != nil {
+			return fmt.Errorf("failed to resolve git bundle path: %w", err)
+		}
+		if _, err := os.Stat(bundlePath); err != nil {
+			return fmt.Errorf("export has no git bundle, re-run the export command: %w", err)
+		}
+		g.SetGitLabBundle(bundlePath)
+	}
 
-	var githubClient *github.Client
-	if cfg.GitHubApiToken != "" {
-		githubClient = github.NewClientByPAT(cfg.GitHubApiToken)
-	} else if cfg.GitHubAppID > 0 && cfg.GitHubAppInstallationID > 0 && cfg.GitHubAppPrivateKey != "" {
-		githubClient = github.NewClientByApp(cfg.GitHubAppID, cfg.GitHubAppInstallationID, cfg.GitHubAppPrivateKey)
-	} else {
-		logger.Fatal("GitHub token or GitHub App settings are required")
+	githubClient := newGitHubClient(cfg)
+	githubClient.SetQuietNotifications(cfg.QuietNotifications)
+	if cfg.FastComments {
+		githubClient.SetContentInterval(0)
 	}
 
-	// 1. リポジトリをミラーリング
-	logger.Info("Migration started...")
-	if err := migration.MirrorRepository(g, cfg, githubClient); err != nil {
-		return fmt.Errorf("failed to mirror repository: %w", err)
+	userMap, err := usermap.Load(cfg.UserMapFile)
+	if err != nil {
+		return err
+	}
+	labelMap, err := labelmap.Load(migrateConfig.LabelMapFile)
+	if err != nil {
+		return err
+	}
+	teamMap, err := teammap.Load(migrateConfig.TeamMapFile)
+	if err != nil {
+		return err
+	}
+	authorClients, err := newAuthorClients(ctx, cfg, migrateConfig.UserTokensFile)
+	if err != nil {
+		return err
+	}
+	commentFilters, err := migration.ParseCommentFilters(migrateConfig.CommentFilters)
+	if err != nil {
+		return err
+	}
+	commentSince, err := parseCommentSince(migrateConfig.CommentSince)
+	if err != nil {
+		return err
+	}
+	markdownFixes, err := migration.ParseMarkdownFixes(migrateConfig.MarkdownFixes)
+	if err != nil {
+		return err
+	}
+
+	// 中断した移行を --resume で再開できるよう、移行状態は常に保存する
+	stateFile := migrateConfig.StateFile
+	if stateFile == "" {
+		stateFile = migration.DefaultStateFile(cfg.GitLabProject, cfg.GitHubOwner, cfg.GitHubRepo)
+	}
+	state := migration.NewStateStore(stateFile)
+	if err := state.Load(); err != nil {
+		return err
+	}
+	if migrateConfig.Resume {
+		succeeded, failed := state.Counts()
+		log.Info("Resuming migration", "skipping", succeeded, "retrying", failed, "state_file", stateFile)
+	}
+	// 前回までに失敗したMRのみを対象とする (成功した場合は移行状態が成功に更新される)
+	if migrateConfig.OnlyFailed {
+		failed := state.FailedMergeRequests()
+		if len(failed) == 0 {
+			log.Info("No failed merge requests to retry", "state_file", stateFile)
+			return nil
+		}
+		log.Info("Retrying failed merge requests", "mr_ids", failed, "state_file", stateFile)
+		migrateConfig.FilterMergeReqIDs = failed
+	}
+
+	// 再実行時にも以前の対応が残るよう、note mapには追記する
+	var noteMap *migration.NoteMap
+	if migrateConfig.NoteMapFile != "" {
+		noteMap, err = migration.OpenNoteMap(migrateConfig.NoteMapFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := noteMap.Close(); err != nil {
+				log.Warn("Failed to close note map", "error", err)
+			}
+		}()
 	}
 
-	// 2. マージリクエストの移行（リクエストされている場合）
 	// マイグレーションオプションを設定
 	migrationOpts := &migration.MigrationOptions{
-		ContinueFromID:    migrateConfig.ContinueFromMRID,
-		FilterMergeReqIDs: migrateConfig.FilterMergeReqIDs,
-		MaxDiscussions:    migrateConfig.MaxDiscussions,
+		ContinueFromID:        migrateConfig.ContinueFromMRID,
+		FilterMergeReqIDs:     migrateConfig.FilterMergeReqIDs,
+		MaxDiscussions:        migrateConfig.MaxDiscussions,
+		DiscussionOrder:       migrateConfig.DiscussionOrder,
+		CommentConcurrency:    migrateConfig.CommentConcurrency,
+		Snippets:              migrateConfig.Snippets,
+		ForceMirror:           migrateConfig.ForceMirror,
+		AppendMode:            migrateConfig.AppendMode,
+		RenameDefaultBranch:   migrateConfig.RenameDefaultBranch,
+		AssumeRepoExists:      migrateConfig.AssumeRepoExists,
+		SystemCommentPrefix:   migrateConfig.SystemCommentPrefix,
+		SummaryComment:        migrateConfig.SummaryComment,
+		RefPollAttempts:       migrateConfig.RefPollAttempts,
+		RefPollInterval:       migrateConfig.RefPollInterval,
+		Subdirectory:          migrateConfig.Subdirectory,
+		ClosedTitleTag:        migrateConfig.ClosedTitleTag,
+		FailedTitleTag:        migrateConfig.FailedTitleTag,
+		DebugDumpDir:          migrateConfig.DebugDumpDir,
+		MRAttempts:            migrateConfig.MRAttempts,
+		State:                 state,
+		NoteMap:               noteMap,
+		Resume:                migrateConfig.Resume,
+		TeamReviewers:         migrateConfig.TeamReviewers,
+		IncludeSystemComments: migrateConfig.IncludeSystemComments,
+		MRSystemTimeline:      migrateConfig.MRSystemTimeline,
+		MigrateApprovalRules:  migrateConfig.MigrateApprovalRules,
+		MigrateCodeowners:     migrateConfig.MigrateCodeowners,
+		ReallyMerge:           migrateConfig.ReallyMerge,
+		NoDiffCommitMessage:   migrateConfig.NoDiffCommitMessage,
+		NoDiffNote:            migrateConfig.NoDiffNote,
+		PipelineStatus:        migrateConfig.PipelineStatus,
+		PipelineStatusContext: migrateConfig.PipelineStatusContext,
+		LeavePRsOpen:          migrateConfig.LeavePRsOpen,
+		GraphQLReviewThreads:  migrateConfig.GraphQLReviewThreads,
+		OutOfHunkStrategy:     migrateConfig.OutOfHunkStrategy,
+		QuickActions:          migrateConfig.QuickActions,
+		MarkdownFixes:         markdownFixes,
+		CommentSince:          commentSince,
+		MRStates:              migrateConfig.MRStates,
+		CloseStates:           migrateConfig.CloseStates,
+		CommentFilters:        commentFilters,
+		UserMap:               userMap,
+		AuthorClients:         authorClients,
+		LabelMap:              labelMap,
+		TeamMap:               teamMap,
+	}
+
+	// 移行を始める前にreviewerとするteamの存在を確認する
+	if err := migration.ValidateTeamReviewers(ctx, cfg, githubClient, migrationOpts); err != nil {
+		return err
+	}
+	migration.WarnMissingMappedTeams(ctx, cfg, githubClient, migrationOpts)
+
+	// scopeが不足したtokenで移行の途中に失敗しないよう、事前に確認して警告する
+	warnMissingTokenScopes(ctx, gitlabClient, githubClient, migrateConfig)
+
+	// アーカイブされたプロジェクトは移行の必要がないことが多いため、意図した移行か確認できるよう警告する
+	// export済みのデータから移行する場合はGitLabにアクセスしない
+	if migrateConfig.FromExport == "" {
+		if archived, err := gitlabpkg.IsProjectArchived(ctx, gitlabClient, cfg.GitLabProject); err != nil {
+			log.Warn("Failed to check if the GitLab project is archived", "error", err)
+		} else if archived {
+			log.Warn("GitLab project is archived and read-only, make sure it should be migrated")
+		}
+	}
+
+	// export済みのディレクトリが指定されている場合は、GitLab APIではなくそこから読み込む
+	var source migration.GitLabSource = gitlabpkg.NewAPISource(gitlabClient, cfg.GitLabProject)
+	if migrateConfig.FromExport != "" {
+		fileSource, err := gitlabpkg.NewFileSource(migrateConfig.FromExport)
+		if err != nil {
+			return err
+		}
+		source = fileSource
+	}
+
+	// --resume-comments-only の場合は、ブランチやPRを作成せず、作成済みのPRに不足しているコメントのみを移行する
+	if migrateConfig.ResumeCommentsOnly {
+		report, err := migration.BackfillComments(ctx, source, githubClient, cfg, migrationOpts)
+		writeReport(log, migrateConfig, report)
+		if err != nil {
+			return fmt.Errorf("failed to backfill comments: %w", err)
+		}
+		return nil
+	}
+
+	// 1. リポジトリをミラーリング
+	log.Info("Migration started...")
+	phaseStart := time.Now()
+	if err := migration.MirrorRepository(ctx, g, cfg, githubClient, migrationOpts); err != nil {
+		return fmt.Errorf("failed to mirror repository: %w", err)
+	}
+	mirrorDuration := time.Since(phaseStart)
+
+	// 2. snippetの移行（リクエストされている場合）
+	phaseStart = time.Now()
+	if err := migration.MigrateSnippets(ctx, gitlabClient, githubClient, g, cfg, migrationOpts); err != nil {
+		return fmt.Errorf("failed to migrate snippets: %w", err)
+	}
+	snippetsDuration := time.Since(phaseStart)
+
+	// 3. マージリクエストの移行
+	report, err := migration.MigrateMergeRequests(ctx, source, githubClient, cfg, migrationOpts)
+	if report != nil {
+		report.AddPhaseDuration(migration.PhaseMirror, mirrorDuration)
+		report.AddPhaseDuration(migration.PhaseSnippets, snippetsDuration)
 	}
-	if err := migration.MigrateMergeRequests(ctx, gitlabClient, githubClient, cfg, migrationOpts); err != nil {
+	// 失敗した場合もそれまでの結果を確認できるよう、reportは書き出しておく
+	writeReport(log, migrateConfig, report)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && report != nil {
+			migrated, failed := report.Counts()
+			log.Warn("Migration timed out, re-run with --resume to continue",
+				"timeout", migrateConfig.Timeout,
+				"migrated", migrated,
+				"failed", failed,
+				"skipped", report.SkippedMergeRequests)
+		}
 		return fmt.Errorf("failed to migrate merge requests: %w", err)
 	}
 
-	logger.Info("Migration completed successfully!")
+	// 4. CODEOWNERSの移行（リクエストされている場合）
+	// 承認設定の移行でcode ownerのレビューを要求できるよう、先にcommitしておく
+	if err := migration.MigrateCodeowners(ctx, gitlabClient, g, cfg, migrationOpts); err != nil {
+		return fmt.Errorf("failed to migrate CODEOWNERS: %w", err)
+	}
+
+	// 5. 承認設定の移行（リクエストされている場合）
+	// 移行中のpushがbranch protectionに妨げられないよう、最後に行う
+	if err := migration.MigrateApprovalRules(ctx, gitlabClient, githubClient, g, cfg, migrationOpts); err != nil {
+		return fmt.Errorf("failed to migrate approval rules: %w", err)
+	}
+
+	// 6. GitLabプロジェクトのarchive（リクエストされている場合）
+	// 一部でも移行に失敗している場合は、GitLab側で確認や再実行ができるようarchiveしない
+	if migrateConfig.ArchiveSourceOnSuccess {
+		if report.Incomplete() {
+			log.Warn("Skipping archiving the GitLab project because some merge requests or discussions failed to migrate")
+		} else {
+			log.Warn("Archiving the GitLab project")
+			if err := gitlabpkg.ArchiveProject(ctx, gitlabClient, cfg.GitLabProject); err != nil {
+				return err
+			}
+			log.Info("Archived the GitLab project")
+		}
+	}
+
+	log.Info("Migration completed successfully!", report.PhaseLogFields()...)
 	return nil
 }
+
+// writeReport writes the migration report if --report-file or --csv-report is given
+func writeReport(log *logger.Logger, migrateConfig config.MigrateConfig, report *migration.Report) {
+	if report == nil {
+		return
+	}
+	if path := migrateConfig.ReportFile; path != "" {
+		if err := report.WriteFile(path); err != nil {
+			log.Warn("Failed to write migration report", "error", err)
+		} else {
+			log.Info("Wrote migration report", "path", path)
+		}
+	}
+	if path := migrateConfig.CSVReportFile; path != "" {
+		if err := report.WriteCSV(path); err != nil {
+			log.Warn("Failed to write CSV report", "error", err)
+		} else {
+			log.Info("Wrote CSV report", "path", path)
+		}
+	}
+}
+
+// warnMissingTokenScopes warns if the GitLab or GitHub token lacks scopes required by the migration
+func warnMissingTokenScopes(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, migrateConfig config.MigrateConfig) {
+	log := logger.FromContext(ctx)
+
+	// export済みのデータから移行する場合はGitLabのtokenを利用しない
+	if migrateConfig.FromExport == "" {
+		gitlabScopes := []string{"read_api", "read_repository"}
+		if migrateConfig.ArchiveSourceOnSuccess {
+			gitlabScopes = append(gitlabScopes, "api")
+		}
+		if missing, err := gitlabpkg.MissingTokenScopes(ctx, gitlabClient, gitlabScopes); err != nil {
+			log.Warn("Failed to check GitLab token scopes", "error", err)
+		} else if len(missing) > 0 {
+			log.Warn("GitLab token is missing scopes required for the migration", "scopes", strings.Join(missing, ","))
+		}
+	}
+
+	githubScopes := []string{"repo"}
+	if migrateConfig.Snippets == migration.SnippetsModeGist {
+		githubScopes = append(githubScopes, "gist")
+	}
+	if len(migrateConfig.TeamReviewers) > 0 {
+		githubScopes = append(githubScopes, "read:org")
+	}
+	if missing, ok, err := githubClient.MissingTokenScopes(ctx, githubScopes); err != nil {
+		log.Warn("Failed to check GitHub token scopes", "error", err)
+	} else if ok && len(missing) > 0 {
+		log.Warn("GitHub token is missing scopes required for the migration", "scopes", strings.Join(missing, ","))
+	}
+}
+
+// newRunID generates a random ID identifying a single migration run
+func newRunID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newGitHubClient creates a GitHub client authenticated by the API token or the GitHub App
+func newGitHubClient(cfg config.GlobalConfig) *github.Client {
+	var githubClient *github.Client
+	if cfg.GitHubApiToken != "" {
+		githubClient = github.NewClientByPAT(cfg.GitHubApiToken, cfg.HTTPTimeout)
+	} else {
+		githubClient = github.NewClientByApp(cfg.GitHubAppID, cfg.GitHubAppInstallationID, cfg.GitHubAppPrivateKey, cfg.HTTPTimeout)
+	}
+	githubClient.SetLimits(cfg.Limits)
+	return githubClient
+}
+
+// newAuthorClients creates GitHub clients authenticated as the users of the user token file, keyed by login
+func newAuthorClients(ctx context.Context, cfg config.GlobalConfig, path string) (map[string]*github.Client, error) {
+	tokens, err := usertokens.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	clients := make(map[string]*github.Client, len(tokens))
+	for login, token := range tokens {
+		client := github.NewClientByPAT(token, cfg.HTTPTimeout)
+		client.SetLimits(cfg.Limits)
+		client.SetQuietNotifications(cfg.QuietNotifications)
+		if cfg.FastComments {
+			client.SetContentInterval(0)
+		}
+		clients[login] = client
+	}
+	if len(clients) > 0 && cfg.UserMapFile == "" {
+		logger.FromContext(ctx).Warn("--user-tokens has no effect without --user-map")
+	}
+	if len(clients) > 0 {
+		logger.FromContext(ctx).Info("Creating comments as mapped users", "users", strings.Join(tokens.Users(), ","))
+	}
+	return clients, nil
+}
+
+// parseCommentSince parses the --comment-since flag, returning the zero time if unset
+func parseCommentSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --comment-since: %w", err)
+	}
+	return t, nil
+}
+
+// newRetryConfig returns the GitHub API retry settings of the global config
+func newRetryConfig(cfg config.GlobalConfig) github.RetryConfig {
+	retryConfig := github.DefaultRetryConfig()
+	retryConfig.Jitter = cfg.RetryJitter
+	if cfg.CircuitBreakerThreshold > 0 {
+		retryConfig.CircuitBreaker = github.NewCircuitBreaker(cfg.CircuitBreakerThreshold)
+	}
+	return retryConfig
+}
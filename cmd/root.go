@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	gitlabpkg "github.com/krrrr38/gitlab-2-github/pkg/gitlab"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,33 +26,71 @@ This tool performs:
 - Repository mirroring with branches and tags
 - Migration of merge requests to GitHub pull requests 
 - Pull request description and comment migration`,
+		// flagのparse後に環境変数での補完を行い、flagの指定を優先させる
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return resolveGlobalConfig(&cfg, cmd.Flags().Changed("gitlab-url"))
+		},
 	}
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfg.GitLabToken, "gitlab-token", "", "GitLab API token (or set GITLAB_TOKEN env)")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitLabTokenFile, "gitlab-token-file", "", "File containing the GitLab API token (or set GITLAB_TOKEN_FILE env)")
 	rootCmd.PersistentFlags().StringVar(&cfg.GitLabURL, "gitlab-url", "https://gitlab.com", "GitLab URL")
-	rootCmd.PersistentFlags().StringVar(&cfg.GitLabProject, "gitlab-project", "", "GitLab project ID or path (namespace/project-name)")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitLabProject, "gitlab-project", "", "GitLab project ID, path (namespace/project-name) or URL")
 	rootCmd.PersistentFlags().StringVar(&cfg.GitHubGitToken, "github-git-token", "", "GitHub Git token (or set GITHUB_GIT_TOKEN env)")
 	rootCmd.PersistentFlags().StringVar(&cfg.GitHubApiToken, "github-api-token", "", "GitHub API token (or set GITHUB_API_TOKEN env)")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitHubGitTokenFile, "github-git-token-file", "", "File containing the GitHub Git token (or set GITHUB_GIT_TOKEN_FILE env)")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitHubApiTokenFile, "github-api-token-file", "", "File containing the GitHub API token (or set GITHUB_API_TOKEN_FILE env)")
 	rootCmd.PersistentFlags().IntVar(&cfg.GitHubAppID, "github-app-id", 0, "GitHub APP ID (or set GITHUB_APP_ID env)")
 	rootCmd.PersistentFlags().IntVar(&cfg.GitHubAppInstallationID, "github-app-installation-id", 0, "GitHub APP Installation ID (or set GITHUB_APP_INSTALLATION_ID env)")
 	rootCmd.PersistentFlags().StringVar(&cfg.GitHubAppPrivateKey, "github-app-private-key", "", "GitHub APP private key (or set GITHUB_APP_PRIVATE_KEY env)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.GitHubAppPrivateKeyAsFile, "github-app-private-key-as-file", false, "GitHub APP private key as file")
-	rootCmd.PersistentFlags().StringVar(&cfg.GitHubOwner, "github-owner", "", "GitHub owner (username or organization)")
-	rootCmd.PersistentFlags().StringVar(&cfg.GitHubRepo, "github-repo", "", "GitHub repository name")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitHubOwner, "github-owner", "", "GitHub owner (username or organization), may contain {namespace} or {project} of the GitLab project")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitHubRepo, "github-repo", "", "GitHub repository name, may contain {namespace} or {project} of the GitLab project")
+	rootCmd.PersistentFlags().StringVar(&cfg.RepoDescription, "repo-description", "", "Description of the created GitHub repository (default \"Migrated from GitLab: <project>\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.RepoHomepage, "homepage", "", "Homepage of the created GitHub repository (default GitLab project URL, \"none\" to omit)")
 	rootCmd.PersistentFlags().StringVar(&cfg.WorkingDir, "working-dir", "./tmp", "Working directory for git operations")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitAuthorName, "git-author-name", git.DefaultAuthorName, "Git user.name of commits created during migration")
+	rootCmd.PersistentFlags().StringVar(&cfg.GitAuthorEmail, "git-author-email", git.DefaultAuthorEmail, "Git user.email of commits created during migration")
+	rootCmd.PersistentFlags().StringVar(&cfg.UserMapFile, "user-map", "", "CSV file mapping GitLab usernames to GitHub logins (gitlab_username,github_login)")
 	rootCmd.PersistentFlags().StringVar(&cfg.LogLevel, "log-level", "info", "Log level (debug, info, warn, error, fatal)")
+	rootCmd.PersistentFlags().StringVar(&cfg.Limits.Truncate.Suffix, "truncate-suffix", utils.TruncateSuffix, "Suffix appended to truncated titles, descriptions and comments")
+	rootCmd.PersistentFlags().IntVar(&cfg.Limits.PRTitle, "max-pr-title-length", utils.MaxPRTitleLength, "Max length of migrated pull request titles")
+	rootCmd.PersistentFlags().IntVar(&cfg.Limits.PRDescription, "max-pr-description-length", utils.MaxPRDescriptionLength, "Max length of migrated pull request descriptions")
+	rootCmd.PersistentFlags().IntVar(&cfg.Limits.Comment, "max-comment-length", utils.MaxCommentLength, "Max length of migrated comments")
+	rootCmd.PersistentFlags().BoolVar(&cfg.QuietNotifications, "quiet-notifications", false, "Avoid actions that notify GitHub users (mentions are neutralized and commit comments are skipped)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.FastComments, "fast-comments", false, "Create comments without the fixed 1s interval and wait only when GitHub reports a rate limit")
+	rootCmd.PersistentFlags().Float64Var(&cfg.RetryJitter, "retry-jitter", github.DefaultRetryJitter, "Ratio of random jitter added to the backoff of retried GitHub API requests (0 disables jitter)")
+	rootCmd.PersistentFlags().IntVar(&cfg.CircuitBreakerThreshold, "circuit-breaker-threshold", github.DefaultCircuitBreakerThreshold, "Abort when this many consecutive GitHub API operations fail with server errors or network failures even after retries (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeout, "http-timeout", config.DefaultHTTPTimeout, "Timeout of each HTTP request to GitLab and GitHub, timed out requests are retried (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.SmartTruncate, "smart-truncate", false, "Truncate at the last newline or whitespace and close open code fences")
 
-	// Use environment variables if flags are not provided
-	if cfg.GitLabToken == "" {
-		cfg.GitLabToken = os.Getenv("GITLAB_TOKEN")
+	// Add subcommands
+	rootCmd.AddCommand(NewMigrateCommand(&cfg))
+	rootCmd.AddCommand(NewExportCommand(&cfg))
+	rootCmd.AddCommand(NewVerifyCommand(&cfg))
+	rootCmd.AddCommand(NewGenerateUserMapCommand(&cfg))
+	rootCmd.AddCommand(NewDeleteMigratedCommand(&cfg))
+
+	return rootCmd
+}
+
+// resolveGlobalConfig fills unset settings from environment variables and loads file based settings
+func resolveGlobalConfig(cfg *config.GlobalConfig, gitlabURLChanged bool) error {
+	// tokenはflag、flagで指定したファイル、環境変数、環境変数で指定したファイルの順に解決する
+	// shell historyやprocess一覧にtokenが残らないよう、ファイルから読み込めるようにする
+	var err error
+	if cfg.GitLabToken, err = resolveSecret(cfg.GitLabToken, cfg.GitLabTokenFile, "GITLAB_TOKEN"); err != nil {
+		return err
 	}
-	if cfg.GitHubGitToken == "" {
-		cfg.GitHubGitToken = os.Getenv("GITHUB_GIT_TOKEN")
+	if cfg.GitHubGitToken, err = resolveSecret(cfg.GitHubGitToken, cfg.GitHubGitTokenFile, "GITHUB_GIT_TOKEN"); err != nil {
+		return err
 	}
-	if cfg.GitHubApiToken == "" {
-		cfg.GitHubApiToken = os.Getenv("GITHUB_API_TOKEN")
+	if cfg.GitHubApiToken, err = resolveSecret(cfg.GitHubApiToken, cfg.GitHubApiTokenFile, "GITHUB_API_TOKEN"); err != nil {
+		return err
 	}
+
+	// Use environment variables if flags are not provided
 	if cfg.GitHubAppID == 0 {
 		cfg.GitHubAppID, _ = strconv.Atoi(os.Getenv("GITHUB_APP_ID"))
 	}
@@ -60,18 +103,61 @@ This tool performs:
 	if cfg.GitHubAppPrivateKeyAsFile {
 		privateKey, err := os.ReadFile(cfg.GitHubAppPrivateKey)
 		if err != nil {
-			logger.Fatal(fmt.Sprintf("could not read private key: %s", cfg.GitHubAppPrivateKey), "err", err)
+			return fmt.Errorf("could not read private key %s: %w", cfg.GitHubAppPrivateKey, err)
 		}
 		cfg.GitHubAppPrivateKey = string(privateKey)
 	}
 
+	// プロジェクトのURLが指定された場合はパスを取り出し、--gitlab-urlが未指定であればURLから補完する
+	configuredURL := ""
+	if gitlabURLChanged {
+		configuredURL = cfg.GitLabURL
+	}
+	if baseURL, project, ok := gitlabpkg.ParseProjectURL(cfg.GitLabProject, configuredURL); ok {
+		cfg.GitLabProject = project
+		if !gitlabURLChanged {
+			cfg.GitLabURL = baseURL
+		}
+	}
+	// sub groupのプロジェクトがURLエンコードされて指定されても、slashを保持したパスとして扱う
+	cfg.GitLabProject = gitlabpkg.NormalizeProjectPath(cfg.GitLabProject)
+
 	// Configure logger based on log level
 	if cfg.LogLevel != "" {
 		logger.SetLevel(cfg.LogLevel)
 	}
 
-	// Add subcommands
-	rootCmd.AddCommand(NewMigrateCommand(&cfg))
+	// 複数のプロジェクトを同じ設定で移行できるよう、GitHubのowner/repoをGitLabのプロジェクトのパスから解決する
+	owner, repo := cfg.GitHubOwner, cfg.GitHubRepo
+	if cfg.GitHubOwner, err = config.ExpandGitHubName(owner, cfg.GitLabProject); err != nil {
+		return fmt.Errorf("failed to resolve --github-owner: %w", err)
+	}
+	if cfg.GitHubRepo, err = config.ExpandGitHubName(repo, cfg.GitLabProject); err != nil {
+		return fmt.Errorf("failed to resolve --github-repo: %w", err)
+	}
+	if owner != cfg.GitHubOwner || repo != cfg.GitHubRepo {
+		logger.Info("Resolved GitHub repository from the GitLab project", "gitlab_project", cfg.GitLabProject, "github_repo", fmt.Sprintf("%s/%s", cfg.GitHubOwner, cfg.GitHubRepo))
+	}
+	return nil
+}
 
-	return rootCmd
+// resolveSecret resolves a secret from the flag, the file flag, the env var or the file named by the <env>_FILE env var
+func resolveSecret(value, file, env string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if file == "" {
+		if v := os.Getenv(env); v != "" {
+			return v, nil
+		}
+		file = os.Getenv(env + "_FILE")
+	}
+	if file == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret file %s: %w", file, err)
+	}
+	return strings.TrimSpace(string(b)), nil
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+)
+
+// writeSecretFile writes the secret to a file in a temporary directory and returns the path
+func writeSecretFile(t *testing.T, name, secret string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveGlobalConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		// flags は解決前のconfig (flagで指定された値) を設定する
+		flags func(t *testing.T, cfg *config.GlobalConfig)
+		env   func(t *testing.T) map[string]string
+		check func(t *testing.T, cfg *config.GlobalConfig)
+	}{
+		{
+			name:  "token flag over env",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) { cfg.GitLabToken = "flag-token" },
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITLAB_TOKEN": "env-token", "GITLAB_TOKEN_FILE": writeSecretFile(t, "env", "env-file-token")}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitLabToken != "flag-token" {
+					t.Errorf("GitLabToken = %q, want flag-token", cfg.GitLabToken)
+				}
+			},
+		},
+		{
+			name: "token file flag over env",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) {
+				cfg.GitHubApiTokenFile = writeSecretFile(t, "flag", "flag-file-token")
+			},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITHUB_API_TOKEN": "env-token"}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitHubApiToken != "flag-file-token" {
+					t.Errorf("GitHubApiToken = %q, want flag-file-token", cfg.GitHubApiToken)
+				}
+			},
+		},
+		{
+			name:  "env over env file",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) {},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITHUB_GIT_TOKEN": "env-token", "GITHUB_GIT_TOKEN_FILE": writeSecretFile(t, "env", "env-file-token")}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitHubGitToken != "env-token" {
+					t.Errorf("GitHubGitToken = %q, want env-token", cfg.GitHubGitToken)
+				}
+			},
+		},
+		{
+			name:  "env file when nothing else is given",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) {},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITLAB_TOKEN_FILE": writeSecretFile(t, "env", "env-file-token")}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitLabToken != "env-file-token" {
+					t.Errorf("GitLabToken = %q, want env-file-token", cfg.GitLabToken)
+				}
+			},
+		},
+		{
+			name: "app flags over env",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) {
+				cfg.GitHubAppID = 1
+				cfg.GitHubAppInstallationID = 2
+				cfg.GitHubAppPrivateKey = "flag-key"
+			},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITHUB_APP_ID": "10", "GITHUB_APP_INSTALLATION_ID": "20", "GITHUB_APP_PRIVATE_KEY": "env-key"}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitHubAppID != 1 || cfg.GitHubAppInstallationID != 2 || cfg.GitHubAppPrivateKey != "flag-key" {
+					t.Errorf("app = (%d, %d, %q), want (1, 2, flag-key)", cfg.GitHubAppID, cfg.GitHubAppInstallationID, cfg.GitHubAppPrivateKey)
+				}
+			},
+		},
+		{
+			name:  "app env when flags are not given",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) {},
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITHUB_APP_ID": "10", "GITHUB_APP_INSTALLATION_ID": "20", "GITHUB_APP_PRIVATE_KEY": "env-key"}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitHubAppID != 10 || cfg.GitHubAppInstallationID != 20 || cfg.GitHubAppPrivateKey != "env-key" {
+					t.Errorf("app = (%d, %d, %q), want (10, 20, env-key)", cfg.GitHubAppID, cfg.GitHubAppInstallationID, cfg.GitHubAppPrivateKey)
+				}
+			},
+		},
+		{
+			name:  "private key file from env",
+			flags: func(t *testing.T, cfg *config.GlobalConfig) { cfg.GitHubAppPrivateKeyAsFile = true },
+			env: func(t *testing.T) map[string]string {
+				return map[string]string{"GITHUB_APP_PRIVATE_KEY": writeSecretFile(t, "key.pem", "pem")}
+			},
+			check: func(t *testing.T, cfg *config.GlobalConfig) {
+				if cfg.GitHubAppPrivateKey != "pem\n" {
+					t.Errorf("GitHubAppPrivateKey = %q, want the content of the file", cfg.GitHubAppPrivateKey)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 実行環境の環境変数に影響されないよう、すべて空にしてから設定する
+			for _, key := range []string{
+				"GITLAB_TOKEN", "GITLAB_TOKEN_FILE",
+				"GITHUB_GIT_TOKEN", "GITHUB_GIT_TOKEN_FILE",
+				"GITHUB_API_TOKEN", "GITHUB_API_TOKEN_FILE",
+				"GITHUB_APP_ID", "GITHUB_APP_INSTALLATION_ID", "GITHUB_APP_PRIVATE_KEY",
+			} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env(t) {
+				t.Setenv(key, value)
+			}
+			cfg := &config.GlobalConfig{
+				GitLabURL:     "https://gitlab.com",
+				GitLabProject: "group/project",
+				GitHubOwner:   "owner",
+				GitHubRepo:    "repo",
+			}
+			tt.flags(t, cfg)
+			if err := resolveGlobalConfig(cfg, false); err != nil {
+				t.Fatalf("resolveGlobalConfig() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	gitlabpkg "github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/usermap"
+	"github.com/spf13/cobra"
+)
+
+func NewGenerateUserMapCommand(cfg *config.GlobalConfig) *cobra.Command {
+	var generateConfig config.GenerateUserMapConfig
+	cmd := &cobra.Command{
+		Use:   "generate-user-map",
+		Short: "Generate a best-guess --user-map CSV from GitLab project members and GitHub organization members",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenerateUserMap(*cfg, generateConfig)
+		},
+	}
+
+	// Generate user map command specific flags
+	cmd.Flags().StringVar(&generateConfig.OutputFile, "output", "user-map.csv", "CSV file to write the generated user map")
+	cmd.Flags().StringVar(&generateConfig.GitHubOrg, "github-org", "", "GitHub organization whose members are matched (default --github-owner)")
+
+	return cmd
+}
+
+func runGenerateUserMap(cfg config.GlobalConfig, generateConfig config.GenerateUserMapConfig) error {
+	problems := append(cfg.ValidateGitLab(), cfg.ValidateGitHubAuth()...)
+	problems = append(problems, generateConfig.Validate(cfg.GitHubOwner)...)
+	if err := config.NewValidationError(problems); err != nil {
+		return err
+	}
+	org := generateConfig.GitHubOrg
+	if org == "" {
+		org = cfg.GitHubOwner
+	}
+
+	gitlabClient, err := gitlabpkg.NewClient(cfg.GitLabToken, cfg.GitLabURL, cfg.HTTPTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	log := logger.With("gitlab_project", cfg.GitLabProject, "github_org", org)
+	ctx := github.NewRetryContext(logger.NewContext(context.Background(), log), newRetryConfig(cfg))
+	githubClient := newGitHubClient(cfg)
+
+	projectMembers, err := gitlabpkg.GetProjectMembers(ctx, gitlabClient, cfg.GitLabProject)
+	if err != nil {
+		return err
+	}
+	gitlabMembers := make([]usermap.Member, 0, len(projectMembers))
+	for _, member := range projectMembers {
+		gitlabMembers = append(gitlabMembers, usermap.Member{Username: member.Username, Name: member.Name, Email: member.Email})
+	}
+
+	orgMembers, err := githubClient.ListOrganizationMembers(ctx, org)
+	if err != nil {
+		return err
+	}
+	githubMembers := make([]usermap.Member, 0, len(orgMembers))
+	for _, member := range orgMembers {
+		githubMembers = append(githubMembers, usermap.Member{Username: member.GetLogin(), Name: member.GetName(), Email: member.GetEmail()})
+	}
+
+	matches := usermap.Generate(gitlabMembers, githubMembers)
+	if err := usermap.WriteFile(generateConfig.OutputFile, matches); err != nil {
+		return err
+	}
+
+	var uncertain int
+	for _, match := range matches {
+		if match.Uncertain() {
+			uncertain++
+		}
+	}
+	log.Info("Generated user map",
+		"path", generateConfig.OutputFile,
+		"gitlab_members", len(gitlabMembers),
+		"github_members", len(githubMembers),
+		"review", uncertain)
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	gitlabpkg "github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/migration"
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyCommand(cfg *config.GlobalConfig) *cobra.Command {
+	var verifyConfig config.VerifyConfig
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Compare GitLab merge requests and discussions with the migrated GitHub pull requests and comments",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(*cfg, verifyConfig)
+		},
+	}
+
+	// Verify command specific flags
+	// 移行時と同じ値を指定することで、移行対象となったnoteのみを比較する
+	cmd.Flags().IntVar(&verifyConfig.MaxDiscussions, "max-discussions", 0, "Max migration discussion count per merge request used by the migration")
+	cmd.Flags().StringVar(&verifyConfig.DiscussionOrder, "discussion-order", gitlabpkg.DiscussionOrderOldest, "Which discussions to keep when --max-discussions is exceeded (oldest, newest)")
+	cmd.Flags().StringVar(&verifyConfig.Subdirectory, "subdirectory", "", "Subdirectory the GitLab project was imported into")
+	cmd.Flags().StringVar(&verifyConfig.FailedTitleTag, "failed-title-tag", "[Failed]", "Tag added to titles of PRs closed after a failed migration")
+	cmd.Flags().BoolVar(&verifyConfig.IncludeSystemComments, "include-system-comments", false, "Expect all GitLab system comments to be migrated")
+	cmd.Flags().StringArrayVar(&verifyConfig.CommentFilters, "comment-filter", nil, "Regex of GitLab notes not migrated by --comment-filter")
+	cmd.Flags().StringSliceVar(&verifyConfig.MRStates, "mr-states", migration.DefaultMRStates, "GitLab merge request states to migrate (opened, closed, merged, locked)")
+	cmd.Flags().StringVar(&verifyConfig.CommentSince, "comment-since", "", "Only migrate GitLab notes created at or after this RFC3339 timestamp")
+	cmd.Flags().StringVar(&verifyConfig.FromExport, "from-export", "", "Read GitLab merge request data from a directory written by the export command instead of the GitLab API")
+	cmd.Flags().StringVar(&verifyConfig.ReportFile, "report-file", "", "Write a JSON report of missing and under-migrated merge requests to this file")
+
+	return cmd
+}
+
+func runVerify(cfg config.GlobalConfig, verifyConfig config.VerifyConfig) error {
+	problems := append(cfg.ValidateGitLab(), cfg.ValidateGitHubAPI()...)
+	problems = append(problems, verifyConfig.Validate()...)
+	if err := config.NewValidationError(problems); err != nil {
+		return err
+	}
+
+	var source migration.GitLabSource
+	if verifyConfig.FromExport != "" {
+		fileSource, err := gitlabpkg.NewFileSource(verifyConfig.FromExport)
+		if err != nil {
+			return err
+		}
+		source = fileSource
+	} else {
+		gitlabClient, err := gitlabpkg.NewClient(cfg.GitLabToken, cfg.GitLabURL, cfg.HTTPTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to create GitLab client: %w", err)
+		}
+		source = gitlabpkg.NewAPISource(gitlabClient, cfg.GitLabProject)
+	}
+
+	log := logger.With(
+		"gitlab_project", cfg.GitLabProject,
+		"github_repo", fmt.Sprintf("%s/%s", cfg.GitHubOwner, cfg.GitHubRepo))
+	ctx := github.NewRetryContext(logger.NewContext(context.Background(), log), newRetryConfig(cfg))
+	githubClient := newGitHubClient(cfg)
+
+	commentFilters, err := migration.ParseCommentFilters(verifyConfig.CommentFilters)
+	if err != nil {
+		return err
+	}
+	commentSince, err := parseCommentSince(verifyConfig.CommentSince)
+	if err != nil {
+		return err
+	}
+	opts := &migration.MigrationOptions{
+		MaxDiscussions:        verifyConfig.MaxDiscussions,
+		DiscussionOrder:       verifyConfig.DiscussionOrder,
+		Subdirectory:          verifyConfig.Subdirectory,
+		FailedTitleTag:        verifyConfig.FailedTitleTag,
+		IncludeSystemComments: verifyConfig.IncludeSystemComments,
+		CommentSince:          commentSince,
+		MRStates:              verifyConfig.MRStates,
+		CommentFilters:        commentFilters,
+	}
+	log.Info("Verification started...")
+	report, err := migration.VerifyMigration(ctx, source, githubClient, cfg, opts)
+	if err != nil {
+		return fmt.Errorf("failed to verify migration: %w", err)
+	}
+	if verifyConfig.ReportFile != "" {
+		if err := report.WriteFile(verifyConfig.ReportFile); err != nil {
+			return err
+		}
+		log.Info("Wrote verify report", "path", verifyConfig.ReportFile)
+	}
+	if n := report.Discrepancies(); n > 0 {
+		return fmt.Errorf("found %d merge requests not fully migrated", n)
+	}
+	log.Info("All merge requests and discussions are migrated")
+	return nil
+}
@@ -1,11 +1,20 @@
 package config
 
+import (
+	"time"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+)
+
 type GlobalConfig struct {
 	GitLabToken               string
+	GitLabTokenFile           string
 	GitLabURL                 string
 	GitLabProject             string
 	GitHubGitToken            string
+	GitHubGitTokenFile        string
 	GitHubApiToken            string
+	GitHubApiTokenFile        string
 	GitHubAppID               int
 	GitHubAppInstallationID   int
 	GitHubAppPrivateKey       string
@@ -14,10 +23,106 @@ type GlobalConfig struct {
 	GitHubRepo                string
 	WorkingDir                string
 	LogLevel                  string
+	SmartTruncate             bool
+	Limits                    utils.Limits
+	UserMapFile               string
+	QuietNotifications        bool
+	FastComments              bool
+	RetryJitter               float64       // GitHub APIの再試行間隔に加えるランダムな揺らぎの割合 (0の場合は揺らぎを加えない)
+	CircuitBreakerThreshold   int           // 再試行しても失敗したGitHub APIの操作がこの回数連続した場合に移行を打ち切る (0の場合は打ち切らない)
+	HTTPTimeout               time.Duration // GitLabとGitHubへのHTTPリクエストのタイムアウト (0の場合はタイムアウトしない)
+	GitAuthorName             string
+	GitAuthorEmail            string
+	RepoDescription           string // 作成するGitHubリポジトリのdescription (空の場合は "Migrated from GitLab: <project>")
+	RepoHomepage              string // 作成するGitHubリポジトリのhomepage (空の場合はGitLabのプロジェクトURL、"none" の場合は設定しない)
+}
+
+// DefaultHTTPTimeout is the default timeout of each HTTP request to GitLab and GitHub
+const DefaultHTTPTimeout = 60 * time.Second
+
+// RepoHomepageNone disables the homepage of the created GitHub repository
+const RepoHomepageNone = "none"
+
+type ExportConfig struct {
+	OutputDir string // export先のディレクトリ
+}
+
+type GenerateUserMapConfig struct {
+	OutputFile string // 生成したuser mapを書き出すCSVファイル
+	GitHubOrg  string // メンバーを候補とするGitHubのorganization (空の場合は--github-owner)
+}
+
+type DeleteMigratedConfig struct {
+	Subdirectory   string // 移行時に指定したsubdirectory
+	FailedTitleTag string // 移行に失敗してcloseしたPRのタイトルに付与したタグ
+	StateFile      string // 移行時に指定した、MRごとの移行状態を保存するJSONファイル (空の場合はデフォルトのパス)
+	Confirm        bool   // 実際にPRのcloseとブランチの削除を行う
+}
+
+type VerifyConfig struct {
+	MaxDiscussions        int      // 移行時に指定したディスカッションの移行数の上限
+	DiscussionOrder       string   // 移行時に指定した、上限を超えた場合に残すディスカッションの順序
+	Subdirectory          string   // 移行時に指定したsubdirectory
+	FailedTitleTag        string   // 移行に失敗してcloseしたPRのタイトルに付与したタグ
+	IncludeSystemComments bool     // 移行時にすべてのsystemコメントを移行した
+	FromExport            string   // GitLab APIではなくexport済みのディレクトリからMRの情報を読み込む
+	ReportFile            string   // 検証結果を書き出すJSONファイル
+	CommentFilters        []string // 移行時に指定した、移行しないnoteにマッチする正規表現
+	CommentSince          string   // 移行時に指定した、移行するnoteの作成日時の下限 (RFC3339)
+	MRStates              []string // 移行時に指定した、移行するMRの状態
 }
 
 type MigrateConfig struct {
-	FilterMergeReqIDs []int
-	ContinueFromMRID  int // 指定したMR IDから処理を再開
-	MaxDiscussions    int // ディスカッションの移行数の上限（未指定の場合はすべて）
+	FilterMergeReqIDs      []int
+	ContinueFromMRID       int           // 指定したMR IDから処理を再開
+	MRStates               []string      // 移行するMRの状態 (opened, closed, merged, locked)
+	CloseStates            []string      // 移行したPRをcloseするMRの状態
+	MaxDiscussions         int           // ディスカッションの移行数の上限（未指定の場合はすべて）
+	DiscussionOrder        string        // 上限を超えた場合に古いものと新しいもののどちらを残すか (oldest, newest)
+	CommentConcurrency     int           // 1つのMRのdiscussionを並行して作成する数
+	Snippets               string        // snippetの移行方法 (gist, repo, none)
+	ForceMirror            bool          // 本ツール以外で作成された内容のあるリポジトリへのミラーリングを許可する
+	AppendMode             bool          // ミラーリングを行わず、既存のリポジトリにMRなどのみを移行する
+	RenameDefaultBranch    string        // GitLabのデフォルトブランチをこの名前でpushし、GitHubリポジトリのデフォルトブランチとする
+	AssumeRepoExists       bool          // GitHubリポジトリの存在確認と作成を行わず、事前に作成されたリポジトリを利用する
+	SystemCommentPrefix    string        // 移行するsystemコメントに付与するprefix (空の場合は付与しない)
+	SummaryComment         bool          // 移行したPRにsummaryコメントを作成してpinする
+	FromExport             string        // GitLab APIではなくexport済みのディレクトリからMRの情報を読み込む
+	RefPollAttempts        int           // PR作成前にブランチがGitHubから参照できるか確認する回数
+	RefPollInterval        time.Duration // ブランチの確認間隔
+	Subdirectory           string        // GitLabプロジェクトを配置するGitHubリポジトリ内のsubdirectory
+	ReportFile             string        // MRごとの移行結果を書き出すJSONファイル
+	CSVReportFile          string        // MRとPRの対応を書き出すCSVファイル (拡張子が.tsvの場合はTSV)
+	StateFile              string        // MRごとの移行状態を保存するJSONファイル (空の場合はデフォルトのパス)
+	DebugDumpDir           string        // 失敗したMRの取得内容とブランチを書き出すディレクトリ
+	Resume                 bool          // 保存された移行状態から、成功済みのMRをスキップして再開する
+	OnlyFailed             bool          // 保存された移行状態で失敗しているMRのみを再試行する
+	TeamReviewers          []string      // OpenのままのPRにreviewerとしてリクエストするteamのslug
+	IncludeSystemComments  bool          // 無視しているsystemコメントも含め、すべてのsystemコメントを移行する
+	MRSystemTimeline       bool          // 移行しないsystemコメントを、MRごとに折りたたんだtimelineのコメントとして残す
+	MigrateApprovalRules   bool          // GitLabの承認設定をGitHubのデフォルトブランチのbranch protectionとして移行する
+	MigrateCodeowners      bool          // GitLabのCODEOWNERSまたは承認ルールから変換したCODEOWNERSをcommitする
+	LabelMapFile           string        // GitLabのラベルからGitHubのラベルへのマッピングを記載したCSVファイル
+	TeamMapFile            string        // GitLabのgroupからGitHubのteamへのマッピングを記載したCSVファイル
+	UserTokensFile         string        // GitHubのloginとそのユーザーのtokenファイルを記載したCSVファイル
+	ArchiveSourceOnSuccess bool          // すべての移行に成功した場合にGitLabのプロジェクトをarchiveする
+	ConfirmArchiveSource   string        // archiveを確認するため、GitLabのプロジェクトのパスを指定する
+	ReallyMerge            bool          // mergedのMRから作成したPRをcloseせずに、実際にmergeする
+	NoDiffCommitMessage    string        // diffの無いMRのブランチに作成するcommitのメッセージ
+	NoDiffNote             bool          // diffの無いMRのブランチに、diffが無かったことを説明するファイルをcommitする
+	PipelineStatus         bool          // MRのhead pipelineの状態をPRのhead commitのcommit statusとして作成する
+	PipelineStatusContext  string        // pipelineの状態を作成するcommit statusのcontext
+	Timeout                time.Duration // 移行全体のタイムアウト (0の場合はタイムアウトしない)
+	ResumeCommentsOnly     bool          // 作成済みのPRに不足しているコメントのみを移行する
+	NoteMapFile            string        // 移行したnoteとGitHubのコメントのURLの対応を追記するCSVファイル
+	MRAttempts             int           // 一時的な失敗の場合に1つのMRの移行を最初からやり直す回数 (最初の実行を含む)
+	LeavePRsOpen           bool          // 移行したPRをcloseやmergeせずにOpenのまま残す
+	GraphQLReviewThreads   bool          // diffへのdiscussionをGraphQL APIでreview threadとして作成する
+	OutOfHunkStrategy      string        // PRのdiff外の行へのreviewコメントの扱い (issue-comment, nearest-line, skip)
+	CommentFilters         []string      // 移行しないnoteにマッチする正規表現 ("body:" や "author:" で対象を限定できる)
+	CommentSince           string        // この日時より前に作成されたnoteは移行しない (RFC3339)
+	QuickActions           string        // GitLabのquick actionの扱い (keep, strip, translate)
+	MarkdownFixes          []string      // 説明文やコメントに適用するmarkdownの変換 ("none"の場合は変換しない)
+	ClosedTitleTag         string        // closedのMRから作成したPRのタイトルに付与するタグ (空の場合は付与しない)
+	FailedTitleTag         string        // 移行に失敗してcloseしたPRのタイトルに付与するタグ
 }
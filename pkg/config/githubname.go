@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// githubNamePlaceholderPattern matches a placeholder such as `{project}` in --github-owner and --github-repo
+var githubNamePlaceholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// ExpandGitHubName resolves the placeholders of a GitHub owner or repository name from the GitLab project path
+// {project} はプロジェクト名、{namespace} はgroupのパスとし、sub groupの "/" はGitHubの名前に使えないため "-" に置き換える
+func ExpandGitHubName(template, gitlabProject string) (string, error) {
+	if !strings.Contains(template, "{") {
+		return template, nil
+	}
+	i := strings.LastIndex(gitlabProject, "/")
+	if i <= 0 || i == len(gitlabProject)-1 {
+		return "", fmt.Errorf("%q requires --gitlab-project to be a path such as group/project, got %q", template, gitlabProject)
+	}
+
+	namespace, project := gitlabProject[:i], gitlabProject[i+1:]
+	var unknown []string
+	expanded := githubNamePlaceholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{project}":
+			return project
+		case "{namespace}":
+			return strings.ReplaceAll(namespace, "/", "-")
+		}
+		unknown = append(unknown, placeholder)
+		return placeholder
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in %q, use {project} or {namespace}", strings.Join(unknown, ", "), template)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+)
+
+// ValidationError lists every invalid setting found before running a command
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// NewValidationError returns a ValidationError for the problems, or nil if there are none
+func NewValidationError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// ValidateGitLab returns the problems of the settings required to read from GitLab
+func (c GlobalConfig) ValidateGitLab() []string {
+	var problems []string
+	if c.GitLabToken == "" {
+		problems = append(problems, "--gitlab-token (or GITLAB_TOKEN env) is required")
+	}
+	if c.GitLabProject == "" {
+		problems = append(problems, "--gitlab-project is required")
+	}
+	if u, err := url.Parse(c.GitLabURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("--gitlab-url must be an http(s) URL such as https://gitlab.com, got %q", c.GitLabURL))
+	}
+	if c.HTTPTimeout < 0 {
+		problems = append(problems, "--http-timeout must not be negative")
+	}
+	return problems
+}
+
+// ValidateGitHub returns the problems of the settings required to write to GitHub
+func (c GlobalConfig) ValidateGitHub() []string {
+	problems := c.ValidateGitHubAPI()
+	if c.GitHubGitToken == "" {
+		problems = append(problems, "--github-git-token (or GITHUB_GIT_TOKEN env) is required")
+	}
+	if c.WorkingDir == "" {
+		problems = append(problems, "--working-dir must not be empty")
+	}
+	if c.Limits.PRTitle <= 0 {
+		problems = append(problems, "--max-pr-title-length must be positive")
+	}
+	if c.Limits.PRDescription < utils.MinBodyLength || c.Limits.Comment < utils.MinBodyLength {
+		problems = append(problems, fmt.Sprintf("--max-pr-description-length and --max-comment-length must be at least %d", utils.MinBodyLength))
+	}
+	return problems
+}
+
+// ValidateGitHubAPI returns the problems of the settings required to call the GitHub API
+func (c GlobalConfig) ValidateGitHubAPI() []string {
+	var problems []string
+	if c.GitHubOwner == "" {
+		problems = append(problems, "--github-owner is required")
+	}
+	if c.GitHubRepo == "" {
+		problems = append(problems, "--github-repo is required")
+	}
+	return append(problems, c.ValidateGitHubAuth()...)
+}
+
+// ValidateGitHubAuth returns the problems of the settings required to authenticate to the GitHub API
+func (c GlobalConfig) ValidateGitHubAuth() []string {
+	var problems []string
+	hasApp := c.GitHubAppID > 0 || c.GitHubAppInstallationID > 0 || c.GitHubAppPrivateKey != ""
+	if c.GitHubApiToken == "" {
+		if !hasApp {
+			problems = append(problems, "--github-api-token (or GITHUB_API_TOKEN env) or GitHub App settings are required")
+		} else if c.GitHubAppID <= 0 || c.GitHubAppInstallationID <= 0 || c.GitHubAppPrivateKey == "" {
+			problems = append(problems, "--github-app-id, --github-app-installation-id and --github-app-private-key are all required to use a GitHub App")
+		}
+	}
+	if c.RetryJitter < 0 || c.RetryJitter >= 1 {
+		problems = append(problems, fmt.Sprintf("--retry-jitter must be at least 0 and less than 1, got %v", c.RetryJitter))
+	}
+	if c.CircuitBreakerThreshold < 0 {
+		problems = append(problems, "--circuit-breaker-threshold must not be negative")
+	}
+	return problems
+}
+
+// Validate returns the problems of the migrate command settings
+func (c MigrateConfig) Validate() []string {
+	var problems []string
+	// 失敗したPRを移行済みと区別できなくなるため、失敗時のタグは空にできない
+	if strings.TrimSpace(c.FailedTitleTag) == "" {
+		problems = append(problems, "--failed-title-tag must not be empty")
+	}
+	switch c.Snippets {
+	case "", "gist", "repo", "none":
+	default:
+		problems = append(problems, fmt.Sprintf("--snippets must be one of gist, repo or none, got %q", c.Snippets))
+	}
+	// export済みのデータから移行する場合はGitLab APIにアクセスしないため、APIが必要な移行は行えない
+	if c.FromExport != "" && ((c.Snippets != "" && c.Snippets != "none") || c.MigrateApprovalRules || c.MigrateCodeowners || c.ArchiveSourceOnSuccess) {
+		problems = append(problems, "--from-export cannot be used with --snippets, --migrate-approval-rules, --migrate-codeowners or --archive-source-on-success")
+	}
+	switch c.QuickActions {
+	case "", "keep", "strip", "translate":
+	default:
+		problems = append(problems, fmt.Sprintf("--quick-actions must be one of keep, strip or translate, got %q", c.QuickActions))
+	}
+	switch c.OutOfHunkStrategy {
+	case "", "issue-comment", "nearest-line", "skip":
+	default:
+		problems = append(problems, fmt.Sprintf("--out-of-hunk-strategy must be one of issue-comment, nearest-line or skip, got %q", c.OutOfHunkStrategy))
+	}
+	if strings.TrimSpace(c.NoDiffCommitMessage) == "" {
+		problems = append(problems, "--no-diff-commit-message must not be empty")
+	}
+	if c.PipelineStatus && strings.TrimSpace(c.PipelineStatusContext) == "" {
+		problems = append(problems, "--pipeline-status-context must not be empty")
+	}
+	if c.OnlyFailed && len(c.FilterMergeReqIDs) > 0 {
+		problems = append(problems, "--only-failed and --mr-ids cannot be used together")
+	}
+	if c.LeavePRsOpen && c.ReallyMerge {
+		problems = append(problems, "--leave-prs-open and --really-merge cannot be used together")
+	}
+	if c.AppendMode && c.ForceMirror {
+		problems = append(problems, "--append-mode and --force-mirror cannot be used together")
+	}
+	if c.RenameDefaultBranch != "" && c.AppendMode {
+		problems = append(problems, "--rename-default-branch and --append-mode cannot be used together")
+	}
+	if c.RenameDefaultBranch != "" && c.Subdirectory != "" {
+		problems = append(problems, "--rename-default-branch and --subdirectory cannot be used together")
+	}
+	if c.Timeout < 0 {
+		problems = append(problems, "--timeout must not be negative")
+	}
+	if c.RefPollAttempts < 0 {
+		problems = append(problems, "--ref-poll-attempts must not be negative")
+	}
+	if c.MaxDiscussions < 0 {
+		problems = append(problems, "--max-discussions must not be negative")
+	}
+	if c.MRAttempts < 1 {
+		problems = append(problems, "--mr-attempts must be at least 1")
+	}
+	if c.CommentConcurrency < 1 {
+		problems = append(problems, "--comment-concurrency must be at least 1")
+	}
+	problems = append(problems, validateDiscussionOrder(c.DiscussionOrder)...)
+	problems = append(problems, validateCommentSince(c.CommentSince)...)
+	problems = append(problems, validateMRStates("--mr-states", c.MRStates)...)
+	problems = append(problems, validateMRStates("--close-states", c.CloseStates)...)
+	return problems
+}
+
+// ValidateArchiveSource returns the problems of archiving the GitLab project after the migration
+// 誤ってarchiveしないよう、プロジェクトのパスを明示的に指定させる
+func (c MigrateConfig) ValidateArchiveSource(gitlabProject string) []string {
+	if !c.ArchiveSourceOnSuccess {
+		return nil
+	}
+	var problems []string
+	if c.ConfirmArchiveSource != gitlabProject {
+		problems = append(problems, fmt.Sprintf("--confirm-archive-source must be the GitLab project path %q to use --archive-source-on-success", gitlabProject))
+	}
+	// 一部のMRのみを移行する場合は、移行が完了したとは判断できない
+	if len(c.FilterMergeReqIDs) > 0 || c.ContinueFromMRID > 0 || c.OnlyFailed || c.ResumeCommentsOnly {
+		problems = append(problems, "--archive-source-on-success cannot be used with --mr-ids, --continue-from, --only-failed or --resume-comments-only")
+	}
+	return problems
+}
+
+// Validate returns the problems of the verify command settings
+func (c VerifyConfig) Validate() []string {
+	var problems []string
+	if strings.TrimSpace(c.FailedTitleTag) == "" {
+		problems = append(problems, "--failed-title-tag must not be empty")
+	}
+	if c.MaxDiscussions < 0 {
+		problems = append(problems, "--max-discussions must not be negative")
+	}
+	problems = append(problems, validateDiscussionOrder(c.DiscussionOrder)...)
+	problems = append(problems, validateCommentSince(c.CommentSince)...)
+	problems = append(problems, validateMRStates("--mr-states", c.MRStates)...)
+	return problems
+}
+
+// validateDiscussionOrder returns the problems of the --discussion-order flag
+func validateDiscussionOrder(order string) []string {
+	switch order {
+	case "", "oldest", "newest":
+		return nil
+	default:
+		return []string{fmt.Sprintf("--discussion-order must be one of oldest or newest, got %q", order)}
+	}
+}
+
+// validateCommentSince returns the problems of the --comment-since flag
+func validateCommentSince(since string) []string {
+	if since == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, since); err != nil {
+		return []string{fmt.Sprintf("--comment-since must be an RFC3339 timestamp such as 2024-01-02T15:04:05Z, got %q", since)}
+	}
+	return nil
+}
+
+// validateMRStates returns the problems of a flag listing GitLab merge request states
+func validateMRStates(flag string, states []string) []string {
+	var problems []string
+	for _, state := range states {
+		switch state {
+		case "opened", "closed", "merged", "locked":
+		default:
+			problems = append(problems, fmt.Sprintf("%s must only contain opened, closed, merged or locked, got %q", flag, state))
+		}
+	}
+	return problems
+}
+
+// Validate returns the problems of the export command settings
+func (c ExportConfig) Validate() []string {
+	var problems []string
+	if c.OutputDir == "" {
+		problems = append(problems, "--output-dir must not be empty")
+	}
+	return problems
+}
+
+// Validate returns the problems of the delete-migrated command settings
+func (c DeleteMigratedConfig) Validate() []string {
+	var problems []string
+	if strings.TrimSpace(c.FailedTitleTag) == "" {
+		problems = append(problems, "--failed-title-tag must not be empty")
+	}
+	return problems
+}
+
+// Validate returns the problems of the generate-user-map command settings
+func (c GenerateUserMapConfig) Validate(githubOwner string) []string {
+	var problems []string
+	if c.OutputFile == "" {
+		problems = append(problems, "--output must not be empty")
+	}
+	if c.GitHubOrg == "" && githubOwner == "" {
+		problems = append(problems, "--github-org or --github-owner is required")
+	}
+	return problems
+}
@@ -0,0 +1,49 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+)
+
+// BundleFile is the name of the git bundle of the GitLab repository written by the export command
+const BundleFile = "repository.bundle"
+
+// mergeRequestRefs is where the merge request refs of a bundle are fetched to
+// bundleからはshaを指定してfetchできないため、MRのrefもまとめてfetchしておく
+const mergeRequestRefs = "refs/gitlab-merge-requests"
+
+// ExportBundle writes a git bundle of all branches, tags and merge request refs of the GitLab project to the directory
+// mirrorとしてcloneし、GitLabが保持しているMRのhead (refs/merge-requests/*) も含める
+func ExportBundle(ctx context.Context, gitlabURL, gitlabProject, gitlabToken, outputDir string) error {
+	repoURL, err := gitlab.ProjectGitURL(gitlabURL, gitlabProject, gitlabToken)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	bundlePath, err := filepath.Abs(filepath.Join(outputDir, BundleFile))
+	if err != nil {
+		return fmt.Errorf("failed to resolve bundle path: %w", err)
+	}
+	mirrorDir, err := os.MkdirTemp("", "gitlab-2-github-export-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(mirrorDir)
+
+	cloneCmd := fmt.Sprintf("git clone --mirror %s %s", repoURL, mirrorDir)
+	if err := utils.ExecuteCommand(ctx, cloneCmd); err != nil {
+		return fmt.Errorf("failed to clone GitLab repository: %w", err)
+	}
+	bundleCmd := fmt.Sprintf("cd %s && git bundle create %s --all", mirrorDir, bundlePath)
+	if err := utils.ExecuteCommand(ctx, bundleCmd); err != nil {
+		return fmt.Errorf("failed to create git bundle: %w", err)
+	}
+	return nil
+}
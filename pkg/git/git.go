@@ -1,18 +1,78 @@
 package git
 
 import (
+	"context"
 	"fmt"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
 	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+const (
+	// DefaultAuthorName is the default git user.name of commits created by this tool
+	DefaultAuthorName = "gitlab-2-github"
+	// DefaultAuthorEmail is the default git user.email of commits created by this tool
+	DefaultAuthorEmail = "gitlab-2-github@example.com"
+)
+
+const (
+	// pushAttempts is the number of times a push to GitHub is attempted before giving up
+	pushAttempts = 4
+	// pushRetryDelay is the wait before the first retry of a failed push, doubled on each retry
+	pushRetryDelay = 5 * time.Second
+	// pushChunkSize is the number of branches or tags pushed to GitHub at once by Init
+	pushChunkSize = 100
+)
+
+// worktreesDir is the directory under the working directory where per-MR worktrees are created
+const worktreesDir = ".worktrees"
+
+// CloneError indicates that the GitHub repository could not be cloned, for example because it does not exist
+type CloneError struct {
+	Owner string
+	Repo  string
+	Err   error
+}
+
+func (e *CloneError) Error() string {
+	return fmt.Sprintf("failed to clone GitHub repository %s/%s: %v", e.Owner, e.Repo, e.Err)
+}
+
+func (e *CloneError) Unwrap() error {
+	return e.Err
+}
+
+// PushError indicates that a push to GitHub kept failing even after retries
+type PushError struct {
+	Err error
+}
+
+func (e *PushError) Error() string {
+	return fmt.Sprintf("failed to push to GitHub: %v", e.Err)
+}
+
+func (e *PushError) Unwrap() error {
+	return e.Err
+}
+
 type Git struct {
 	workingDir    string
 	githubOwner   string
 	githubRepo    string
 	gitlabURL     string
 	gitlabProject string
+	subdirectory  string
+	appendMode    bool
+	defaultBranch string
+	gitlabBundle  string
+	authorName    string
+	authorEmail   string
+	log           *logger.Logger
+	ctx           context.Context
 }
 
 func NewGit(workingDir, githubOwner, githubRepo, gitlabURL, gitlabProject string) *Git {
@@ -22,9 +82,51 @@ func NewGit(workingDir, githubOwner, githubRepo, gitlabURL, gitlabProject string
 		githubRepo:    githubRepo,
 		gitlabURL:     gitlabURL,
 		gitlabProject: gitlabProject,
+		authorName:    DefaultAuthorName,
+		authorEmail:   DefaultAuthorEmail,
+		log:           logger.Default(),
+		ctx:           context.Background(),
 	}
 }
 
+// SetContext sets the context whose cancellation kills running git commands
+func (g *Git) SetContext(ctx context.Context) {
+	g.ctx = ctx
+}
+
+// SetLogger sets the scoped logger used for git operations
+func (g *Git) SetLogger(l *logger.Logger) {
+	g.log = l
+}
+
+// SetIdentity sets the git user.name and user.email configured by Init
+func (g *Git) SetIdentity(name, email string) {
+	g.authorName = name
+	g.authorEmail = email
+}
+
+// SetSubdirectory places the GitLab project under the subdirectory of the GitHub repository
+// 複数のGitLabプロジェクトを1つのGitHubリポジトリ(monorepo)に集約する場合に利用する
+func (g *Git) SetSubdirectory(dir string) {
+	g.subdirectory = strings.Trim(dir, "/")
+}
+
+// SetAppendMode makes Init only prepare the working directory without pushing the GitLab history
+// 既存のGitHubリポジトリにコードが取り込み済みの場合に、force pushで上書きしないようにする
+func (g *Git) SetAppendMode(appendMode bool) {
+	g.appendMode = appendMode
+}
+
+// SetDefaultBranch makes Init push the GitLab default branch under the name instead of the branch checked out by the clone
+func (g *Git) SetDefaultBranch(branch string) {
+	g.defaultBranch = branch
+}
+
+// SetGitLabBundle makes Init read the GitLab repository from a bundle written by ExportBundle instead of GitLab
+func (g *Git) SetGitLabBundle(path string) {
+	g.gitlabBundle = path
+}
+
 func (g *Git) Init(githubToken, gitlabToken string) error {
 	_ = utils.CleanupDirectory(g.workingDir)
 
@@ -34,86 +136,252 @@ func (g *Git) Init(githubToken, gitlabToken string) error {
 		g.githubOwner,
 		g.githubRepo)
 	cloneCmd := fmt.Sprintf("git clone %s %s", repoURL, g.workingDir)
-	if err := utils.ExecuteCommand(cloneCmd); err != nil {
-		return fmt.Errorf("failed to clone GitHub repository: %w", err)
+	if err := utils.ExecuteCommand(g.ctx, cloneCmd); err != nil {
+		return &CloneError{Owner: g.githubOwner, Repo: g.githubRepo, Err: err}
 	}
 
-	configUserNameCmd := fmt.Sprintf("cd %s && git config --local user.name \"%s\"", g.workingDir, "gitlab-2-github")
-	if err := utils.ExecuteCommand(configUserNameCmd); err != nil {
+	configUserNameCmd := fmt.Sprintf("cd %s && git config --local user.name \"%s\"", g.workingDir, g.authorName)
+	if err := utils.ExecuteCommand(g.ctx, configUserNameCmd); err != nil {
 		return fmt.Errorf("failed to set git config user.name: %w", err)
 	}
-	configUserEmailCmd := fmt.Sprintf("cd %s && git config --local user.email \"%s\"", g.workingDir, "gitlab-2-github@example.com")
-	if err := utils.ExecuteCommand(configUserEmailCmd); err != nil {
-		return fmt.Errorf("failed to set git config user.name: %w", err)
+	configUserEmailCmd := fmt.Sprintf("cd %s && git config --local user.email \"%s\"", g.workingDir, g.authorEmail)
+	if err := utils.ExecuteCommand(g.ctx, configUserEmailCmd); err != nil {
+		return fmt.Errorf("failed to set git config user.email: %w", err)
 	}
 
 	// Add GitLab remote to help with Git operations
-	gitlabRemoteURL := fmt.Sprintf("https://oauth2:%s@%s/%s.git",
-		gitlabToken,
-		strings.TrimPrefix(g.gitlabURL, "https://"),
-		g.gitlabProject)
+	// bundleが指定されている場合は、GitLabにアクセスせずbundleをremoteとする
+	gitlabRemoteURL := g.gitlabBundle
+	if gitlabRemoteURL == "" {
+		var err error
+		if gitlabRemoteURL, err = gitlab.ProjectGitURL(g.gitlabURL, g.gitlabProject, gitlabToken); err != nil {
+			return err
+		}
+	}
 	addRemoteCmd := fmt.Sprintf("cd %s && git remote add gitlab %s", g.workingDir, gitlabRemoteURL)
-	if err := utils.ExecuteCommand(addRemoteCmd); err != nil {
+	if err := utils.ExecuteCommand(g.ctx, addRemoteCmd); err != nil {
 		return fmt.Errorf("failed to add GitLab remote: %w", err)
 	}
+	if g.gitlabBundle != "" {
+		refspecCmd := fmt.Sprintf("cd %s && git config --add remote.gitlab.fetch '+refs/merge-requests/*:%s/*'", g.workingDir, mergeRequestRefs)
+		if err := utils.ExecuteCommand(g.ctx, refspecCmd); err != nil {
+			return fmt.Errorf("failed to configure GitLab bundle remote: %w", err)
+		}
+	}
 
 	// Fetch everything from GitLab
 	fetchCmd := fmt.Sprintf("cd %s && git fetch gitlab --prune --tags", g.workingDir)
-	if err := utils.ExecuteCommand(fetchCmd); err != nil {
+	if err := utils.ExecuteCommand(g.ctx, fetchCmd); err != nil {
 		return fmt.Errorf("failed to fetch from GitLab: %w", err)
 	}
+	// worktreeのディレクトリがuntrackedとして扱われないようにする
+	if err := g.excludeWorktrees(); err != nil {
+		return err
+	}
+
+	// append modeでは、MRのブランチ作成に必要なcommitをfetchするのみとする
+	if g.appendMode {
+		g.log.Info("Append mode, skipping push of the GitLab history")
+		return nil
+	}
+
+	// cloneしたブランチ (GitHubのデフォルトブランチ) の名前を変更し、GitLabの履歴を変更後の名前でpushする
+	if g.defaultBranch != "" {
+		renameCmd := fmt.Sprintf("cd %s && git branch -M %s", g.workingDir, g.defaultBranch)
+		if err := utils.ExecuteCommand(g.ctx, renameCmd); err != nil {
+			return fmt.Errorf("failed to rename default branch: %w", err)
+		}
+	}
+
+	// コードを持たないGitLabプロジェクトはpullできるブランチが無いため、GitHubリポジトリの作成のみとする
+	empty, err := g.isGitLabEmpty()
+	if err != nil {
+		return err
+	}
+	if empty {
+		g.log.Info("GitLab repository has no branches, skipping push")
+		return nil
+	}
+
+	if g.subdirectory != "" {
+		return g.importSubdirectory()
+	}
+
 	pullCmd := fmt.Sprintf("cd %s && git pull gitlab HEAD", g.workingDir)
-	if err := utils.ExecuteCommand(pullCmd); err != nil {
+	if err := utils.ExecuteCommand(g.ctx, pullCmd); err != nil {
 		return fmt.Errorf("failed to pull from GitLab: %w", err)
 	}
 
 	// Push everything to GitHub
-	// tagやbranchの件数が多い状態でまとめてpushをすると、GitHubで500が返却されることがあるため、分割してpushする
-	pushTagsCmd := fmt.Sprintf("cd %s && git push origin --tags", g.workingDir)
-	if err := utils.ExecuteCommand(pushTagsCmd); err != nil {
-		return fmt.Errorf("failed to push tags to GitHub: %w", err)
+	// tagはbranchのcommitを参照するため、branchを先にpushする
+	if err := g.pushRefsInChunks("refs/heads"); err != nil {
+		return err
+	}
+	return g.pushRefsInChunks("refs/tags")
+}
+
+// pushRefsInChunks pushes the local refs under the prefix to GitHub, pushAttempts times at most for each chunk of pushChunkSize refs
+// tagやbranchの件数が多い状態でまとめてpushをすると、GitHubで500が返却されることがあるため、分割してpushする
+// 分割しても一時的に500が返却されることがあるため、それぞれのpushを再試行する
+func (g *Git) pushRefsInChunks(prefix string) error {
+	out, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git for-each-ref --format='%%(refname)' %s", g.workingDir, prefix))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", prefix, err)
 	}
-	pushAllCmd := fmt.Sprintf("cd %s && git push origin --all", g.workingDir)
-	if err := utils.ExecuteCommand(pushAllCmd); err != nil {
-		return fmt.Errorf("failed to push all to GitHub: %w", err)
+	refs := strings.Fields(out)
+	for start := 0; start < len(refs); start += pushChunkSize {
+		chunk := refs[start:min(start+pushChunkSize, len(refs))]
+		// ref名にはshellで解釈される文字を含められるため、シングルクォートで囲む
+		quoted := make([]string, 0, len(chunk))
+		for _, ref := range chunk {
+			quoted = append(quoted, "'"+strings.ReplaceAll(ref, "'", `'\''`)+"'")
+		}
+		pushCmd := fmt.Sprintf("cd %s && git push origin %s", g.workingDir, strings.Join(quoted, " "))
+		if err := utils.RetryCommand(g.ctx, pushCmd, pushAttempts, pushRetryDelay); err != nil {
+			return fmt.Errorf("failed to push %s: %w", prefix, &PushError{Err: err})
+		}
 	}
 	return nil
 }
 
-func (g *Git) CreateBranch(branch, sha string) error {
+// GitLabDefaultBranch returns the name of the default branch of the GitLab repository
+func (g *Git) GitLabDefaultBranch() (string, error) {
+	setHeadCmd := fmt.Sprintf("cd %s && git remote set-head gitlab --auto", g.workingDir)
+	if err := utils.ExecuteCommand(g.ctx, setHeadCmd); err != nil {
+		return "", fmt.Errorf("failed to resolve GitLab default branch: %w", err)
+	}
+	out, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git symbolic-ref --short refs/remotes/gitlab/HEAD", g.workingDir))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve GitLab default branch: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(out), "gitlab/"), nil
+}
+
+// isGitLabEmpty checks if no branch was fetched from the GitLab remote
+func (g *Git) isGitLabEmpty() (bool, error) {
+	out, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git for-each-ref --count=1 refs/remotes/gitlab", g.workingDir))
+	if err != nil {
+		return false, fmt.Errorf("failed to list GitLab branches: %w", err)
+	}
+	return strings.TrimSpace(out) == "", nil
+}
+
+// importSubdirectory merges the GitLab default branch into the subdirectory of the current branch and pushes it
+// GitLabの履歴はmerge commitの親として残すが、その履歴上のファイルはsubdirectoryではなくルートに配置されたままとなる
+func (g *Git) importSubdirectory() error {
+	// 空のリポジトリの場合はHEADが存在しないため、起点となる空のcommitを作成する
+	if _, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git rev-parse --verify HEAD", g.workingDir)); err != nil {
+		if err := g.Commit("Initial commit", "--allow-empty"); err != nil {
+			return err
+		}
+	}
+
+	setHeadCmd := fmt.Sprintf("cd %s && git remote set-head gitlab --auto", g.workingDir)
+	if err := utils.ExecuteCommand(g.ctx, setHeadCmd); err != nil {
+		return fmt.Errorf("failed to resolve GitLab default branch: %w", err)
+	}
+	mergeCmd := fmt.Sprintf("cd %s && git merge -s ours --no-commit --allow-unrelated-histories gitlab/HEAD", g.workingDir)
+	if err := utils.ExecuteCommand(g.ctx, mergeCmd); err != nil {
+		return fmt.Errorf("failed to merge GitLab history: %w", err)
+	}
+	// 再実行時は既存のsubdirectoryの内容をGitLabの最新の内容で置き換える
+	if err := g.replaceSubdirectory("gitlab/HEAD"); err != nil {
+		return err
+	}
+	if err := g.Commit(fmt.Sprintf("Import %s into %s", g.gitlabProject, g.subdirectory), "--allow-empty"); err != nil {
+		return err
+	}
+
+	// tagは他のプロジェクトと衝突する可能性があるため、subdirectoryに取り込む場合はpushしない
+	pushCmd := fmt.Sprintf("cd %s && git push origin HEAD", g.workingDir)
+	if err := utils.RetryCommand(g.ctx, pushCmd, pushAttempts, pushRetryDelay); err != nil {
+		return &PushError{Err: err}
+	}
+	return nil
+}
+
+// replaceSubdirectory replaces the subdirectory in the index and working tree with the tree of the commit
+func (g *Git) replaceSubdirectory(sha string) error {
+	rmCmd := fmt.Sprintf("cd %s && git rm -r -q --ignore-unmatch -- %s", g.workingDir, g.subdirectory)
+	if err := utils.ExecuteCommand(g.ctx, rmCmd); err != nil {
+		return fmt.Errorf("failed to remove subdirectory: %w", err)
+	}
+	readTreeCmd := fmt.Sprintf("cd %s && git read-tree --prefix=%s/ -u %s", g.workingDir, g.subdirectory, sha)
+	if err := utils.ExecuteCommand(g.ctx, readTreeCmd); err != nil {
+		return fmt.Errorf("failed to read tree into subdirectory: %w", err)
+	}
+	return nil
+}
+
+// FetchCommit makes sure the commit exists locally, fetching it from GitLab if needed
+func (g *Git) FetchCommit(sha string) error {
 	// 削除済みのMRにおけるcommitなどは手元にないため、その場合には、shaを指定してfetchする
-	catFile, _ := utils.ExecuteCommandOutput(fmt.Sprintf("cd %s && git cat-file -t %s", g.workingDir, sha))
+	catFile, _ := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git cat-file -t %s", g.workingDir, sha))
 	if !strings.Contains(catFile, "commit") {
 		fetchShaCmd := fmt.Sprintf("cd %s && git fetch gitlab %s", g.workingDir, sha)
-		if err := utils.ExecuteCommand(fetchShaCmd); err != nil {
+		if err := utils.ExecuteCommand(g.ctx, fetchShaCmd); err != nil {
 			return fmt.Errorf("failed to fetch sha from GitLab: %w", err)
 		}
 	}
+	return nil
+}
+
+// ResolveCommit returns the full SHA of the commit, which may be given as an abbreviated hash
+func (g *Git) ResolveCommit(sha string) (string, error) {
+	out, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git rev-parse --verify --quiet %s^{commit}", g.workingDir, sha))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit %s: %w", sha, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (g *Git) CreateBranch(branch, sha string) error {
+	if err := g.FetchCommit(sha); err != nil {
+		return err
+	}
+	if g.subdirectory != "" && sha != "" {
+		return g.createSubdirectoryBranch(branch, sha)
+	}
 
 	// Create branch from base_sha
-	baseSHACmd := fmt.Sprintf("cd %s && git checkout -b %s %s",
+	// --mr-attemptsで再試行する場合や再実行時に前回のブランチが残っているため、既存のブランチは作り直す
+	baseSHACmd := fmt.Sprintf("cd %s && git checkout -B %s %s",
 		g.workingDir, branch, sha)
-	if err := utils.ExecuteCommand(baseSHACmd); err != nil {
-		logger.Warn("Failed to checkout branch from sha",
+	if err := utils.ExecuteCommand(g.ctx, baseSHACmd); err != nil {
+		g.log.Warn("Failed to checkout branch from sha",
 			"branch", branch,
 			"sha", sha,
 			"error", err)
 
 		// Fallback to using target branch directly
-		branchCmd := fmt.Sprintf("cd %s && git checkout -b %s gitlab/%s",
+		branchCmd := fmt.Sprintf("cd %s && git checkout -B %s gitlab/%s",
 			g.workingDir, branch, branch)
 
-		if err := utils.ExecuteCommand(branchCmd); err != nil {
+		if err := utils.ExecuteCommand(g.ctx, branchCmd); err != nil {
 			return fmt.Errorf("failed to create branch: %w", err)
 		}
 	}
 	return nil
 }
 
+// createSubdirectoryBranch creates a branch from HEAD with a commit placing the tree of sha under the subdirectory
+// GitLabのcommitをそのまま使うとmonorepoのルートに展開されてしまうため、HEADを起点にsubdirectoryのみを差し替える
+func (g *Git) createSubdirectoryBranch(branch, sha string) error {
+	checkoutCmd := fmt.Sprintf("cd %s && git checkout -B %s", g.workingDir, branch)
+	if err := utils.ExecuteCommand(g.ctx, checkoutCmd); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+	if err := g.replaceSubdirectory(sha); err != nil {
+		return err
+	}
+	return g.Commit(fmt.Sprintf("Import %s of %s into %s", sha, g.gitlabProject, g.subdirectory), "--allow-empty")
+}
+
 func (g *Git) Commit(comment string, options ...string) error {
+	// メッセージは設定から指定できるため、シングルクォートをエスケープする
 	commitCmd := fmt.Sprintf("cd %s && git commit %s -m '%s'",
-		g.workingDir, strings.Join(options, " "), comment)
-	if err := utils.ExecuteCommand(commitCmd); err != nil {
+		g.workingDir, strings.Join(options, " "), strings.ReplaceAll(comment, "'", `'\''`))
+	if err := utils.ExecuteCommand(g.ctx, commitCmd); err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
 	}
 	return nil
@@ -121,8 +389,113 @@ func (g *Git) Commit(comment string, options ...string) error {
 
 func (g *Git) PushBranchOrigins(branches ...string) error {
 	pushSourceCmd := fmt.Sprintf("cd %s && git push origin %s --force", g.workingDir, strings.Join(branches, " "))
-	if err := utils.ExecuteCommand(pushSourceCmd); err != nil {
-		return fmt.Errorf("failed to push source branch: %w", err)
+	if err := utils.RetryCommand(g.ctx, pushSourceCmd, pushAttempts, pushRetryDelay); err != nil {
+		return fmt.Errorf("failed to push source branch: %w", &PushError{Err: err})
+	}
+	return nil
+}
+
+// CurrentBranch returns the name of the checked out branch
+func (g *Git) CurrentBranch() (string, error) {
+	out, err := utils.ExecuteCommandOutput(g.ctx, fmt.Sprintf("cd %s && git rev-parse --abbrev-ref HEAD", g.workingDir))
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Checkout checks out an existing branch
+func (g *Git) Checkout(branch string) error {
+	checkoutCmd := fmt.Sprintf("cd %s && git checkout %s", g.workingDir, branch)
+	if err := utils.ExecuteCommand(g.ctx, checkoutCmd); err != nil {
+		return fmt.Errorf("failed to checkout branch: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes a file at the path relative to the working directory
+func (g *Git) WriteFile(path string, content []byte) error {
+	fullPath := filepath.Join(g.workingDir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// ReadFile reads a file at the path relative to the working directory
+func (g *Git) ReadFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(g.workingDir, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return content, nil
+}
+
+// FileExists checks if the file exists at the path relative to the working directory
+func (g *Git) FileExists(path string) bool {
+	info, err := os.Stat(filepath.Join(g.workingDir, path))
+	return err == nil && !info.IsDir()
+}
+
+// Add stages the paths relative to the working directory
+func (g *Git) Add(paths ...string) error {
+	addCmd := fmt.Sprintf("cd %s && git add -- %s", g.workingDir, strings.Join(paths, " "))
+	if err := utils.ExecuteCommand(g.ctx, addCmd); err != nil {
+		return fmt.Errorf("failed to add files: %w", err)
+	}
+	return nil
+}
+
+// AddWorktree creates a detached worktree under the working directory and returns a Git operating within it
+// 複数のMRのブランチ作成やcheckoutがお互いのHEADに影響しないよう、MRごとにworktreeを作成する
+func (g *Git) AddWorktree(name string) (*Git, error) {
+	relPath := filepath.Join(worktreesDir, name)
+	// 前回の実行で残ったworktreeがあれば削除しておく
+	_ = os.RemoveAll(filepath.Join(g.workingDir, relPath))
+	pruneCmd := fmt.Sprintf("cd %s && git worktree prune", g.workingDir)
+	if err := utils.ExecuteCommand(g.ctx, pruneCmd); err != nil {
+		return nil, fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+
+	addCmd := fmt.Sprintf("cd %s && git worktree add --detach %s", g.workingDir, relPath)
+	if err := utils.ExecuteCommand(g.ctx, addCmd); err != nil {
+		return nil, fmt.Errorf("failed to add worktree: %w", err)
+	}
+
+	worktree := *g
+	worktree.workingDir = filepath.Join(g.workingDir, relPath)
+	return &worktree, nil
+}
+
+// RemoveWorktree removes a worktree created by AddWorktree
+func (g *Git) RemoveWorktree(worktree *Git) error {
+	relPath, err := filepath.Rel(g.workingDir, worktree.workingDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	removeCmd := fmt.Sprintf("cd %s && git worktree remove --force %s", g.workingDir, relPath)
+	if err := utils.ExecuteCommand(g.ctx, removeCmd); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	return nil
+}
+
+// excludeWorktrees adds the worktrees directory to the local git exclude file
+func (g *Git) excludeWorktrees() error {
+	excludePath := filepath.Join(g.workingDir, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return fmt.Errorf("failed to create git info directory: %w", err)
+	}
+	f, err := os.OpenFile(excludePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open git exclude file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("/" + worktreesDir + "/\n"); err != nil {
+		return fmt.Errorf("failed to write git exclude file: %w", err)
 	}
 	return nil
 }
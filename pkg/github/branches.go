@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+)
+
+// BranchProtectionOptions contains the review requirements of a protected branch
+type BranchProtectionOptions struct {
+	RequiredApprovingReviewCount int
+	RequireCodeOwnerReviews      bool
+	DismissStaleReviews          bool
+}
+
+// ProtectBranch creates or replaces the branch protection requiring pull request reviews
+func (client *Client) ProtectBranch(ctx context.Context, owner, repo, branch string, opts *BranchProtectionOptions) error {
+	logger.FromContext(ctx).Debug("Protecting branch",
+		"owner", owner,
+		"repo", repo,
+		"branch", branch,
+		"requiredApprovingReviewCount", opts.RequiredApprovingReviewCount,
+		"requireCodeOwnerReviews", opts.RequireCodeOwnerReviews)
+
+	preq := &githublib.ProtectionRequest{
+		RequiredPullRequestReviews: &githublib.PullRequestReviewsEnforcementRequest{
+			RequiredApprovingReviewCount: opts.RequiredApprovingReviewCount,
+			RequireCodeOwnerReviews:      opts.RequireCodeOwnerReviews,
+			DismissStaleReviews:          opts.DismissStaleReviews,
+		},
+	}
+	err := RetryableOperation(ctx, func() error {
+		_, _, err := client.GetInner().Repositories.UpdateBranchProtection(ctx, owner, repo, branch, preq)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to protect branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// SetDefaultBranch changes the default branch of the repository
+func (client *Client) SetDefaultBranch(ctx context.Context, owner, repo, branch string) error {
+	err := RetryableOperation(ctx, func() error {
+		_, _, err := client.GetInner().Repositories.Edit(ctx, owner, repo, &githublib.Repository{DefaultBranch: githublib.String(branch)})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set default branch %s: %w", branch, err)
+	}
+	return nil
+}
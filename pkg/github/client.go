@@ -2,49 +2,118 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
-	"math/rand"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v70/github"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
 
 // Client wraps the GitHub client with retry capabilities
 type Client struct {
-	inner *github.Client
-	v4    *githubv4.Client
+	inner  *github.Client
+	v4     *githubv4.Client
+	limits utils.Limits
+	// quietNotifications suppresses mentions in created content
+	quietNotifications bool
+	// contentInterval is the wait before each content-generating request
+	contentInterval time.Duration
+	// nextContentAt is when the next content-generating request may be sent, shared by concurrent requests
+	nextContentAt time.Time
+	contentMu     sync.Mutex
+	// reviewMu serializes review thread creation, since a user can only have one pending review per pull request
+	reviewMu sync.Mutex
 }
 
+// defaultContentInterval keeps content-generating requests under the secondary rate limit
+const defaultContentInterval = 1 * time.Second
+
 // NewClientByPAT creates a new GitHub client with the provided token
-func NewClientByPAT(token string) *Client {
+// timeoutに0を指定した場合はタイムアウトしない
+func NewClientByPAT(token string, timeout time.Duration) *Client {
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
+	tc.Timeout = timeout
 
 	return &Client{
-		inner: github.NewClient(tc),
-		v4:    githubv4.NewClient(tc),
+		inner:           github.NewClient(tc),
+		v4:              githubv4.NewClient(tc),
+		limits:          utils.DefaultLimits(),
+		contentInterval: defaultContentInterval,
 	}
 }
 
-func NewClientByApp(appID, installationID int, privateKey string) *Client {
+func NewClientByApp(appID, installationID int, privateKey string, timeout time.Duration) *Client {
 	itr, err := ghinstallation.New(http.DefaultTransport, int64(appID), int64(installationID), []byte(privateKey))
 	if err != nil {
 		logger.Fatal("failed to create gh client", "error", err)
 	}
 	return &Client{
-		inner: github.NewClient(&http.Client{Transport: itr}),
-		v4:    githubv4.NewClient(&http.Client{Transport: itr}),
+		inner:           github.NewClient(&http.Client{Transport: itr, Timeout: timeout}),
+		v4:              githubv4.NewClient(&http.Client{Transport: itr, Timeout: timeout}),
+		limits:          utils.DefaultLimits(),
+		contentInterval: defaultContentInterval,
+	}
+}
+
+// SetLimits overrides the text length limits used when creating pull requests and comments
+func (client *Client) SetLimits(limits utils.Limits) {
+	client.limits = limits
+}
+
+// SetContentInterval overrides the wait before each content-generating request
+// 0を指定した場合は待機せず、secondary rate limitに達した際のRetry-Afterによる待機のみに頼る
+func (client *Client) SetContentInterval(interval time.Duration) {
+	client.contentInterval = interval
+}
+
+// waitContentInterval waits before a content-generating request
+// 並行してコメントを作成する場合も、リクエスト同士の間隔がcontentInterval以上となるよう順番に待機時刻を割り当てる
+func (client *Client) waitContentInterval() {
+	if client.contentInterval <= 0 {
+		return
+	}
+	client.contentMu.Lock()
+	start := time.Now()
+	if client.nextContentAt.After(start) {
+		start = client.nextContentAt
 	}
+	client.nextContentAt = start.Add(client.contentInterval)
+	wait := time.Until(client.nextContentAt)
+	client.contentMu.Unlock()
+	time.Sleep(wait)
+}
+
+// SetQuietNotifications makes the client avoid content that notifies GitHub users
+func (client *Client) SetQuietNotifications(quiet bool) {
+	client.quietNotifications = quiet
+}
+
+// QuietNotifications reports whether notification-generating actions should be avoided
+func (client *Client) QuietNotifications() bool {
+	return client.quietNotifications
+}
+
+// prepareBody suppresses mentions in the body if quiet notifications are enabled
+func (client *Client) prepareBody(body string) string {
+	if client.quietNotifications {
+		return utils.SuppressMentions(body)
+	}
+	return body
 }
 
 // GetInner returns the underlying GitHub client
@@ -59,7 +128,7 @@ func (client *Client) GetV4() *githubv4.Client {
 
 // DeleteRepository deletes a GitHub repository
 func DeleteRepository(ctx context.Context, client *Client, owner, repo string) error {
-	logger.Debug("Deleting GitHub repository", "owner", owner, "repo", repo)
+	logger.FromContext(ctx).Debug("Deleting GitHub repository", "owner", owner, "repo", repo)
 
 	err := RetryableOperation(ctx, func() error {
 		_, err := client.GetInner().Repositories.Delete(ctx, owner, repo)
@@ -67,23 +136,47 @@ func DeleteRepository(ctx context.Context, client *Client, owner, repo string) e
 	})
 
 	if err != nil {
-		logger.Error("Failed to delete GitHub repository", "owner", owner, "repo", repo, "error", err)
+		logger.FromContext(ctx).Error("Failed to delete GitHub repository", "owner", owner, "repo", repo, "error", err)
 		return fmt.Errorf("failed to delete GitHub repository: %w", err)
 	}
 
-	logger.Debug("Successfully deleted GitHub repository", "owner", owner, "repo", repo)
+	logger.FromContext(ctx).Debug("Successfully deleted GitHub repository", "owner", owner, "repo", repo)
 	return nil
 }
 
+// CreateRepositoryOptions contains options for creating a repository
+type CreateRepositoryOptions struct {
+	Description string
+	// Homepage is the homepage URL of the repository, omitted if nil
+	Homepage *url.URL
+}
+
 // CreateRepository creates an empty GitHub repository
-func CreateRepository(ctx context.Context, client *Client, owner, repo, description string, url *url.URL) error {
-	logger.Debug("Creating GitHub repository", "owner", owner, "repo", repo, "url", url)
+func CreateRepository(ctx context.Context, client *Client, owner, repo string, opts *CreateRepositoryOptions) error {
+	logger.FromContext(ctx).Debug("Creating GitHub repository", "owner", owner, "repo", repo, "homepage", opts.Homepage)
 
 	ownerDetail, _, err := client.GetInner().Users.Get(ctx, owner)
 	if err != nil {
 		return fmt.Errorf("failed to get owner detail: %w", err)
 	}
 
+	err = createInternalRepository(ctx, client, ownerDetail, repo, opts)
+	if err != nil && isInternalVisibilityError(err) {
+		// internalのリポジトリを作成できない (Enterprise以外のアカウントやscopeの不足した token/App) 場合は、privateとしてREST APIで作成する
+		logger.FromContext(ctx).Warn("Cannot create an internal repository, creating a private repository instead", "owner", owner, "repo", repo, "error", err)
+		err = createPrivateRepository(ctx, client, ownerDetail, repo, opts)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create GitHub repository", "owner", owner, "repo", repo, "error", err)
+		return fmt.Errorf("failed to create GitHub repository: %w", err)
+	}
+
+	logger.FromContext(ctx).Debug("Successfully created GitHub repository", "owner", owner, "repo", repo)
+	return nil
+}
+
+// createInternalRepository creates the repository with internal visibility using the GraphQL API
+func createInternalRepository(ctx context.Context, client *Client, ownerDetail *github.User, repo string, opts *CreateRepositoryOptions) error {
 	// visibility=Internal とするためにRESTAPIではなくgraphql APIを利用
 	var mutation struct {
 		CreateRepository struct {
@@ -100,45 +193,162 @@ func CreateRepository(ctx context.Context, client *Client, owner, repo, descript
 		Name:           githubv4.String(repo),
 		Visibility:     githubv4.RepositoryVisibilityInternal,
 		OwnerID:        githubv4.NewID(ownerDetail.GetNodeID()),
-		Description:    githubv4.NewString(githubv4.String(description)),
+		Description:    githubv4.NewString(githubv4.String(opts.Description)),
 		HasWikiEnabled: githubv4.NewBoolean(false),
-		HomepageURL: githubv4.NewURI(githubv4.URI{
-			URL: url,
-		}),
 	}
-	err = RetryableOperation(ctx, func() error {
+	if opts.Homepage != nil {
+		input.HomepageURL = githubv4.NewURI(githubv4.URI{
+			URL: opts.Homepage,
+		})
+	}
+	return RetryableOperation(ctx, func() error {
 		return client.GetV4().Mutate(ctx, &mutation, input, nil)
 	})
-	if err != nil {
-		logger.Error("Failed to create GitHub repository", "owner", owner, "repo", repo, "error", err)
-		return fmt.Errorf("failed to create GitHub repository: %w", err)
+}
+
+// createPrivateRepository creates the repository with private visibility using the REST API
+func createPrivateRepository(ctx context.Context, client *Client, ownerDetail *github.User, repo string, opts *CreateRepositoryOptions) error {
+	newRepo := &github.Repository{
+		Name:        github.String(repo),
+		Visibility:  github.String("private"),
+		Description: github.String(opts.Description),
+		HasWiki:     github.Bool(false),
+	}
+	if opts.Homepage != nil {
+		newRepo.Homepage = github.String(opts.Homepage.String())
+	}
+	// 個人アカウントの場合は、orgを空として認証ユーザーのリポジトリを作成するため、他のユーザーのリポジトリは作成できない
+	org := ""
+	if ownerDetail.GetType() == "Organization" {
+		org = ownerDetail.GetLogin()
+	} else {
+		user, _, err := client.GetInner().Users.Get(ctx, "")
+		if err != nil {
+			return fmt.Errorf("failed to get authenticated user: %w", err)
+		}
+		if !strings.EqualFold(user.GetLogin(), ownerDetail.GetLogin()) {
+			return fmt.Errorf("cannot create a repository of user %s as %s", ownerDetail.GetLogin(), user.GetLogin())
+		}
+	}
+	return RetryableOperation(ctx, func() error {
+		_, _, err := client.GetInner().Repositories.Create(ctx, org, newRepo)
+		return err
+	})
+}
+
+// internalVisibilityErrorMessages are the GraphQL error messages of createRepository when internal repositories cannot be created
+// Enterprise以外のアカウントや、権限の不足したtoken/Appの場合に返される
+var internalVisibilityErrorMessages = []string{
+	"resource not accessible by integration",
+	"has not been granted the required scopes",
+	"internal repositories",
+	"visibility",
+}
+
+// isInternalVisibilityError determines if the GraphQL createRepository mutation failed because the internal visibility or the permission is not available
+// HTTPのエラー (5xxなど) や通信エラーはGraphQLのエラーではないため、privateへのfallbackの対象としない
+func isInternalVisibilityError(err error) bool {
+	if _, ok := graphQLStatusCode(err); ok {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
 	}
+	message := strings.ToLower(err.Error())
+	for _, s := range internalVisibilityErrorMessages {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
 
-	logger.Debug("Successfully created GitHub repository", "owner", owner, "repo", repo)
-	return nil
+// graphQLStatusPattern matches the error githubv4 returns for a non-200 HTTP response
+var graphQLStatusPattern = regexp.MustCompile(`non-200 OK status code: (\d{3})`)
+
+// graphQLStatusCode returns the HTTP status code of a GraphQL request which failed with a non-200 response
+func graphQLStatusCode(err error) (int, bool) {
+	m := graphQLStatusPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return code, true
 }
 
 // RetryableOperation retries a GitHub API operation with exponential backoff
 func RetryableOperation(ctx context.Context, operation func() error) error {
 	var err error
-	maxRetries := 5
-	backoffFactor := 2.0
-	initialDelay := 1 * time.Second
-	maxDelay := 60 * time.Second
+	retryConfig := RetryConfigFromContext(ctx)
+	maxRetries := retryConfig.MaxRetries
+	// GitHubが停止していると判断した後は、再試行に時間を費やさずにすぐに失敗させる
+	if err := retryConfig.CircuitBreaker.Err(); err != nil {
+		return err
+	}
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		err = operation()
 		if err == nil {
+			retryConfig.CircuitBreaker.record(false)
 			return nil
 		}
 
+		// secondary rate limitやrate limitの場合は、GitHubが指定する時間だけ待ってから再試行する
+		if delay, ok := rateLimitDelay(err); ok {
+			logger.FromContext(ctx).Info(fmt.Sprintf("Rate limited: %v. Retrying after %s (attempt %d/%d)", err, delay, attempt+1, maxRetries))
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// 短時間にコメントを作成しすぎた場合のspam protectionは、長めに待ってから再試行する
+		if isSubmittedTooQuicklyError(err) {
+			delay := retryConfig.backoff(attempt, submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay)
+			logger.FromContext(ctx).Info(fmt.Sprintf("Submitted too quickly: %v. Retrying after %s (attempt %d/%d)", err, delay, attempt+1, maxRetries))
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
 		// Check if error is related to rate limit
 		if isRateLimitError(err) {
-			return fmt.Errorf("rate limited: %w", err)
+			reset, remaining, ok := rateLimitReset(err)
+			if !ok {
+				return fmt.Errorf("rate limited: %w", err)
+			}
+			// resetまでが短い場合は待ってから再試行し、長い場合はいつ再実行できるかを示して終了する
+			delay := time.Until(reset)
+			if delay > maxRateLimitWait {
+				logger.FromContext(ctx).Warn("Rate limited", "reset", reset.Format(time.RFC3339), "remaining", remaining)
+				return fmt.Errorf("rate limited until %s (remaining %d): %w", reset.Format(time.RFC3339), remaining, err)
+			}
+			if delay < time.Second {
+				delay = time.Second
+			}
+			logger.FromContext(ctx).Info(fmt.Sprintf("Rate limited: %v. Retrying after reset at %s (attempt %d/%d)", err, reset.Format(time.RFC3339), attempt+1, maxRetries))
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		} else if isRetryableError(err) {
 			// Other retryable errors (network issues, 500s, etc.)
-			delay := calculateBackoff(attempt, initialDelay, backoffFactor, maxDelay)
-			logger.Info(fmt.Sprintf("Retryable error: %v. Retrying after %s (attempt %d/%d)", err, delay, attempt+1, maxRetries))
+			delay := retryConfig.backoff(attempt, retryConfig.InitialDelay, retryConfig.MaxDelay)
+			logger.FromContext(ctx).Info(fmt.Sprintf("Retryable error: %v. Retrying after %s (attempt %d/%d)", err, delay, attempt+1, maxRetries))
 
 			select {
 			case <-time.After(delay):
@@ -148,13 +358,70 @@ func RetryableOperation(ctx context.Context, operation func() error) error {
 			}
 		} else {
 			// Non-retryable error
+			// GitHubからは応答があったため、連続した失敗には数えない
+			retryConfig.CircuitBreaker.record(false)
 			return err
 		}
 	}
 
+	// rate limitやspam protectionによる失敗はGitHubが応答しているため、停止しているとは判断しない
+	retryConfig.CircuitBreaker.record(IsUnavailableError(err))
+
+	if reset, remaining, ok := rateLimitReset(err); ok {
+		return fmt.Errorf("operation failed after %d attempts, rate limit resets at %s (remaining %d): %w", maxRetries, reset.Format(time.RFC3339), remaining, err)
+	}
 	return fmt.Errorf("operation failed after %d attempts: %w", maxRetries, err)
 }
 
+// submittedTooQuicklyDelay and submittedTooQuicklyMaxDelay bound the backoff of content rejected as submitted too quickly
+// テストで待機時間を短くできるよう、変数とする
+var (
+	submittedTooQuicklyDelay    = 30 * time.Second
+	submittedTooQuicklyMaxDelay = 5 * time.Minute
+)
+
+// maxRateLimitWait is the longest wait for a rate limit reset before giving up
+const maxRateLimitWait = 15 * time.Minute
+
+// rateLimitReset returns the reset time and remaining requests of the rate limit reported with the error
+func rateLimitReset(err error) (time.Time, int, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		return rateErr.Rate.Reset.Time, rateErr.Rate.Remaining, true
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return time.Time{}, 0, false
+	}
+	reset, perr := strconv.ParseInt(errResp.Response.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if perr != nil {
+		return time.Time{}, 0, false
+	}
+	remaining, _ := strconv.Atoi(errResp.Response.Header.Get("X-RateLimit-Remaining"))
+	return time.Unix(reset, 0), remaining, true
+}
+
+// rateLimitDelay returns how long to wait before retrying a request rejected by a rate limit
+func rateLimitDelay(err error) (time.Duration, bool) {
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		// Retry-Afterが無い場合は1分待つ
+		return 60 * time.Second, true
+	}
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		delay := time.Until(rateErr.Rate.Reset.Time)
+		if delay < time.Second {
+			delay = time.Second
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
 // isRateLimitError determines if an error is due to rate limiting
 func isRateLimitError(err error) bool {
 	if err == nil {
@@ -162,7 +429,9 @@ func isRateLimitError(err error) bool {
 	}
 
 	// Check if err is a GitHub error response
-	if errResp, ok := err.(*github.ErrorResponse); ok {
+	// x-github-request-idなどを付与してwrapされたエラーも判定できるよう、errors.Asを利用する
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
 		statusCode := errResp.Response.StatusCode
 		return (statusCode == http.StatusForbidden && errResp.Message == "rate limit") || statusCode == http.StatusTooManyRequests
 	}
@@ -170,6 +439,93 @@ func isRateLimitError(err error) bool {
 	return false
 }
 
+// IsPermissionError determines if an error is due to the token lacking access to the repository
+// GitHubは権限の無いリポジトリへのアクセスに404を返すことがあるため、404も権限不足として扱う
+func IsPermissionError(err error) bool {
+	if isRateLimitError(err) {
+		return false
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+// IsOutsideDiffError determines if an error is GitHub's 422 rejecting a review comment on a line outside the PR diff
+func IsOutsideDiffError(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	messages := []string{errResp.Message}
+	for _, e := range errResp.Errors {
+		messages = append(messages, e.Message)
+	}
+	for _, message := range messages {
+		// start_lineの場合も "pull_request_review_thread.start_line must be part of the diff" となる
+		if strings.Contains(strings.ToLower(message), "must be part of the diff") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubmittedTooQuicklyError determines if an error is GitHub's 422 spam protection for content created too quickly
+func isSubmittedTooQuicklyError(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	messages := []string{errResp.Message}
+	for _, e := range errResp.Errors {
+		messages = append(messages, e.Message)
+	}
+	for _, message := range messages {
+		message = strings.ToLower(message)
+		if strings.Contains(message, "submitted too quickly") || strings.Contains(message, "abuse detection mechanism") {
+			return true
+		}
+	}
+	return false
+}
+
+// isBodyTooLongError determines if an error is GitHub's 422 rejecting the body of a PR or comment as too long
+func isBodyTooLongError(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	messages := []string{errResp.Message}
+	for _, e := range errResp.Errors {
+		messages = append(messages, e.Message)
+	}
+	for _, message := range messages {
+		message = strings.ToLower(message)
+		if strings.Contains(message, "too long") || strings.Contains(message, "maximum is 65536") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnavailableError determines if an error is a server error or a network failure, suggesting that GitHub is down
+func IsUnavailableError(err error) bool {
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return errResp.Response.StatusCode >= http.StatusInternalServerError
+	}
+	if code, ok := graphQLStatusCode(err); ok {
+		return code >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
 // isRetryableError determines if an error should be retried
 func isRetryableError(err error) bool {
 	if err == nil {
@@ -187,24 +543,13 @@ func isRetryableError(err error) bool {
 			code == http.StatusGatewayTimeout
 	}
 
-	// Also retry on network/transport errors
-	_, isNetError := err.(*url.Error)
-	return isNetError
-}
-
-// calculateBackoff computes the backoff duration using exponential backoff with jitter
-func calculateBackoff(attempt int, initialDelay time.Duration, factor float64, maxDelay time.Duration) time.Duration {
-	// Calculate exponential backoff
-	backoff := float64(initialDelay) * math.Pow(factor, float64(attempt))
-
-	// Add some jitter (±20%)
-	jitter := backoff * 0.2 * (rand.Float64()*2 - 1)
-	backoff = backoff + jitter
-
-	// Ensure we don't exceed max delay
-	if backoff > float64(maxDelay) {
-		backoff = float64(maxDelay)
+	// GraphQL APIのHTTPのエラーは、REST APIと同様に5xxと429を再試行する
+	if code, ok := graphQLStatusCode(err); ok {
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
 	}
 
-	return time.Duration(backoff)
+	// Also retry on network/transport errors
+	// --http-timeoutによるタイムアウトもurl.Errorとして返るため、wrapされていても再試行する
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
 }
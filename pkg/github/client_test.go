@@ -0,0 +1,289 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+	"github.com/shurcooL/githubv4"
+)
+
+// newTestClient returns a client sending both REST and GraphQL requests to the handler
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	inner := github.NewClient(srv.Client())
+	baseURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.BaseURL = baseURL
+	return &Client{
+		inner:  inner,
+		v4:     githubv4.NewEnterpriseClient(srv.URL+"/graphql", srv.Client()),
+		limits: utils.DefaultLimits(),
+	}
+}
+
+// newTestContext returns a context retrying without waiting
+func newTestContext() context.Context {
+	return NewRetryContext(context.Background(), RetryConfig{
+		MaxRetries:    3,
+		InitialDelay:  time.Millisecond,
+		MaxDelay:      time.Millisecond,
+		BackoffFactor: 1,
+	})
+}
+
+func TestCreateRepository(t *testing.T) {
+	tests := []struct {
+		name string
+		// graphql returns the status and body of the n-th (0-based) createRepository mutation
+		graphql         func(n int) (int, string)
+		wantErr         bool
+		wantGraphQLReqs int32
+		wantRESTCreate  bool
+	}{
+		{
+			name: "internal repository created",
+			graphql: func(int) (int, string) {
+				return http.StatusOK, `{"data":{"createRepository":{"repository":{"id":"R_1","name":"repo","owner":{"login":"org"}}}}}`
+			},
+			wantGraphQLReqs: 1,
+		},
+		{
+			name: "falls back to private when the integration cannot create internal repositories",
+			graphql: func(int) (int, string) {
+				return http.StatusOK, `{"data":{"createRepository":null},"errors":[{"message":"Resource not accessible by integration"}]}`
+			},
+			wantGraphQLReqs: 1,
+			wantRESTCreate:  true,
+		},
+		{
+			name: "retries a transient server error instead of falling back",
+			graphql: func(n int) (int, string) {
+				if n == 0 {
+					return http.StatusInternalServerError, `{"message":"Internal Server Error"}`
+				}
+				return http.StatusOK, `{"data":{"createRepository":{"repository":{"id":"R_1","name":"repo","owner":{"login":"org"}}}}}`
+			},
+			wantGraphQLReqs: 2,
+		},
+		{
+			name: "fails without falling back when the server keeps failing",
+			graphql: func(int) (int, string) {
+				return http.StatusInternalServerError, `{"message":"Internal Server Error"}`
+			},
+			wantErr:         true,
+			wantGraphQLReqs: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var graphqlReqs int32
+			var restCreated atomic.Bool
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /users/org", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"login":"org","type":"Organization","node_id":"O_1"}`)
+			})
+			mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&graphqlReqs, 1) - 1
+				status, body := tt.graphql(int(n))
+				w.WriteHeader(status)
+				fmt.Fprint(w, body)
+			})
+			mux.HandleFunc("POST /orgs/org/repos", func(w http.ResponseWriter, r *http.Request) {
+				var repo github.Repository
+				if err := json.NewDecoder(r.Body).Decode(&repo); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if got := repo.GetVisibility(); got != "private" {
+					t.Errorf("visibility = %q, want private", got)
+				}
+				restCreated.Store(true)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"name":"repo"}`)
+			})
+			client := newTestClient(t, mux)
+
+			err := CreateRepository(newTestContext(), client, "org", "repo", &CreateRepositoryOptions{Description: "desc"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&graphqlReqs); got != tt.wantGraphQLReqs {
+				t.Errorf("graphql requests = %d, want %d", got, tt.wantGraphQLReqs)
+			}
+			if got := restCreated.Load(); got != tt.wantRESTCreate {
+				t.Errorf("REST create = %v, want %v", got, tt.wantRESTCreate)
+			}
+		})
+	}
+}
+
+func TestClientRetriesTimedOutRequests(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初のリクエストはクライアントのタイムアウトより長く応答しない
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, `[{"number":1,"title":"Migrated GitLab snippets"}]`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClientByPAT("token", 50*time.Millisecond)
+	baseURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.inner.BaseURL = baseURL
+
+	issue, err := client.FindIssueByTitle(newTestContext(), "owner", "repo", "Migrated GitLab snippets")
+	if err != nil {
+		t.Fatalf("FindIssueByTitle() error = %v", err)
+	}
+	if issue.GetNumber() != 1 {
+		t.Errorf("issue number = %d, want 1", issue.GetNumber())
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestIsInternalVisibilityError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"integration without permission", errors.New("Resource not accessible by integration"), true},
+		{"token without scopes", errors.New("Your token has not been granted the required scopes to execute this query."), true},
+		{"visibility not available", errors.New("Visibility can't be internal for this repository"), true},
+		{"server error", errors.New(`non-200 OK status code: 500 Internal Server Error body: "{}"`), false},
+		{"forbidden response", errors.New(`non-200 OK status code: 403 Forbidden body: "permission denied"`), false},
+		{"network error", &url.Error{Op: "Post", URL: "https://api.github.com/graphql", Err: errors.New("internal error")}, false},
+		{"name already exists", errors.New("Name already exists on this account"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInternalVisibilityError(tt.err); got != tt.want {
+				t.Errorf("isInternalVisibilityError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOutsideDiffError(t *testing.T) {
+	errorResponse := func(status int, message string, errs ...string) error {
+		resp := &github.ErrorResponse{Response: &http.Response{StatusCode: status}, Message: message}
+		for _, e := range errs {
+			resp.Errors = append(resp.Errors, github.Error{Message: e})
+		}
+		return resp
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"line outside the diff", errorResponse(http.StatusUnprocessableEntity, "Validation Failed", "pull_request_review_thread.line must be part of the diff"), true},
+		{"start line outside the diff", errorResponse(http.StatusUnprocessableEntity, "Validation Failed", "pull_request_review_thread.start_line must be part of the diff"), true},
+		{"wrapped", fmt.Errorf("failed to create comment: %w", errorResponse(http.StatusUnprocessableEntity, "Validation Failed", "pull_request_review_thread.line must be part of the diff")), true},
+		{"body too long", errorResponse(http.StatusUnprocessableEntity, "Validation Failed", "Body is too long (maximum is 65536 characters)"), false},
+		{"submitted too quickly", errorResponse(http.StatusUnprocessableEntity, "Validation Failed", "was submitted too quickly"), false},
+		{"not unprocessable", errorResponse(http.StatusBadRequest, "must be part of the diff"), false},
+		{"server error", errorResponse(http.StatusInternalServerError, "Internal Server Error"), false},
+		{"not a response error", errors.New("pull_request_review_thread.line must be part of the diff"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOutsideDiffError(tt.err); got != tt.want {
+				t.Errorf("IsOutsideDiffError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// createCommentError returns the error of creating an issue comment on a server responding with the status and body
+func createCommentError(t *testing.T, status int, body string) error {
+	t.Helper()
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	_, _, err := client.GetInner().Issues.CreateComment(context.Background(), "owner", "repo", 1, &github.IssueComment{Body: github.Ptr("body")})
+	return err
+}
+
+func TestIsSubmittedTooQuicklyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"submitted too quickly", http.StatusUnprocessableEntity, `{"message":"Validation Failed","errors":[{"resource":"IssueComment","code":"custom","field":"body","message":"was submitted too quickly"}]}`, true},
+		{"abuse detection mechanism", http.StatusUnprocessableEntity, `{"message":"You have triggered an abuse detection mechanism. Please wait a few minutes before you try again."}`, true},
+		{"body too long", http.StatusUnprocessableEntity, `{"message":"Validation Failed","errors":[{"resource":"IssueComment","code":"custom","field":"body","message":"Body is too long (maximum is 65536 characters)"}]}`, false},
+		{"line outside the diff", http.StatusUnprocessableEntity, `{"message":"Validation Failed","errors":[{"message":"pull_request_review_thread.line must be part of the diff"}]}`, false},
+		{"no commits", http.StatusUnprocessableEntity, `{"message":"Validation Failed","errors":[{"resource":"PullRequest","code":"custom","message":"No commits between"}]}`, false},
+		{"forbidden with the same message", http.StatusForbidden, `{"message":"was submitted too quickly"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := createCommentError(t, tt.status, tt.body)
+			if err == nil {
+				t.Fatal("CreateComment() error = nil, want an error response")
+			}
+			if got := isSubmittedTooQuicklyError(err); got != tt.want {
+				t.Errorf("isSubmittedTooQuicklyError(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryableOperationRetriesSubmittedTooQuickly(t *testing.T) {
+	delay, maxDelay := submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay
+	submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay = delay, maxDelay
+	})
+
+	var requests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初の2回はspam protectionにより拒否される
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message":"Validation Failed","errors":[{"message":"was submitted too quickly"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":1}`)
+	}))
+
+	comment, err := client.CreateIssueComment(newTestContext(), "owner", "repo", 1, "body", false)
+	if err != nil {
+		t.Fatalf("CreateIssueComment() error = %v", err)
+	}
+	if comment.GetID() != 1 {
+		t.Errorf("comment ID = %d, want 1", comment.GetID())
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
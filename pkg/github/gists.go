@@ -0,0 +1,46 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+)
+
+// GistOptions contains options for creating a gist
+type GistOptions struct {
+	Description string
+	Public      bool
+	Files       map[string]string // filename -> content
+}
+
+// CreateGist creates a gist owned by the authenticated user
+func (client *Client) CreateGist(ctx context.Context, opts *GistOptions) (*githublib.Gist, error) {
+	logger.FromContext(ctx).Debug("Creating gist", "description", opts.Description, "files", len(opts.Files))
+
+	files := make(map[githublib.GistFilename]githublib.GistFile, len(opts.Files))
+	for name, content := range opts.Files {
+		files[githublib.GistFilename(name)] = githublib.GistFile{
+			Content: githublib.String(content),
+		}
+	}
+	newGist := &githublib.Gist{
+		Description: githublib.String(opts.Description),
+		Public:      githublib.Bool(opts.Public),
+		Files:       files,
+	}
+
+	var gist *githublib.Gist
+	err := RetryableOperation(ctx, func() error {
+		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
+		var err error
+		gist, _, err = client.GetInner().Gists.Create(ctx, newGist)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gist: %w", err)
+	}
+	return gist, nil
+}
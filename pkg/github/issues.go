@@ -0,0 +1,97 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+	"github.com/shurcooL/githubv4"
+)
+
+// CreateIssue creates a new issue in the repository
+func (client *Client) CreateIssue(ctx context.Context, owner, repo, title, body string) (*githublib.Issue, error) {
+	logger.FromContext(ctx).Debug("Creating issue",
+		"owner", owner,
+		"repo", repo,
+		"title", title)
+
+	issueRequest := &githublib.IssueRequest{
+		Title: githublib.String(utils.TruncateTextWithOptions(title, client.limits.PRTitle, client.limits.Truncate)),
+		Body:  githublib.String(utils.TruncateTextWithOptions(client.prepareBody(body), client.limits.PRDescription, client.limits.Truncate)),
+	}
+
+	var issue *githublib.Issue
+	err := RetryableOperation(ctx, func() error {
+		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
+		var err error
+		issue, _, err = client.GetInner().Issues.Create(ctx, owner, repo, issueRequest)
+		return err
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create issue",
+			"owner", owner,
+			"repo", repo,
+			"error", err)
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return issue, nil
+}
+
+// FindIssueByTitle returns the issue with the title in the repository, or nil if there is none
+// pull requestもissueとして返されるため除外する
+func (client *Client) FindIssueByTitle(ctx context.Context, owner, repo, title string) (*githublib.Issue, error) {
+	opts := &githublib.IssueListByRepoOptions{
+		State:       "all",
+		ListOptions: githublib.ListOptions{PerPage: 100},
+	}
+	for {
+		var issues []*githublib.Issue
+		var resp *githublib.Response
+		err := RetryableOperation(ctx, func() error {
+			var err error
+			issues, resp, err = client.GetInner().Issues.ListByRepo(ctx, owner, repo, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub issues: %w", err)
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && issue.GetTitle() == title {
+				return issue, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// PinIssueCommentInput is an autogenerated input type of PinIssueComment
+// githubv4 does not provide this input type yet, so it is defined here
+type PinIssueCommentInput struct {
+	// The ID of the issue comment to be pinned. (Required.)
+	IssueCommentID githubv4.ID `json:"issueCommentId"`
+}
+
+// PinIssueComment pins an issue comment on its issue or pull request
+func (client *Client) PinIssueComment(ctx context.Context, commentNodeID string) error {
+	var mutation struct {
+		PinIssueComment struct {
+			ClientMutationID githubv4.String
+		} `graphql:"pinIssueComment(input: $input)"`
+	}
+	input := PinIssueCommentInput{
+		IssueCommentID: githubv4.ID(commentNodeID),
+	}
+	err := RetryableOperation(ctx, func() error {
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pin issue comment: %w", err)
+	}
+	return nil
+}
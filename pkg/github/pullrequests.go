@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	githublib "github.com/google/go-github/v70/github"
@@ -10,14 +11,6 @@ import (
 	"github.com/krrrr38/gitlab-2-github/pkg/utils"
 )
 
-// min returns the smaller of x or y.
-func min(x, y int) int {
-	if x < y {
-		return x
-	}
-	return y
-}
-
 // PullRequestOptions contains options for creating a pull request
 type PullRequestOptions struct {
 	Title               string
@@ -38,8 +31,41 @@ func (e *NoDiffError) Error() string {
 	return fmt.Sprintf("no diff found between branches: %s and %s", e.Head, e.Base)
 }
 
-func (client *Client) GetClosedPullRequestTitles(ctx context.Context, owner, repo string) ([]string, error) {
-	var titles []string
+// BodyTooLongError indicates that GitHub kept rejecting the body as too long even after truncating it further
+type BodyTooLongError struct {
+	Limit int // 最後に試した本文の最大長
+	Err   error
+}
+
+func (e *BodyTooLongError) Error() string {
+	return fmt.Sprintf("body is too long even when truncated to %d characters: %v", e.Limit, e.Err)
+}
+
+func (e *BodyTooLongError) Unwrap() error {
+	return e.Err
+}
+
+// bodyTooLongAttempts is the number of times a body rejected as too long is truncated further and retried
+const bodyTooLongAttempts = 3
+
+// createWithShorterBody runs the operation with the body truncated to the limit, shortening it by a quarter while GitHub rejects it as too long
+// GitHubの上限は表示前の文字数と一致しない場合があり (絵文字やエスケープなど)、上限内に切り詰めた本文でも拒否されることがある
+func createWithShorterBody(ctx context.Context, limit int, truncate func(limit int) string, operation func(body string) error) error {
+	err := operation(truncate(limit))
+	for attempt := 1; attempt <= bodyTooLongAttempts && isBodyTooLongError(err); attempt++ {
+		limit = limit * 3 / 4
+		logger.FromContext(ctx).Warn("GitHub rejected the body as too long, retrying with a shorter body", "attempt", attempt, "limit", limit, "error", err)
+		err = operation(truncate(limit))
+	}
+	if isBodyTooLongError(err) {
+		return &BodyTooLongError{Limit: limit, Err: err}
+	}
+	return err
+}
+
+// GetClosedPullRequests returns all closed pull requests in the repository
+func (client *Client) GetClosedPullRequests(ctx context.Context, owner, repo string) ([]*githublib.PullRequest, error) {
+	var ret []*githublib.PullRequest
 	var page = 1
 	for {
 		opts := &githublib.PullRequestListOptions{
@@ -53,15 +79,13 @@ func (client *Client) GetClosedPullRequestTitles(ctx context.Context, owner, rep
 		if err != nil {
 			return nil, fmt.Errorf("failed to get GitHub PRs: %w", err)
 		}
-		for _, pr := range prs {
-			titles = append(titles, pr.GetTitle())
-		}
+		ret = append(ret, prs...)
 		if len(prs) < 100 {
 			break
 		}
 		page += 1
 	}
-	return titles, nil
+	return ret, nil
 }
 
 func (client *Client) GetOpenedPullRequests(ctx context.Context, owner, repo string) ([]*githublib.PullRequest, error) {
@@ -93,18 +117,17 @@ func (client *Client) GetOpenedPullRequests(ctx context.Context, owner, repo str
 // CreatePullRequest creates a new pull request in GitHub
 func (client *Client) CreatePullRequest(ctx context.Context, owner, repo string, opts *PullRequestOptions) (*githublib.PullRequest, error) {
 	// Log the operation with key parameters
-	logger.Debug("Creating GitHub pull request",
+	logger.FromContext(ctx).Debug("Creating GitHub pull request",
 		"owner", owner,
 		"repo", repo,
 		"head", opts.Head,
 		"base", opts.Base,
-		"title", opts.Title[:min(50, len(opts.Title))]+"...", // Truncate long titles
+		"title", utils.Truncate(opts.Title, 50),
 		"draft", opts.Draft)
 
 	// Create pull request
 	newPR := &githublib.NewPullRequest{
 		Title:               githublib.String(opts.Title),
-		Body:                githublib.String(opts.Body),
 		Head:                githublib.String(opts.Head),
 		Base:                githublib.String(opts.Base),
 		MaintainerCanModify: githublib.Bool(opts.MaintainerCanModify),
@@ -112,16 +135,21 @@ func (client *Client) CreatePullRequest(ctx context.Context, owner, repo string,
 	}
 
 	var pr *githublib.PullRequest
-	var err error
-
-	err = RetryableOperation(ctx, func() error {
-		pr, _, err = client.GetInner().PullRequests.Create(ctx, owner, repo, newPR)
-		return err
+	truncate := func(limit int) string {
+		return utils.TruncateTextWithOptions(client.prepareBody(opts.Body), limit, client.limits.Truncate)
+	}
+	err := createWithShorterBody(ctx, client.limits.PRDescription, truncate, func(body string) error {
+		newPR.Body = githublib.String(body)
+		return RetryableOperation(ctx, func() error {
+			var err error
+			pr, _, err = client.GetInner().PullRequests.Create(ctx, owner, repo, newPR)
+			return err
+		})
 	})
 
 	// Log any errors with request parameters
 	if err != nil {
-		logger.Error("Failed to create GitHub PR",
+		logger.FromContext(ctx).Error("Failed to create GitHub PR",
 			"owner", owner,
 			"repo", repo,
 			"head", opts.Head,
@@ -147,7 +175,7 @@ func (client *Client) CreatePullRequest(ctx context.Context, owner, repo string,
 
 func (client *Client) AddLabelsToIssue(ctx context.Context, owner, repo string, issueNumber int, labels []string) error {
 	// Log the operation with key parameters
-	logger.Debug("Adding labels to issue",
+	logger.FromContext(ctx).Debug("Adding labels to issue",
 		"owner", owner,
 		"repo", repo,
 		"issueNumber", issueNumber,
@@ -160,7 +188,7 @@ func (client *Client) AddLabelsToIssue(ctx context.Context, owner, repo string,
 	})
 
 	if err != nil {
-		logger.Error("Failed to add labels to issue",
+		logger.FromContext(ctx).Error("Failed to add labels to issue",
 			"owner", owner,
 			"repo", repo,
 			"issueNumber", issueNumber,
@@ -175,7 +203,7 @@ func (client *Client) AddLabelsToIssue(ctx context.Context, owner, repo string,
 // UpdatePullRequestTitle edit a pull request title
 func (client *Client) UpdatePullRequestTitle(ctx context.Context, owner, repo string, prNumber int, title string) error {
 	// Log the operation with key parameters
-	logger.Debug("Updating pull request",
+	logger.FromContext(ctx).Debug("Updating pull request",
 		"owner", owner,
 		"repo", repo,
 		"prNumber", prNumber)
@@ -194,7 +222,7 @@ func (client *Client) UpdatePullRequestTitle(ctx context.Context, owner, repo st
 	})
 
 	if err != nil {
-		logger.Error("Failed to update GitHub PR",
+		logger.FromContext(ctx).Error("Failed to update GitHub PR",
 			"owner", owner,
 			"repo", repo,
 			"prNumber", prNumber,
@@ -208,7 +236,7 @@ func (client *Client) UpdatePullRequestTitle(ctx context.Context, owner, repo st
 // ClosePullRequest closes a pull request
 func (client *Client) ClosePullRequest(ctx context.Context, owner, repo string, prNumber int) error {
 	// Log the operation with key parameters
-	logger.Debug("Closing pull request",
+	logger.FromContext(ctx).Debug("Closing pull request",
 		"owner", owner,
 		"repo", repo,
 		"prNumber", prNumber)
@@ -228,7 +256,7 @@ func (client *Client) ClosePullRequest(ctx context.Context, owner, repo string,
 	})
 
 	if err != nil {
-		logger.Error("Failed to close GitHub PR",
+		logger.FromContext(ctx).Error("Failed to close GitHub PR",
 			"owner", owner,
 			"repo", repo,
 			"prNumber", prNumber,
@@ -239,10 +267,51 @@ func (client *Client) ClosePullRequest(ctx context.Context, owner, repo string,
 	return nil
 }
 
+// ReopenPullRequest reopens a closed pull request
+func (client *Client) ReopenPullRequest(ctx context.Context, owner, repo string, prNumber int) error {
+	logger.FromContext(ctx).Debug("Reopening pull request",
+		"owner", owner,
+		"repo", repo,
+		"prNumber", prNumber)
+
+	err := RetryableOperation(ctx, func() error {
+		reopenRequest := &githublib.PullRequest{
+			State: githublib.String("open"),
+		}
+		_, resp, err := client.GetInner().PullRequests.Edit(ctx, owner, repo, prNumber, reopenRequest)
+		if err != nil && resp != nil {
+			err = fmt.Errorf("%w, x-github-request-id: %s", err, resp.Header.Get("x-github-request-id"))
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reopen GitHub PR: %w", err)
+	}
+	return nil
+}
+
+// MergePullRequest merges a pull request with a merge commit
+func (client *Client) MergePullRequest(ctx context.Context, owner, repo string, prNumber int, commitMessage string) error {
+	logger.FromContext(ctx).Debug("Merging GitHub pull request",
+		"owner", owner,
+		"repo", repo,
+		"prNumber", prNumber)
+
+	err := RetryableOperation(ctx, func() error {
+		_, _, err := client.GetInner().PullRequests.Merge(ctx, owner, repo, prNumber, commitMessage,
+			&githublib.PullRequestOptions{MergeMethod: "merge"})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge GitHub PR: %w", err)
+	}
+	return nil
+}
+
 // DeleteBranch deletes a branch from the repository
 func (client *Client) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
 	// Log the operation with key parameters
-	logger.Debug("Deleting branch",
+	logger.FromContext(ctx).Debug("Deleting branch",
 		"owner", owner,
 		"repo", repo,
 		"branch", branch)
@@ -254,7 +323,7 @@ func (client *Client) DeleteBranch(ctx context.Context, owner, repo, branch stri
 	})
 
 	if err != nil {
-		logger.Error("Failed to delete branch",
+		logger.FromContext(ctx).Error("Failed to delete branch",
 			"owner", owner,
 			"repo", repo,
 			"branch", branch,
@@ -262,31 +331,189 @@ func (client *Client) DeleteBranch(ctx context.Context, owner, repo, branch stri
 		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
 	}
 
-	logger.Debug("Deleted branch", "branch", branch)
+	logger.FromContext(ctx).Debug("Deleted branch", "branch", branch)
+	return nil
+}
+
+// ListBranches returns the names of the branches starting with the prefix
+func (client *Client) ListBranches(ctx context.Context, owner, repo, prefix string) ([]string, error) {
+	var ret []string
+	opts := &githublib.ReferenceListOptions{
+		Ref:         "heads/" + prefix,
+		ListOptions: githublib.ListOptions{PerPage: 100},
+	}
+	for {
+		var refs []*githublib.Reference
+		var resp *githublib.Response
+		err := RetryableOperation(ctx, func() error {
+			var err error
+			refs, resp, err = client.GetInner().Git.ListMatchingRefs(ctx, owner, repo, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub branches: %w", err)
+		}
+		for _, ref := range refs {
+			ret = append(ret, strings.TrimPrefix(ref.GetRef(), "refs/heads/"))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
+// WaitForBranches polls until all branches are visible through the GitHub API
+// push直後はGitHub側のreplicationが間に合わず、PR作成時に "head branch not found" となることがあるため
+func (client *Client) WaitForBranches(ctx context.Context, owner, repo string, attempts int, interval time.Duration, branches ...string) error {
+	for _, branch := range branches {
+		var err error
+		for i := 0; i < attempts; i++ {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(interval):
+				}
+			}
+			_, _, err = client.GetInner().Git.GetRef(ctx, owner, repo, "heads/"+branch)
+			if err == nil {
+				break
+			}
+			logger.FromContext(ctx).Debug("Branch is not visible yet", "branch", branch, "attempt", i+1, "error", err)
+		}
+		if err != nil {
+			return fmt.Errorf("branch %s is not visible after %d attempts: %w", branch, attempts, err)
+		}
+	}
 	return nil
 }
 
+// RequestTeamReviewers requests the teams as reviewers of the pull request
+func (client *Client) RequestTeamReviewers(ctx context.Context, owner, repo string, prNumber int, teams []string) error {
+	logger.FromContext(ctx).Debug("Requesting team reviewers",
+		"owner", owner,
+		"repo", repo,
+		"prNumber", prNumber,
+		"teams", teams)
+
+	err := RetryableOperation(ctx, func() error {
+		_, _, err := client.GetInner().PullRequests.RequestReviewers(ctx, owner, repo, prNumber, githublib.ReviewersRequest{
+			TeamReviewers: teams,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request team reviewers: %w", err)
+	}
+	return nil
+}
+
+// TeamExists checks if the team exists in the organization
+func (client *Client) TeamExists(ctx context.Context, org, slug string) (bool, error) {
+	var exists bool
+	err := RetryableOperation(ctx, func() error {
+		_, resp, err := client.GetInner().Teams.GetTeamBySlug(ctx, org, slug)
+		if err != nil {
+			if resp != nil && resp.StatusCode == 404 {
+				exists = false
+				return nil
+			}
+			return err
+		}
+		exists = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get team %s: %w", slug, err)
+	}
+	return exists, nil
+}
+
+// ListIssueComments returns all regular (non-review) comments of a pull request
+func (client *Client) ListIssueComments(ctx context.Context, owner, repo string, prNumber int) ([]*githublib.IssueComment, error) {
+	var ret []*githublib.IssueComment
+	opts := &githublib.IssueListCommentsOptions{
+		ListOptions: githublib.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := client.GetInner().Issues.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub issue comments: %w", err)
+		}
+		ret = append(ret, comments...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
+// ListPRComments returns all review comments of a pull request
+func (client *Client) ListPRComments(ctx context.Context, owner, repo string, prNumber int) ([]*githublib.PullRequestComment, error) {
+	var ret []*githublib.PullRequestComment
+	opts := &githublib.PullRequestListCommentsOptions{
+		ListOptions: githublib.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := client.GetInner().PullRequests.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub review comments: %w", err)
+		}
+		ret = append(ret, comments...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
+// ListPRFiles returns the changed files of a pull request with their patches
+func (client *Client) ListPRFiles(ctx context.Context, owner, repo string, prNumber int) ([]*githublib.CommitFile, error) {
+	var ret []*githublib.CommitFile
+	opts := &githublib.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := client.GetInner().PullRequests.ListFiles(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub PR files: %w", err)
+		}
+		ret = append(ret, files...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
 // CreateIssueComment creates a regular (non-review) comment on a pull request
 func (client *Client) CreateIssueComment(ctx context.Context, owner, repo string, prNumber int, body string, resolved bool) (*githublib.IssueComment, error) {
 	// 文字数制限に合わせて切り詰める
-	truncatedBody := utils.TruncateText(body, utils.MaxCommentLength)
-	if resolved {
-		// resolveされている場合は折りたたむ (github apiでresolvedとするにはgraphql apiを利用する必要があり、手間がかかるため短期解を選択)
-		truncatedBody = utils.WrapCommentAsResolved(truncatedBody)
+	truncate := func(limit int) string {
+		truncatedBody := utils.TruncateTextWithOptions(client.prepareBody(body), limit, client.limits.Truncate)
+		if resolved {
+			// resolveされている場合は折りたたむ (github apiでresolvedとするにはgraphql apiを利用する必要があり、手間がかかるため短期解を選択)
+			truncatedBody = utils.WrapCommentAsResolved(truncatedBody, limit, client.limits.Truncate)
+		}
+		return truncatedBody
 	}
 
 	var comment *githublib.IssueComment
-	err := RetryableOperation(ctx, func() error {
-		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
-		time.Sleep(1 * time.Second) // In general, no more than 80 content-generating requests per minute
-		c, resp, err := client.GetInner().Issues.CreateComment(ctx, owner, repo, prNumber,
-			&githublib.IssueComment{Body: &truncatedBody})
-		comment = c
-		xGitHubRequestId := resp.Header.Get("x-github-request-id")
-		if err != nil {
-			err = fmt.Errorf("%w, x-github-request-id: %s", err, xGitHubRequestId)
-		}
-		return err
+	err := createWithShorterBody(ctx, client.limits.Comment, truncate, func(truncatedBody string) error {
+		return RetryableOperation(ctx, func() error {
+			// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
+			client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
+			c, resp, err := client.GetInner().Issues.CreateComment(ctx, owner, repo, prNumber,
+				&githublib.IssueComment{Body: &truncatedBody})
+			comment = c
+			if err != nil && resp != nil {
+				err = fmt.Errorf("%w, x-github-request-id: %s", err, resp.Header.Get("x-github-request-id"))
+			}
+			return err
+		})
 	})
 	return comment, err
 }
@@ -294,10 +521,10 @@ func (client *Client) CreateIssueComment(ctx context.Context, owner, repo string
 // CreateCommitComment creates a regular (non-review) comment on a pull request
 func (client *Client) CreateCommitComment(ctx context.Context, owner, repo, commit string, body string) error {
 	// 文字数制限に合わせて切り詰める
-	truncatedBody := utils.TruncateText(body, utils.MaxCommentLength)
+	truncatedBody := utils.TruncateTextWithOptions(client.prepareBody(body), client.limits.Comment, client.limits.Truncate)
 	err := RetryableOperation(ctx, func() error {
 		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
-		time.Sleep(1 * time.Second) // In general, no more than 80 content-generating requests per minute
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
 		comment := &struct {
 			Body string `json:"body,omitempty"`
 		}{
@@ -331,13 +558,41 @@ type CreatePRCommentInput struct {
 	Path      string
 	Sha1      string
 	Resolved  bool
+	StartSide string // 範囲の開始行のdiffの側 (SideLeft, SideRight)
 	StartLine *int
+	Side      string // 範囲の最終行のdiffの側 (空の場合はSideRight)
 	LastLine  *int
 }
 
+// SideLeft and SideRight are the sides of a diff a review comment is anchored to
+const (
+	SideLeft  = "LEFT"  // 削除された行 (変更前)
+	SideRight = "RIGHT" // 追加された行や変更されていない行 (変更後)
+)
+
+// diffSide returns the side, defaulting to SideRight
+func diffSide(side string) string {
+	if side == "" {
+		return SideRight
+	}
+	return side
+}
+
+// isMultiLineRange checks if the start and last lines form a range of several lines
+// 開始行と最終行が異なる側にある場合は、行番号を比較できないため範囲として扱う
+func isMultiLineRange(startSide string, startLine *int, side string, lastLine *int) bool {
+	if startLine == nil || lastLine == nil {
+		return false
+	}
+	if diffSide(startSide) != diffSide(side) {
+		return true
+	}
+	return *startLine < *lastLine
+}
+
 // CreatePRComment creates a single review comment and returns the review ID
 func (client *Client) CreatePRComment(ctx context.Context, input *CreatePRCommentInput) (*githublib.PullRequestComment, error) {
-	logger.Debug("Creating PR comment",
+	logger.FromContext(ctx).Debug("Creating PR comment",
 		"owner", input.Owner,
 		"repo", input.Repo,
 		"prNumber", input.PrNumber,
@@ -347,29 +602,29 @@ func (client *Client) CreatePRComment(ctx context.Context, input *CreatePRCommen
 		"resolved", input.Resolved)
 
 	// 文字数制限に合わせて切り詰める
-	truncatedBody := utils.TruncateText(input.Body, utils.MaxCommentLength)
+	truncatedBody := utils.TruncateTextWithOptions(client.prepareBody(input.Body), client.limits.Comment, client.limits.Truncate)
 	if input.Resolved {
 		// resolveされている場合は折りたたむ (github apiでresolvedとするにはgraphql apiを利用する必要があり、手間がかかるため短期解を選択)
-		truncatedBody = utils.WrapCommentAsResolved(truncatedBody)
+		truncatedBody = utils.WrapCommentAsResolved(truncatedBody, client.limits.Comment, client.limits.Truncate)
 	}
 
 	// Create a draft review with the comment
 	var comment *githublib.PullRequestComment
 	err := RetryableOperation(ctx, func() error {
 		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
-		time.Sleep(1 * time.Second) // In general, no more than 80 content-generating requests per minute
-		var startLine *int
-		if input.StartLine != nil && input.LastLine != nil && *input.StartLine < *input.LastLine {
-			startLine = input.StartLine
-		}
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
 		prComment := &githublib.PullRequestComment{
 			// required
 			Body:     githublib.String(truncatedBody),
 			CommitID: githublib.String(input.Sha1),
 			Path:     githublib.String(input.Path),
 			// optional
-			StartLine: startLine,
-			Line:      input.LastLine, // For a multi-line comment, the last line of the range that your comment applies to.
+			Side: githublib.String(diffSide(input.Side)),
+			Line: input.LastLine, // For a multi-line comment, the last line of the range that your comment applies to.
+		}
+		if isMultiLineRange(input.StartSide, input.StartLine, input.Side, input.LastLine) {
+			prComment.StartSide = githublib.String(diffSide(input.StartSide))
+			prComment.StartLine = input.StartLine
 		}
 
 		var err error
@@ -393,28 +648,24 @@ type CreatePRCommentReplyInput struct {
 	PrNumber  int
 	Body      string
 	CommentID int64
-	Resolved  bool
 }
 
 // CreatePRCommentReply creates a reply to an existing review comment
-func (client *Client) CreatePRCommentReply(ctx context.Context, input *CreatePRCommentReplyInput) error {
-	logger.Debug("Creating PR review comment reply",
+func (client *Client) CreatePRCommentReply(ctx context.Context, input *CreatePRCommentReplyInput) (*githublib.PullRequestComment, error) {
+	logger.FromContext(ctx).Debug("Creating PR review comment reply",
 		"owner", input.Owner,
 		"repo", input.Repo,
 		"prNumber", input.PrNumber,
-		"commentID", input.CommentID,
-		"resolved", input.Resolved)
+		"commentID", input.CommentID)
 
 	// 文字数制限に合わせて切り詰める
-	truncatedBody := utils.TruncateText(input.Body, utils.MaxCommentLength)
-	if input.Resolved {
-		// resolveされている場合は折りたたむ (github apiでresolvedとするにはgraphql apiを利用する必要があり、手間がかかるため短期解を選択)
-		truncatedBody = utils.WrapCommentAsResolved(truncatedBody)
-	}
+	// resolveの状態はthread単位のため、replyは折りたたまず、先頭のコメントのみ折りたたむ
+	truncatedBody := utils.TruncateTextWithOptions(client.prepareBody(input.Body), client.limits.Comment, client.limits.Truncate)
 
+	var reply *githublib.PullRequestComment
 	err := RetryableOperation(ctx, func() error {
 		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#calculating-points-for-the-secondary-rate-limit
-		time.Sleep(1 * time.Second) // In general, no more than 80 content-generating requests per minute
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
 		comment := &struct {
 			Body string `json:"body,omitempty"`
 		}{
@@ -430,13 +681,14 @@ func (client *Client) CreatePRCommentReply(ctx context.Context, input *CreatePRC
 		resp, err = client.GetInner().Do(ctx, req, c)
 		xGitHubRequestId := resp.Header.Get("x-github-request-id")
 		if err != nil {
-			err = fmt.Errorf("%w, x-github-request-id: %s", err, xGitHubRequestId)
+			return fmt.Errorf("%w, x-github-request-id: %s", err, xGitHubRequestId)
 		}
-		return err
+		reply = c
+		return nil
 	})
 	if err != nil {
-		logger.Error("Failed to create comment reply", "error", err)
-		return err
+		logger.FromContext(ctx).Error("Failed to create comment reply", "error", err)
+		return nil, err
 	}
-	return nil
+	return reply, nil
 }
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCreateIssueCommentBodyTooLong(t *testing.T) {
+	const limit = 1000
+	tests := []struct {
+		name      string
+		rejects   int // 本文が長すぎるとして拒否する回数
+		wantCalls int
+		wantErr   bool
+	}{
+		{"accepted at once", 0, 1, false},
+		{"accepted after shortening twice", 2, 3, false},
+		{"accepted at the last attempt", bodyTooLongAttempts, bodyTooLongAttempts + 1, false},
+		{"rejected at every attempt", bodyTooLongAttempts + 1, bodyTooLongAttempts + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var lengths []int
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /repos/owner/repo/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+				var comment struct {
+					Body string `json:"body"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				mu.Lock()
+				lengths = append(lengths, utf8.RuneCountInString(comment.Body))
+				n := len(lengths)
+				mu.Unlock()
+				if n <= tt.rejects {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					fmt.Fprint(w, `{"message":"Validation Failed","errors":[{"resource":"IssueComment","code":"custom","field":"body","message":"body is too long (maximum is 65536 characters)"}]}`)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":1,"html_url":"https://github.com/owner/repo/pull/1#issuecomment-1"}`)
+			})
+			client := newTestClient(t, mux)
+			client.limits.Comment = limit
+
+			comment, err := client.CreateIssueComment(newTestContext(), "owner", "repo", 1, strings.Repeat("a", 2*limit), false)
+			var tooLong *BodyTooLongError
+			if tt.wantErr {
+				if !errors.As(err, &tooLong) {
+					t.Fatalf("CreateIssueComment() error = %v, want BodyTooLongError", err)
+				}
+				if tooLong.Limit != lengths[len(lengths)-1] {
+					t.Errorf("BodyTooLongError.Limit = %d, want the last body length %d", tooLong.Limit, lengths[len(lengths)-1])
+				}
+			} else if err != nil {
+				t.Fatalf("CreateIssueComment() error = %v", err)
+			} else if comment.GetID() != 1 {
+				t.Errorf("comment id = %d, want 1", comment.GetID())
+			}
+
+			if len(lengths) != tt.wantCalls {
+				t.Fatalf("requests = %d, want %d", len(lengths), tt.wantCalls)
+			}
+			// 拒否されるたびに上限の3/4まで短くして再試行する
+			want := limit
+			for i, length := range lengths {
+				if length != want {
+					t.Errorf("body length of request %d = %d, want %d", i+1, length, want)
+				}
+				want = want * 3 / 4
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultRetryJitter is the default ratio of the random jitter added to retry backoff
+const DefaultRetryJitter = 0.2
+
+// RetryConfig holds the backoff settings of RetryableOperation
+type RetryConfig struct {
+	MaxRetries    int
+	InitialDelay  time.Duration
+	MaxDelay      time.Duration
+	BackoffFactor float64
+	// Jitter はbackoffに加えるランダムな揺らぎの割合 (0の場合は揺らぎを加えない)
+	Jitter float64
+	// Float64 は[0.0, 1.0)の乱数を返す (nilの場合はmath/randのグローバルな乱数を利用する)
+	Float64 func() float64
+	// CircuitBreaker は連続して失敗した場合に以降の操作を打ち切る (nilの場合は打ち切らない)
+	CircuitBreaker *CircuitBreaker
+}
+
+// DefaultCircuitBreakerThreshold is the default number of consecutive failed operations before giving up
+const DefaultCircuitBreakerThreshold = 10
+
+// ErrGitHubUnavailable is returned for every operation once the circuit breaker is open
+var ErrGitHubUnavailable = errors.New("GitHub appears to be down")
+
+// CircuitBreaker stops GitHub API operations after consecutive operations failed with server errors or network failures even after retries
+type CircuitBreaker struct {
+	threshold int
+	mu        sync.Mutex
+	failures  int
+}
+
+// NewCircuitBreaker creates a circuit breaker opening after threshold consecutive failed operations
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold}
+}
+
+// Err returns ErrGitHubUnavailable if the circuit breaker is open
+func (b *CircuitBreaker) Err() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	return fmt.Errorf("%w: %d consecutive operations failed after retries", ErrGitHubUnavailable, b.failures)
+}
+
+// record counts an operation which failed after retries as GitHub was unavailable, or resets the count when GitHub responded
+func (b *CircuitBreaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if failed {
+		b.failures++
+	} else {
+		b.failures = 0
+	}
+}
+
+type retryConfigKey struct{}
+
+// DefaultRetryConfig returns the retry settings used when the context carries none
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:    5,
+		InitialDelay:  1 * time.Second,
+		MaxDelay:      60 * time.Second,
+		BackoffFactor: 2.0,
+		Jitter:        DefaultRetryJitter,
+	}
+}
+
+// NewRetryContext returns a context carrying the retry settings
+func NewRetryContext(ctx context.Context, cfg RetryConfig) context.Context {
+	return context.WithValue(ctx, retryConfigKey{}, cfg)
+}
+
+// RetryConfigFromContext returns the retry settings carried by the context, or the default settings
+func RetryConfigFromContext(ctx context.Context) RetryConfig {
+	if cfg, ok := ctx.Value(retryConfigKey{}).(RetryConfig); ok {
+		return cfg
+	}
+	return DefaultRetryConfig()
+}
+
+// CircuitBreakerError returns ErrGitHubUnavailable if the circuit breaker carried by the context is open
+func CircuitBreakerError(ctx context.Context) error {
+	return RetryConfigFromContext(ctx).CircuitBreaker.Err()
+}
+
+// backoff computes the backoff duration using exponential backoff with jitter
+// 揺らぎを加えた結果は[min(delay*(1-Jitter), maxDelay), min(delay*(1+Jitter), maxDelay)]に収まる
+func (c RetryConfig) backoff(attempt int, initialDelay, maxDelay time.Duration) time.Duration {
+	// Calculate exponential backoff
+	backoff := float64(initialDelay) * math.Pow(c.BackoffFactor, float64(attempt))
+
+	if c.Jitter > 0 {
+		random := c.Float64
+		if random == nil {
+			random = rand.Float64
+		}
+		backoff += backoff * c.Jitter * (random()*2 - 1)
+	}
+
+	// Ensure we don't exceed max delay
+	if backoff > float64(maxDelay) {
+		backoff = float64(maxDelay)
+	}
+
+	return time.Duration(backoff)
+}
@@ -0,0 +1,149 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+)
+
+func TestRetryConfigBackoff(t *testing.T) {
+	const (
+		initialDelay = time.Second
+		maxDelay     = 10 * time.Second
+	)
+	tests := []struct {
+		name    string
+		attempt int
+		jitter  float64
+		random  float64
+		want    time.Duration
+	}{
+		{"no jitter", 1, 0, 0.9, 2 * time.Second},
+		{"lowest jitter", 1, 0.2, 0, 1600 * time.Millisecond},
+		{"middle jitter", 1, 0.2, 0.5, 2 * time.Second},
+		{"highest jitter", 1, 0.2, 0.75, 2200 * time.Millisecond},
+		{"clamped to max delay", 4, 0.2, 0.75, maxDelay},
+		{"jitter below max delay", 3, 0.5, 0, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := RetryConfig{BackoffFactor: 2, Jitter: tt.jitter, Float64: func() float64 { return tt.random }}
+			if got := cfg.backoff(tt.attempt, initialDelay, maxDelay); got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryConfigBackoffBounds(t *testing.T) {
+	const (
+		initialDelay = 100 * time.Millisecond
+		maxDelay     = 5 * time.Second
+	)
+	random := rand.New(rand.NewSource(1))
+	for _, jitter := range []float64{0.1, 0.2, 0.5} {
+		cfg := RetryConfig{BackoffFactor: 2, Jitter: jitter, Float64: random.Float64}
+		for attempt := 0; attempt < 8; attempt++ {
+			delay := float64(initialDelay) * math.Pow(cfg.BackoffFactor, float64(attempt))
+			lower := time.Duration(math.Min(delay*(1-jitter), float64(maxDelay)))
+			upper := time.Duration(math.Min(delay*(1+jitter), float64(maxDelay)))
+			for i := 0; i < 100; i++ {
+				got := cfg.backoff(attempt, initialDelay, maxDelay)
+				if got < lower || got > upper {
+					t.Fatalf("backoff(%d) with jitter %v = %v, want within [%v, %v]", attempt, jitter, got, lower, upper)
+				}
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  []bool // recordに渡す、操作が失敗したかどうか
+		wantOpen bool
+	}{
+		{"below the threshold", []bool{true, true}, false},
+		{"reaches the threshold", []bool{true, true, true}, true},
+		{"reset by a response", []bool{true, true, false, true, true}, false},
+		{"reaches the threshold after a reset", []bool{true, false, true, true, true}, true},
+		{"stays open", []bool{true, true, true, true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(3)
+			for _, failed := range tt.records {
+				b.record(failed)
+			}
+			err := b.Err()
+			if (err != nil) != tt.wantOpen {
+				t.Fatalf("Err() = %v, want open %v", err, tt.wantOpen)
+			}
+			if err != nil && !errors.Is(err, ErrGitHubUnavailable) {
+				t.Errorf("Err() = %v, want ErrGitHubUnavailable", err)
+			}
+		})
+	}
+
+	t.Run("nil breaker never opens", func(t *testing.T) {
+		var b *CircuitBreaker
+		for i := 0; i < DefaultCircuitBreakerThreshold+1; i++ {
+			b.record(true)
+		}
+		if err := b.Err(); err != nil {
+			t.Errorf("Err() = %v, want nil", err)
+		}
+	})
+}
+
+func TestRetryableOperationCircuitBreaker(t *testing.T) {
+	delay, maxDelay := submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay
+	submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		submittedTooQuicklyDelay, submittedTooQuicklyMaxDelay = delay, maxDelay
+	})
+	errorResponse := func(status int, message string) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: status}, Message: message}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantOpen bool
+	}{
+		{"server errors", errorResponse(http.StatusBadGateway, "Bad Gateway"), true},
+		{"network failures", &url.Error{Op: "Post", URL: "https://api.github.com", Err: errors.New("connection refused")}, true},
+		{"graphql server errors", errors.New(`non-200 OK status code: 503 Service Unavailable body: ""`), true},
+		{"submitted too quickly", errorResponse(http.StatusUnprocessableEntity, "was submitted too quickly"), false},
+		{"too many requests", errorResponse(http.StatusTooManyRequests, "Too Many Requests"), false},
+		{"validation errors", errorResponse(http.StatusUnprocessableEntity, "Validation Failed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const threshold = 2
+			ctx := NewRetryContext(context.Background(), RetryConfig{
+				MaxRetries:     2,
+				InitialDelay:   time.Millisecond,
+				MaxDelay:       time.Millisecond,
+				BackoffFactor:  1,
+				CircuitBreaker: NewCircuitBreaker(threshold),
+			})
+			for i := 0; i < threshold; i++ {
+				if err := RetryableOperation(ctx, func() error { return tt.err }); err == nil {
+					t.Fatal("RetryableOperation() error = nil, want the operation error")
+				}
+			}
+			err := CircuitBreakerError(ctx)
+			if (err != nil) != tt.wantOpen {
+				t.Errorf("CircuitBreakerError() = %v, want open %v", err, tt.wantOpen)
+			}
+		})
+	}
+}
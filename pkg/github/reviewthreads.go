@@ -0,0 +1,247 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+	"github.com/shurcooL/githubv4"
+)
+
+// ErrReviewThreadNotCreated is returned when creating the review thread failed without posting anything
+var ErrReviewThreadNotCreated = errors.New("review thread not created")
+
+// CreateReviewThreadInput holds a review thread created through the GraphQL API
+type CreateReviewThreadInput struct {
+	PullRequestNodeID string
+	Sha1              string
+	Path              string
+	StartSide         string // 範囲の開始行のdiffの側 (SideLeft, SideRight)
+	StartLine         *int
+	Side              string // 範囲の最終行のdiffの側 (空の場合はSideRight)
+	LastLine          *int
+	Body              string   // 先頭のコメント
+	Replies           []string // 先頭のコメントに続くreply
+	Resolved          bool
+}
+
+// CreateReviewThread creates a review with a single thread, its replies and the resolution through the GraphQL API
+// 返り値は作成したコメントのURLで、先頭のコメント、replyの順に並ぶ
+// 何も作成せずに失敗した場合はErrReviewThreadNotCreatedを返す
+// pending reviewはPRごとに1つしか作成できないため、並行して作成しないようにする
+func (client *Client) CreateReviewThread(ctx context.Context, input *CreateReviewThreadInput) ([]string, error) {
+	client.reviewMu.Lock()
+	defer client.reviewMu.Unlock()
+
+	logger.FromContext(ctx).Debug("Creating PR review thread",
+		"path", input.Path,
+		"startLine", input.StartLine,
+		"lastLine", input.LastLine,
+		"replies", len(input.Replies),
+		"resolved", input.Resolved)
+
+	reviewID, err := client.addPendingReview(ctx, input.PullRequestNodeID, input.Sha1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReviewThreadNotCreated, err)
+	}
+
+	// pending reviewのまま失敗した場合は削除し、何も作成されていない状態に戻す
+	// 残ったpending reviewは以降のreviewの作成を妨げる
+	abort := func(err error) ([]string, error) {
+		if derr := client.deletePendingReview(ctx, reviewID); derr != nil {
+			logger.FromContext(ctx).Warn("Failed to delete pending review", "error", derr)
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrReviewThreadNotCreated, err)
+	}
+
+	threadID, headURL, err := client.addReviewThread(ctx, reviewID, input)
+	if err != nil {
+		return abort(err)
+	}
+	urls := []string{headURL}
+	for _, reply := range input.Replies {
+		replyURL, err := client.addReviewThreadReply(ctx, reviewID, threadID, reply)
+		if err != nil {
+			return abort(err)
+		}
+		urls = append(urls, replyURL)
+	}
+	if err := client.submitReview(ctx, reviewID); err != nil {
+		return abort(err)
+	}
+
+	if input.Resolved {
+		// resolveに失敗してもコメント自体は作成済みのため、警告に留める
+		if err := client.resolveReviewThread(ctx, threadID); err != nil {
+			logger.FromContext(ctx).Warn("Failed to resolve review thread", "error", err)
+		}
+	}
+	return urls, nil
+}
+
+// addPendingReview starts a pending review of the pull request on the commit
+func (client *Client) addPendingReview(ctx context.Context, pullRequestNodeID, sha1 string) (githubv4.ID, error) {
+	var mutation struct {
+		AddPullRequestReview struct {
+			PullRequestReview struct {
+				ID githubv4.ID
+			}
+		} `graphql:"addPullRequestReview(input: $input)"`
+	}
+	commitOID := githubv4.GitObjectID(sha1)
+	input := githubv4.AddPullRequestReviewInput{
+		PullRequestID: githubv4.ID(pullRequestNodeID),
+		CommitOID:     &commitOID,
+	}
+	err := RetryableOperation(ctx, func() error {
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add pending review: %w", err)
+	}
+	return mutation.AddPullRequestReview.PullRequestReview.ID, nil
+}
+
+// addReviewThread adds the first comment of the thread to the pending review and returns the thread and the URL of the comment
+func (client *Client) addReviewThread(ctx context.Context, reviewID githubv4.ID, input *CreateReviewThreadInput) (githubv4.ID, string, error) {
+	var mutation struct {
+		AddPullRequestReviewThread struct {
+			Thread struct {
+				ID       githubv4.ID
+				Comments struct {
+					Nodes []struct {
+						URL githubv4.URI
+					}
+				} `graphql:"comments(first: 1)"`
+			}
+		} `graphql:"addPullRequestReviewThread(input: $input)"`
+	}
+	side := githubv4.DiffSide(diffSide(input.Side))
+	threadInput := githubv4.AddPullRequestReviewThreadInput{
+		PullRequestReviewID: &reviewID,
+		Path:                githubv4.String(input.Path),
+		Body:                githubv4.String(utils.TruncateTextWithOptions(client.prepareBody(input.Body), client.limits.Comment, client.limits.Truncate)),
+		Side:                &side,
+	}
+	if input.LastLine != nil {
+		line := githubv4.Int(*input.LastLine)
+		threadInput.Line = &line
+	}
+	if isMultiLineRange(input.StartSide, input.StartLine, input.Side, input.LastLine) {
+		startLine := githubv4.Int(*input.StartLine)
+		startSide := githubv4.DiffSide(diffSide(input.StartSide))
+		threadInput.StartLine = &startLine
+		threadInput.StartSide = &startSide
+	}
+	err := RetryableOperation(ctx, func() error {
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
+		return client.GetV4().Mutate(ctx, &mutation, threadInput, nil)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to add review thread: %w", err)
+	}
+	// 行がdiffの範囲外の場合などは、エラーではなくthreadがnullで返される
+	thread := mutation.AddPullRequestReviewThread.Thread
+	if thread.ID == nil {
+		return nil, "", fmt.Errorf("failed to add review thread: no thread returned for %s", input.Path)
+	}
+	headURL := ""
+	if len(thread.Comments.Nodes) > 0 {
+		headURL = uriString(thread.Comments.Nodes[0].URL)
+	}
+	return thread.ID, headURL, nil
+}
+
+// addReviewThreadReply adds a reply to the thread in the pending review and returns the URL of the reply
+func (client *Client) addReviewThreadReply(ctx context.Context, reviewID, threadID githubv4.ID, body string) (string, error) {
+	var mutation struct {
+		AddPullRequestReviewThreadReply struct {
+			Comment struct {
+				ID  githubv4.ID
+				URL githubv4.URI
+			}
+		} `graphql:"addPullRequestReviewThreadReply(input: $input)"`
+	}
+	input := githubv4.AddPullRequestReviewThreadReplyInput{
+		PullRequestReviewThreadID: threadID,
+		PullRequestReviewID:       &reviewID,
+		Body:                      githubv4.String(utils.TruncateTextWithOptions(client.prepareBody(body), client.limits.Comment, client.limits.Truncate)),
+	}
+	err := RetryableOperation(ctx, func() error {
+		client.waitContentInterval() // In general, no more than 80 content-generating requests per minute
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to add review thread reply: %w", err)
+	}
+	return uriString(mutation.AddPullRequestReviewThreadReply.Comment.URL), nil
+}
+
+// uriString returns the URI as a string, or empty if the URI is not returned
+func uriString(uri githubv4.URI) string {
+	if uri.URL == nil {
+		return ""
+	}
+	return uri.String()
+}
+
+// submitReview submits the pending review as a comment review
+func (client *Client) submitReview(ctx context.Context, reviewID githubv4.ID) error {
+	var mutation struct {
+		SubmitPullRequestReview struct {
+			ClientMutationID githubv4.String
+		} `graphql:"submitPullRequestReview(input: $input)"`
+	}
+	input := githubv4.SubmitPullRequestReviewInput{
+		PullRequestReviewID: &reviewID,
+		Event:               githubv4.PullRequestReviewEventComment,
+	}
+	err := RetryableOperation(ctx, func() error {
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to submit review: %w", err)
+	}
+	return nil
+}
+
+// deletePendingReview deletes a pending review left by a failed thread creation
+func (client *Client) deletePendingReview(ctx context.Context, reviewID githubv4.ID) error {
+	var mutation struct {
+		DeletePullRequestReview struct {
+			ClientMutationID githubv4.String
+		} `graphql:"deletePullRequestReview(input: $input)"`
+	}
+	input := githubv4.DeletePullRequestReviewInput{
+		PullRequestReviewID: reviewID,
+	}
+	err := RetryableOperation(ctx, func() error {
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete pending review: %w", err)
+	}
+	return nil
+}
+
+// resolveReviewThread marks the review thread as resolved
+func (client *Client) resolveReviewThread(ctx context.Context, threadID githubv4.ID) error {
+	var mutation struct {
+		ResolveReviewThread struct {
+			ClientMutationID githubv4.String
+		} `graphql:"resolveReviewThread(input: $input)"`
+	}
+	input := githubv4.ResolveReviewThreadInput{
+		ThreadID: threadID,
+	}
+	err := RetryableOperation(ctx, func() error {
+		return client.GetV4().Mutate(ctx, &mutation, input, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve review thread: %w", err)
+	}
+	return nil
+}
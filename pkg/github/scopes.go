@@ -0,0 +1,51 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	githublib "github.com/google/go-github/v70/github"
+)
+
+// impliedScopes are the narrower OAuth scopes granted by a GitHub token scope
+var impliedScopes = map[string][]string{
+	"repo":      {"public_repo", "repo:status"},
+	"admin:org": {"write:org", "read:org"},
+	"write:org": {"read:org"},
+}
+
+// MissingTokenScopes returns the scopes in required not granted to the token of the client
+// fine-grained tokenやGitHub Appのtokenはscopeを返さないため、確認できない場合はfalseを返す
+func (client *Client) MissingTokenScopes(ctx context.Context, required []string) ([]string, bool, error) {
+	var resp *githublib.Response
+	err := RetryableOperation(ctx, func() error {
+		var err error
+		// rate limitの取得はrate limitを消費せず、どの認証方式でも利用できる
+		_, resp, err = client.GetInner().RateLimit.Get(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get GitHub token scopes: %w", err)
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil, false, nil
+	}
+
+	granted := map[string]bool{}
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		scope = strings.TrimSpace(scope)
+		granted[scope] = true
+		for _, implied := range impliedScopes[scope] {
+			granted[implied] = true
+		}
+	}
+	var missing []string
+	for _, scope := range required {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing, true, nil
+}
@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	githublib "github.com/google/go-github/v70/github"
+)
+
+// Commit status states accepted by GitHub
+const (
+	StatusStateSuccess = "success"
+	StatusStateFailure = "failure"
+	StatusStateError   = "error"
+	StatusStatePending = "pending"
+)
+
+// CreateCommitStatusInput contains the parameters of a commit status
+type CreateCommitStatusInput struct {
+	Owner       string
+	Repo        string
+	SHA         string
+	State       string // StatusStateSuccess, StatusStateFailure, StatusStateError, StatusStatePending
+	Context     string // 同じcontextのstatusは上書きされる
+	TargetURL   string
+	Description string
+}
+
+// CommitExists checks if the commit exists in the repository
+func (client *Client) CommitExists(ctx context.Context, owner, repo, sha string) (bool, error) {
+	var exists bool
+	err := RetryableOperation(ctx, func() error {
+		_, resp, err := client.GetInner().Repositories.GetCommitSHA1(ctx, owner, repo, sha, "")
+		if err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+				exists = false
+				return nil
+			}
+			return err
+		}
+		exists = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get commit %s: %w", sha, err)
+	}
+	return exists, nil
+}
+
+// CreateCommitStatus creates a commit status
+func (client *Client) CreateCommitStatus(ctx context.Context, input *CreateCommitStatusInput) error {
+	status := &githublib.RepoStatus{
+		State:       githublib.String(input.State),
+		Context:     githublib.String(input.Context),
+		Description: githublib.String(input.Description),
+	}
+	if input.TargetURL != "" {
+		status.TargetURL = githublib.String(input.TargetURL)
+	}
+	err := RetryableOperation(ctx, func() error {
+		client.waitContentInterval()
+		_, _, err := client.GetInner().Repositories.CreateStatus(ctx, input.Owner, input.Repo, input.SHA, status)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create commit status: %w", err)
+	}
+	return nil
+}
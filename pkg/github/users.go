@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+)
+
+// ListOrganizationMembers retrieves all members of the organization with their names and public emails
+// メンバー一覧にはloginしか含まれないため、ユーザーごとに詳細を取得する
+func (client *Client) ListOrganizationMembers(ctx context.Context, org string) ([]*githublib.User, error) {
+	opts := &githublib.ListMembersOptions{
+		ListOptions: githublib.ListOptions{PerPage: 100},
+	}
+
+	var logins []string
+	for {
+		var members []*githublib.User
+		var resp *githublib.Response
+		err := RetryableOperation(ctx, func() error {
+			var err error
+			members, resp, err = client.GetInner().Organizations.ListMembers(ctx, org, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization members: %w", err)
+		}
+		for _, member := range members {
+			logins = append(logins, member.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	users := make([]*githublib.User, 0, len(logins))
+	for _, login := range logins {
+		var user *githublib.User
+		err := RetryableOperation(ctx, func() error {
+			var err error
+			user, _, err = client.GetInner().Users.Get(ctx, login)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user %s: %w", login, err)
+		}
+		users = append(users, user)
+	}
+	logger.FromContext(ctx).Debug("Listed organization members", "org", org, "members", len(users))
+	return users, nil
+}
@@ -0,0 +1,55 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// ApprovalRequirement summarizes the project level approval configuration
+type ApprovalRequirement struct {
+	// RequiredApprovals is the largest number of approvals required by a rule
+	RequiredApprovals int
+	// ResetApprovalsOnPush removes approvals when new commits are pushed
+	ResetApprovalsOnPush bool
+	// HasCodeOwnerRule indicates that code owner approval is required
+	HasCodeOwnerRule bool
+}
+
+// GetProjectApprovalRequirement retrieves the approval configuration and rules of a GitLab project
+func GetProjectApprovalRequirement(ctx context.Context, client *gitlab.Client, projectID string) (*ApprovalRequirement, error) {
+	config, _, err := client.Projects.GetApprovalConfiguration(projectID, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitLab approval configuration: %w", classifyError(err))
+	}
+	rules, err := GetProjectApprovalRules(ctx, client, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// approvals_before_mergeは古い設定のため、ruleと合わせて最も厳しい値を採用する
+	requirement := &ApprovalRequirement{
+		RequiredApprovals:    int(config.ApprovalsBeforeMerge),
+		ResetApprovalsOnPush: config.ResetApprovalsOnPush,
+	}
+	for _, rule := range rules {
+		if rule.RuleType == "code_owner" {
+			requirement.HasCodeOwnerRule = true
+			continue
+		}
+		if int(rule.ApprovalsRequired) > requirement.RequiredApprovals {
+			requirement.RequiredApprovals = int(rule.ApprovalsRequired)
+		}
+	}
+	return requirement, nil
+}
+
+// GetProjectApprovalRules retrieves the approval rules of a GitLab project
+func GetProjectApprovalRules(ctx context.Context, client *gitlab.Client, projectID string) ([]*gitlab.ProjectApprovalRule, error) {
+	rules, _, err := client.Projects.GetProjectApprovalRules(projectID, &gitlab.GetProjectApprovalRulesListsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitLab approval rules: %w", classifyError(err))
+	}
+	return rules, nil
+}
@@ -0,0 +1,66 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// NewClient creates a GitLab client whose requests time out after the timeout
+// 0を指定した場合はタイムアウトしない
+func NewClient(token, baseURL string, timeout time.Duration) (*gitlab.Client, error) {
+	return gitlab.NewClient(token,
+		gitlab.WithBaseURL(baseURL),
+		gitlab.WithHTTPClient(&http.Client{Timeout: timeout}),
+		gitlab.WithCustomRetry(retryHTTPCheck),
+	)
+}
+
+// retryHTTPCheck retries rate limited requests, server errors and timed out requests
+// go-gitlabのデフォルトではエラーとなったリクエストは再試行されないため、タイムアウトした場合も再試行する
+func retryHTTPCheck(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true, nil
+		}
+		return false, err
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, nil
+}
+
+// impliedScopes are the narrower token scopes granted by a GitLab token scope
+var impliedScopes = map[string][]string{
+	"api":              {"read_api"},
+	"write_repository": {"read_repository"},
+}
+
+// MissingTokenScopes returns the scopes in required not granted to the personal access token of the client
+func MissingTokenScopes(ctx context.Context, client *gitlab.Client, required []string) ([]string, error) {
+	token, _, err := client.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitLab token scopes: %w", classifyError(err))
+	}
+	granted := map[string]bool{}
+	for _, scope := range token.Scopes {
+		granted[scope] = true
+		for _, implied := range impliedScopes[scope] {
+			granted[implied] = true
+		}
+	}
+	var missing []string
+	for _, scope := range required {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,41 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesTimedOutRequests(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初のリクエストはクライアントのタイムアウトより長く応答しない
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"archived":true}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient("token", srv.URL, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := client.Projects.GetProject("group/project", nil)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if !project.Archived {
+		t.Errorf("project = %+v, want the response of the retried request", project)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
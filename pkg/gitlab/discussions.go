@@ -1,7 +1,9 @@
 package gitlab
 
 import (
-	"github.com/xanzy/go-gitlab"
+	"context"
+
+	"gitlab.com/gitlab-org/api/client-go"
 )
 
 // DiscussionNote represents a note within a discussion with parent-child relationships
@@ -11,27 +13,49 @@ type DiscussionNote struct {
 	Discussion string // Discussion ID this note belongs to
 }
 
+const (
+	// DiscussionOrderOldest keeps the oldest discussions when limiting the discussion count
+	DiscussionOrderOldest = "oldest"
+	// DiscussionOrderNewest keeps the newest discussions when limiting the discussion count
+	DiscussionOrderNewest = "newest"
+)
+
 // GetMergeRequestDiscussions retrieves discussions from a GitLab merge request
-func GetMergeRequestDiscussions(client *gitlab.Client, projectID string, mrIID, maxDiscussions int) ([]*gitlab.Discussion, error) {
+// maxDiscussionsを指定した場合、orderに従って古いものか新しいものを残す
+// 新しいものを残す場合は、すべてのdiscussionを取得する必要がある
+func GetMergeRequestDiscussions(ctx context.Context, client *gitlab.Client, projectID string, mrIID int, maxDiscussions int, order string) ([]*gitlab.Discussion, error) {
 	// Get all discussions for the MR
 	var ret []*gitlab.Discussion
-	var page = 1
+	var page int64 = 1
 	for {
-		discussions, _, err := client.Discussions.ListMergeRequestDiscussions(projectID, mrIID, &gitlab.ListMergeRequestDiscussionsOptions{
-			PerPage: 100,
-			Page:    page,
-		})
+		discussions, _, err := client.Discussions.ListMergeRequestDiscussions(projectID, int64(mrIID), &gitlab.ListMergeRequestDiscussionsOptions{
+			ListOptions: gitlab.ListOptions{
+				PerPage: 100,
+				Page:    page,
+			},
+		}, gitlab.WithContext(ctx))
 		if err != nil {
-			return nil, err
+			return nil, classifyError(err)
 		}
 		ret = append(ret, discussions...)
 		if len(discussions) < 100 {
 			break
 		}
-		if maxDiscussions > 0 && len(ret) >= maxDiscussions {
+		if maxDiscussions > 0 && len(ret) >= maxDiscussions && order != DiscussionOrderNewest {
 			break
 		}
 		page += 1
 	}
-	return ret, nil
+	return LimitDiscussions(ret, maxDiscussions, order), nil
+}
+
+// LimitDiscussions keeps at most maxDiscussions discussions in the order, preserving their chronological order
+func LimitDiscussions(discussions []*gitlab.Discussion, maxDiscussions int, order string) []*gitlab.Discussion {
+	if maxDiscussions <= 0 || len(discussions) <= maxDiscussions {
+		return discussions
+	}
+	if order == DiscussionOrderNewest {
+		return discussions[len(discussions)-maxDiscussions:]
+	}
+	return discussions[:maxDiscussions]
 }
@@ -0,0 +1,71 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// NotFoundError indicates that the GitLab resource does not exist or is not visible to the token, such as a deleted merge request
+type NotFoundError struct {
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("GitLab resource not found: %v", e.Err)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError indicates that GitLab kept rejecting the request by the rate limit even after retries
+type RateLimitError struct {
+	// RetryAfter はGitLabが指定した再試行までの待機時間 (分からない場合は0)
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("GitLab rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("GitLab rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError converts an error response of GitLab into NotFoundError or RateLimitError by its status code
+// それ以外のエラーはそのまま返す
+func classifyError(err error) error {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return err
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusNotFound:
+		return &NotFoundError{Err: err}
+	case http.StatusTooManyRequests:
+		return &RateLimitError{RetryAfter: retryAfter(errResp.Response), Err: err}
+	}
+	return err
+}
+
+// retryAfter returns the wait GitLab asks for with the Retry-After or RateLimit-Reset header
+func retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		if d := time.Until(time.Unix(reset, 0)); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// newErrorResponse returns a GitLab error response of the status code with the headers
+func newErrorResponse(statusCode int, header map[string]string) error {
+	resp := &http.Response{StatusCode: statusCode, Header: http.Header{}}
+	for k, v := range header {
+		resp.Header.Set(k, v)
+	}
+	return &gitlab.ErrorResponse{Response: resp, Message: http.StatusText(statusCode)}
+}
+
+func TestClassifyError(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		name          string
+		err           error
+		wantNotFound  bool
+		wantRateLimit bool
+		minRetryAfter time.Duration
+		maxRetryAfter time.Duration
+		wantUnchanged bool
+	}{
+		{name: "not found", err: newErrorResponse(http.StatusNotFound, nil), wantNotFound: true},
+		{name: "wrapped not found", err: fmt.Errorf("failed: %w", newErrorResponse(http.StatusNotFound, nil)), wantNotFound: true},
+		{
+			name:          "rate limited with Retry-After",
+			err:           newErrorResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}),
+			wantRateLimit: true,
+			minRetryAfter: 30 * time.Second,
+			maxRetryAfter: 30 * time.Second,
+		},
+		{
+			name:          "rate limited with RateLimit-Reset",
+			err:           newErrorResponse(http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": strconv.FormatInt(reset, 10)}),
+			wantRateLimit: true,
+			minRetryAfter: 50 * time.Second,
+			maxRetryAfter: time.Minute,
+		},
+		{
+			name:          "rate limited with Retry-After over RateLimit-Reset",
+			err:           newErrorResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "5", "RateLimit-Reset": strconv.FormatInt(reset, 10)}),
+			wantRateLimit: true,
+			minRetryAfter: 5 * time.Second,
+			maxRetryAfter: 5 * time.Second,
+		},
+		{
+			name:          "rate limited with a past RateLimit-Reset",
+			err:           newErrorResponse(http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": "1"}),
+			wantRateLimit: true,
+		},
+		{name: "rate limited without headers", err: newErrorResponse(http.StatusTooManyRequests, nil), wantRateLimit: true},
+		{name: "forbidden", err: newErrorResponse(http.StatusForbidden, nil), wantUnchanged: true},
+		{name: "server error", err: newErrorResponse(http.StatusInternalServerError, nil), wantUnchanged: true},
+		{name: "error response without response", err: &gitlab.ErrorResponse{Message: "broken"}, wantUnchanged: true},
+		{name: "network error", err: errors.New("connection refused"), wantUnchanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if tt.wantUnchanged && got != tt.err {
+				t.Errorf("classifyError() = %v, want the error unchanged", got)
+			}
+
+			var notFound *NotFoundError
+			if errors.As(got, &notFound) != tt.wantNotFound {
+				t.Errorf("classifyError() = %T, want NotFoundError %v", got, tt.wantNotFound)
+			}
+
+			var rateLimit *RateLimitError
+			if errors.As(got, &rateLimit) != tt.wantRateLimit {
+				t.Fatalf("classifyError() = %T, want RateLimitError %v", got, tt.wantRateLimit)
+			}
+			if rateLimit != nil && (rateLimit.RetryAfter < tt.minRetryAfter || rateLimit.RetryAfter > tt.maxRetryAfter) {
+				t.Errorf("RetryAfter = %v, want within [%v, %v]", rateLimit.RetryAfter, tt.minRetryAfter, tt.maxRetryAfter)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("classifyError() = %v, does not wrap the original error", got)
+			}
+		})
+	}
+}
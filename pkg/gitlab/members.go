@@ -0,0 +1,29 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// GetProjectMembers retrieves all members of a GitLab project, including the members inherited from its groups
+func GetProjectMembers(ctx context.Context, client *gitlab.Client, projectID string) ([]*gitlab.ProjectMember, error) {
+	opts := &gitlab.ListProjectMembersOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	var ret []*gitlab.ProjectMember
+	for {
+		members, resp, err := client.ProjectMembers.ListAllProjectMembers(projectID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitLab project members: %w", classifyError(err))
+		}
+		ret = append(ret, members...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
@@ -1,11 +1,13 @@
 package gitlab
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
-	"github.com/xanzy/go-gitlab"
+	"gitlab.com/gitlab-org/api/client-go"
 )
 
 // ApprovalInfo はマージリクエストの承認情報を格納する構造体
@@ -15,48 +17,96 @@ type ApprovalInfo struct {
 }
 
 // GetMergeRequests retrieves merge requests from GitLab project
-func GetMergeRequests(client *gitlab.Client, projectID string, page int) ([]*gitlab.MergeRequest, error) {
+func GetMergeRequests(ctx context.Context, client *gitlab.Client, projectID string, page int) ([]*gitlab.BasicMergeRequest, error) {
 	// List all merge requests from GitLab
 	opts := &gitlab.ListProjectMergeRequestsOptions{
-		OrderBy: gitlab.String("created_at"),
-		Sort:    gitlab.String("asc"),
+		OrderBy: gitlab.Ptr("created_at"),
+		Sort:    gitlab.Ptr("asc"),
 		ListOptions: gitlab.ListOptions{
 			PerPage: 100,
-			Page:    page,
+			Page:    int64(page),
 		},
 	}
 
-	mrs, _, err := client.MergeRequests.ListProjectMergeRequests(projectID, opts)
-	return mrs, err
+	mrs, _, err := client.MergeRequests.ListProjectMergeRequests(projectID, opts, gitlab.WithContext(ctx))
+	return mrs, classifyError(err)
 }
 
 // HasMergeRequestDiffs retrieves mr diffs
-func HasMergeRequestDiffs(client *gitlab.Client, projectID string, mrIID int) (bool, error) {
+func HasMergeRequestDiffs(ctx context.Context, client *gitlab.Client, projectID string, mrIID int) (bool, error) {
 	opts := &gitlab.ListMergeRequestDiffsOptions{
 		ListOptions: gitlab.ListOptions{
 			PerPage: 1,
 		},
 	}
 
-	diffs, _, err := client.MergeRequests.ListMergeRequestDiffs(projectID, mrIID, opts)
+	diffs, _, err := client.MergeRequests.ListMergeRequestDiffs(projectID, int64(mrIID), opts, gitlab.WithContext(ctx))
 	if err != nil {
-		return false, fmt.Errorf("failed to list GitLab list mr diffs: %w", err)
+		return false, fmt.Errorf("failed to list GitLab list mr diffs: %w", classifyError(err))
 	}
 	return len(diffs) > 0, nil
 }
 
+// GetMergeRequestDiffVersions retrieves all diff versions of a GitLab merge request
+func GetMergeRequestDiffVersions(ctx context.Context, client *gitlab.Client, projectID string, mrIID int) ([]*gitlab.MergeRequestDiffVersion, error) {
+	opts := &gitlab.GetMergeRequestDiffVersionsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var allVersions []*gitlab.MergeRequestDiffVersion
+	for {
+		versions, resp, err := client.MergeRequests.GetMergeRequestDiffVersions(projectID, int64(mrIID), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitLab MR diff versions: %w", classifyError(err))
+		}
+		allVersions = append(allVersions, versions...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allVersions, nil
+}
+
+// GetLatestMergeRequestVersionSHA returns the head SHA of the newest diff version accepted by isAvailable
+// force pushによってMRのhead shaが失われている場合に、取得可能な最新のdiff versionのhead shaを探すために利用する
+func GetLatestMergeRequestVersionSHA(ctx context.Context, client *gitlab.Client, projectID string, mrIID int, isAvailable func(sha string) bool) (string, error) {
+	versions, err := GetMergeRequestDiffVersions(ctx, client, projectID, mrIID)
+	if err != nil {
+		return "", err
+	}
+	return latestVersionSHA(versions, isAvailable), nil
+}
+
+// latestVersionSHA returns the head SHA of the newest version accepted by isAvailable, or empty if none
+func latestVersionSHA(versions []*gitlab.MergeRequestDiffVersion, isAvailable func(sha string) bool) string {
+	sorted := make([]*gitlab.MergeRequestDiffVersion, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID > sorted[j].ID
+	})
+	for _, version := range sorted {
+		if version.HeadCommitSHA != "" && isAvailable(version.HeadCommitSHA) {
+			return version.HeadCommitSHA
+		}
+	}
+	return ""
+}
+
 // GetMergeRequestApprovals retrieves approval information for a GitLab merge request
-func GetMergeRequestApprovals(client *gitlab.Client, projectID string, mrIID int) ([]ApprovalInfo, error) {
+func GetMergeRequestApprovals(ctx context.Context, client *gitlab.Client, projectID string, mrIID int) ([]ApprovalInfo, error) {
 	// マージリクエストの承認情報を取得
-	_, _, err := client.MergeRequestApprovals.GetConfiguration(projectID, mrIID)
+	_, _, err := client.MergeRequestApprovals.GetConfiguration(projectID, int64(mrIID), gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MR approval configuration: %w", err)
+		return nil, fmt.Errorf("failed to get MR approval configuration: %w", classifyError(err))
 	}
 
 	// 承認履歴を取得
-	approvalState, _, err := client.MergeRequestApprovals.GetApprovalState(projectID, mrIID)
+	approvalState, _, err := client.MergeRequestApprovals.GetApprovalState(projectID, int64(mrIID), gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MR approval state: %w", err)
+		return nil, fmt.Errorf("failed to get MR approval state: %w", classifyError(err))
 	}
 
 	// 承認情報を整理
@@ -79,21 +129,21 @@ func GetMergeRequestApprovals(client *gitlab.Client, projectID string, mrIID int
 	}
 
 	// 承認日時を取得するために、マージリクエストのイベントを確認
-	events, err := GetMergeRequestEvents(client, projectID, mrIID)
+	events, err := GetMergeRequestEvents(ctx, client, projectID, mrIID)
 	if err != nil {
-		logger.Warn("Failed to get MR events for approval timestamps", "error", err)
+		logger.FromContext(ctx).Warn("Failed to get MR events for approval timestamps", "error", err)
 		// エラーがあっても処理は続行
 	} else {
 		// イベントから承認日時を更新
 		updateApprovalTimesFromEvents(events, &approvalInfos)
 	}
 
-	logger.Debug("Found approvals for MR", "count", len(approvalInfos), "mr_id", mrIID)
+	logger.FromContext(ctx).Debug("Found approvals for MR", "count", len(approvalInfos), "mr_id", mrIID)
 	return approvalInfos, nil
 }
 
 // GetMergeRequestEvents retrieves events for a GitLab merge request
-func GetMergeRequestEvents(client *gitlab.Client, projectID string, mrIID int) ([]*gitlab.StateEvent, error) {
+func GetMergeRequestEvents(ctx context.Context, client *gitlab.Client, projectID string, mrIID int) ([]*gitlab.StateEvent, error) {
 	opts := &gitlab.ListStateEventsOptions{
 		ListOptions: gitlab.ListOptions{
 			PerPage: 100,
@@ -102,9 +152,9 @@ func GetMergeRequestEvents(client *gitlab.Client, projectID string, mrIID int) (
 
 	var allEvents []*gitlab.StateEvent
 	for {
-		events, resp, err := client.ResourceStateEvents.ListMergeStateEvents(projectID, mrIID, opts)
+		events, resp, err := client.ResourceStateEvents.ListMergeStateEvents(projectID, int64(mrIID), opts, gitlab.WithContext(ctx))
 		if err != nil {
-			return nil, fmt.Errorf("failed to list GitLab MR events: %w", err)
+			return nil, fmt.Errorf("failed to list GitLab MR events: %w", classifyError(err))
 		}
 
 		allEvents = append(allEvents, events...)
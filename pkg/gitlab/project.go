@@ -0,0 +1,82 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// NormalizeProjectPath returns the literal project path such as "group/subgroup/project"
+// URLエンコードされたパス ("group%2Fsubgroup%2Fproject") が指定されても、slashを保持したパスとして扱う
+func NormalizeProjectPath(project string) string {
+	if unescaped, err := url.PathUnescape(project); err == nil {
+		project = unescaped
+	}
+	return strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+}
+
+// ParseProjectURL splits a project web or clone URL into the GitLab base URL and the project path
+// baseURLが指定され、URLがそのprefixを持つ場合はsub pathにインストールされたGitLabとして扱う
+func ParseProjectURL(rawURL, baseURL string) (string, string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", false
+	}
+	// "group/project/-/merge_requests" のようなプロジェクト配下のページのURLも受け付ける
+	projectPath, _, _ := strings.Cut(u.Path, "/-/")
+	base := u.Scheme + "://" + u.Host
+	if baseURL != "" {
+		trimmed := strings.TrimRight(baseURL, "/")
+		if full := base + projectPath; strings.HasPrefix(full, trimmed+"/") {
+			base = trimmed
+			projectPath = strings.TrimPrefix(full, trimmed)
+		}
+	}
+	projectPath = NormalizeProjectPath(projectPath)
+	if projectPath == "" {
+		return "", "", false
+	}
+	return base, projectPath, true
+}
+
+// ProjectWebURL returns the web URL of the project
+func ProjectWebURL(baseURL, project string) string {
+	return strings.TrimRight(baseURL, "/") + "/" + NormalizeProjectPath(project)
+}
+
+// MergeRequestWebURL returns the web URL of the merge request
+func MergeRequestWebURL(baseURL, project string, mrIID int) string {
+	return fmt.Sprintf("%s/merge_requests/%d", ProjectWebURL(baseURL, project), mrIID)
+}
+
+// ProjectGitURL returns the authenticated git remote URL of the project
+// GitLabのURLにsub pathが含まれる場合やhttpの場合も、そのままremoteのURLとして利用する
+func ProjectGitURL(baseURL, project, token string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse GitLab URL: %w", err)
+	}
+	u.User = url.UserPassword("oauth2", token)
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + NormalizeProjectPath(project) + ".git"
+	return u.String(), nil
+}
+
+// IsProjectArchived checks if the project is archived
+func IsProjectArchived(ctx context.Context, client *gitlab.Client, projectID string) (bool, error) {
+	project, _, err := client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to get GitLab project: %w", classifyError(err))
+	}
+	return project.Archived, nil
+}
+
+// ArchiveProject archives the project, making it read-only
+func ArchiveProject(ctx context.Context, client *gitlab.Client, projectID string) error {
+	if _, _, err := client.Projects.ArchiveProject(projectID, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to archive GitLab project: %w", classifyError(err))
+	}
+	return nil
+}
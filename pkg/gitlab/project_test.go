@@ -0,0 +1,127 @@
+package gitlab
+
+import "testing"
+
+func TestParseProjectURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawURL      string
+		baseURL     string
+		wantBase    string
+		wantProject string
+		wantOK      bool
+	}{
+		{
+			name:        "web URL",
+			rawURL:      "https://gitlab.com/group/project",
+			wantBase:    "https://gitlab.com",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "clone URL",
+			rawURL:      "https://gitlab.com/group/project.git",
+			wantBase:    "https://gitlab.com",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "page under the project",
+			rawURL:      "https://gitlab.example.com/group/project/-/merge_requests/1",
+			wantBase:    "https://gitlab.example.com",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "trailing slash",
+			rawURL:      "http://gitlab.example.com/group/project/",
+			wantBase:    "http://gitlab.example.com",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "GitLab installed under a sub path",
+			rawURL:      "https://example.com/gitlab/group/project",
+			baseURL:     "https://example.com/gitlab/",
+			wantBase:    "https://example.com/gitlab",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "base URL of another host",
+			rawURL:      "https://gitlab.example.com/group/project",
+			baseURL:     "https://example.com/gitlab",
+			wantBase:    "https://gitlab.example.com",
+			wantProject: "group/project",
+			wantOK:      true,
+		},
+		{
+			name:        "base URL sharing a prefix with the project",
+			rawURL:      "https://example.com/gitlab-group/project",
+			baseURL:     "https://example.com/gitlab",
+			wantBase:    "https://example.com",
+			wantProject: "gitlab-group/project",
+			wantOK:      true,
+		},
+		{name: "project path", rawURL: "group/project"},
+		{name: "URL encoded project path", rawURL: "group%2Fproject"},
+		{name: "numeric project ID", rawURL: "12345"},
+		{name: "ssh clone URL", rawURL: "git@gitlab.com:group/project.git"},
+		{name: "URL without project", rawURL: "https://gitlab.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, project, ok := ParseProjectURL(tt.rawURL, tt.baseURL)
+			if ok != tt.wantOK || base != tt.wantBase || project != tt.wantProject {
+				t.Errorf("ParseProjectURL(%q, %q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.rawURL, tt.baseURL, base, project, ok, tt.wantBase, tt.wantProject, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeProjectPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		want    string
+	}{
+		{"project path", "group/project", "group/project"},
+		{"three level nested path", "group/subgroup/project", "group/subgroup/project"},
+		{"URL encoded three level nested path", "group%2Fsubgroup%2Fproject", "group/subgroup/project"},
+		{"lowercase URL encoded nested path", "group%2fsubgroup%2fproject", "group/subgroup/project"},
+		{"surrounding slashes", "/group/subgroup/project/", "group/subgroup/project"},
+		{"git suffix", "group/subgroup/project.git", "group/subgroup/project"},
+		{"numeric project ID", "12345", "12345"},
+		{"invalid escape kept", "group/100%/project", "group/100%/project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeProjectPath(tt.project); got != tt.want {
+				t.Errorf("NormalizeProjectPath(%q) = %q, want %q", tt.project, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectWebURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		project string
+		want    string
+	}{
+		{"project path", "https://gitlab.com", "group/project", "https://gitlab.com/group/project"},
+		{"three level nested path", "https://gitlab.com", "group/subgroup/project", "https://gitlab.com/group/subgroup/project"},
+		{"URL encoded three level nested path", "https://gitlab.com", "group%2Fsubgroup%2Fproject", "https://gitlab.com/group/subgroup/project"},
+		{"base URL with trailing slash", "https://gitlab.com/", "group/subgroup/project", "https://gitlab.com/group/subgroup/project"},
+		{"GitLab installed under a sub path", "https://example.com/gitlab/", "group/subgroup/project", "https://example.com/gitlab/group/subgroup/project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProjectWebURL(tt.baseURL, tt.project); got != tt.want {
+				t.Errorf("ProjectWebURL(%q, %q) = %q, want %q", tt.baseURL, tt.project, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+// SnippetFile represents a single file of a GitLab snippet
+type SnippetFile struct {
+	Path    string
+	Content []byte
+}
+
+// GetProjectSnippets retrieves all snippets of a GitLab project
+func GetProjectSnippets(ctx context.Context, client *gitlab.Client, projectID string) ([]*gitlab.Snippet, error) {
+	opts := &gitlab.ListProjectSnippetsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var ret []*gitlab.Snippet
+	for {
+		snippets, resp, err := client.ProjectSnippets.ListSnippets(projectID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitLab project snippets: %w", classifyError(err))
+		}
+		ret = append(ret, snippets...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
+// GetProjectSnippetFiles retrieves the raw content of each file in a GitLab project snippet
+func GetProjectSnippetFiles(ctx context.Context, client *gitlab.Client, projectID string, snippet *gitlab.Snippet) ([]SnippetFile, error) {
+	// 複数ファイルを持たない古いsnippetは、snippet自体のcontentを取得する
+	if len(snippet.Files) == 0 {
+		content, _, err := client.ProjectSnippets.SnippetContent(projectID, snippet.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitLab snippet content: %w", classifyError(err))
+		}
+		return []SnippetFile{{Path: snippet.FileName, Content: content}}, nil
+	}
+
+	var files []SnippetFile
+	for _, file := range snippet.Files {
+		u := fmt.Sprintf("projects/%s/snippets/%d/files/%s/%s/raw",
+			gitlab.PathEscape(projectID), snippet.ID, gitlab.PathEscape(snippetFileRef(file)), gitlab.PathEscape(file.Path))
+		req, err := client.NewRequest(http.MethodGet, u, nil, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if _, err := client.Do(req, &b); err != nil {
+			return nil, fmt.Errorf("failed to get GitLab snippet file content: %w, path=%s", classifyError(err), file.Path)
+		}
+		files = append(files, SnippetFile{Path: file.Path, Content: b.Bytes()})
+	}
+	return files, nil
+}
+
+// snippetFileRef returns the ref of the snippet file from its raw URL (.../-/snippets/:id/raw/:ref/:path)
+// snippetのデフォルトブランチはmainとは限らないため、raw URLから取得できない場合のみHEADとする
+func snippetFileRef(file gitlab.SnippetFile) string {
+	u, err := url.Parse(file.RawURL)
+	if err != nil {
+		return "HEAD"
+	}
+	p := strings.TrimSuffix(u.Path, "/"+file.Path)
+	i := strings.LastIndex(p, "/raw/")
+	if p == u.Path || i < 0 || i+len("/raw/") == len(p) {
+		return "HEAD"
+	}
+	return p[i+len("/raw/"):]
+}
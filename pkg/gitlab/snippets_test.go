@@ -0,0 +1,53 @@
+package gitlab
+
+import (
+	"testing"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestSnippetFileRef(t *testing.T) {
+	tests := []struct {
+		name string
+		file gitlab.SnippetFile
+		want string
+	}{
+		{
+			name: "main branch",
+			file: gitlab.SnippetFile{Path: "add.rb", RawURL: "https://gitlab.example.com/group/project/-/snippets/1/raw/main/add.rb"},
+			want: "main",
+		},
+		{
+			name: "master branch",
+			file: gitlab.SnippetFile{Path: "add.rb", RawURL: "https://gitlab.example.com/group/project/-/snippets/1/raw/master/add.rb"},
+			want: "master",
+		},
+		{
+			name: "nested file path",
+			file: gitlab.SnippetFile{Path: "lib/add.rb", RawURL: "https://gitlab.example.com/group/project/-/snippets/1/raw/master/lib/add.rb"},
+			want: "master",
+		},
+		{
+			name: "escaped file path",
+			file: gitlab.SnippetFile{Path: "my script.sh", RawURL: "https://gitlab.example.com/group/project/-/snippets/1/raw/main/my%20script.sh"},
+			want: "main",
+		},
+		{
+			name: "no ref in raw URL",
+			file: gitlab.SnippetFile{Path: "add.rb", RawURL: "https://gitlab.example.com/group/project/-/snippets/1/raw"},
+			want: "HEAD",
+		},
+		{
+			name: "empty raw URL",
+			file: gitlab.SnippetFile{Path: "add.rb"},
+			want: "HEAD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippetFileRef(tt.file); got != tt.want {
+				t.Errorf("snippetFileRef() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// exportMergeRequestsDir is the directory holding exported merge requests
+	exportMergeRequestsDir = "merge_requests"
+	// exportIssuesFile is the file holding exported issues
+	exportIssuesFile = "issues.json"
+	// exportPageSize is the page size used when reading exported merge requests
+	exportPageSize = 100
+)
+
+// ExportedMergeRequest holds all GitLab data of a merge request needed for a migration
+type ExportedMergeRequest struct {
+	MergeRequest *gitlab.MergeRequest              `json:"merge_request"`
+	HasDiffs     bool                              `json:"has_diffs"`
+	Approvals    []ApprovalInfo                    `json:"approvals"`
+	Events       []*gitlab.StateEvent              `json:"events"`
+	Discussions  []*gitlab.Discussion              `json:"discussions"`
+	Versions     []*gitlab.MergeRequestDiffVersion `json:"versions"`
+}
+
+// APISource reads GitLab data from the live GitLab API
+type APISource struct {
+	client    *gitlab.Client
+	projectID string
+}
+
+// NewAPISource creates a source reading from the live GitLab API
+func NewAPISource(client *gitlab.Client, projectID string) *APISource {
+	return &APISource{client: client, projectID: projectID}
+}
+
+// GetMergeRequests retrieves a page of merge requests
+func (s *APISource) GetMergeRequests(ctx context.Context, page int) ([]*gitlab.BasicMergeRequest, error) {
+	return GetMergeRequests(ctx, s.client, s.projectID, page)
+}
+
+// GetMergeRequest retrieves a detailed merge request
+func (s *APISource) GetMergeRequest(ctx context.Context, mrIID int) (*gitlab.MergeRequest, error) {
+	mr, _, err := s.client.MergeRequests.GetMergeRequest(s.projectID, int64(mrIID), nil, gitlab.WithContext(ctx))
+	return mr, classifyError(err)
+}
+
+// HasMergeRequestDiffs checks if the merge request has diffs
+func (s *APISource) HasMergeRequestDiffs(ctx context.Context, mrIID int) (bool, error) {
+	return HasMergeRequestDiffs(ctx, s.client, s.projectID, mrIID)
+}
+
+// GetMergeRequestApprovals retrieves approval information of the merge request
+func (s *APISource) GetMergeRequestApprovals(ctx context.Context, mrIID int) ([]ApprovalInfo, error) {
+	return GetMergeRequestApprovals(ctx, s.client, s.projectID, mrIID)
+}
+
+// GetMergeRequestDiscussions retrieves discussions of the merge request
+func (s *APISource) GetMergeRequestDiscussions(ctx context.Context, mrIID int, maxDiscussions int, order string) ([]*gitlab.Discussion, error) {
+	return GetMergeRequestDiscussions(ctx, s.client, s.projectID, mrIID, maxDiscussions, order)
+}
+
+// GetLatestMergeRequestVersionSHA returns the head SHA of the newest diff version accepted by isAvailable
+func (s *APISource) GetLatestMergeRequestVersionSHA(ctx context.Context, mrIID int, isAvailable func(sha string) bool) (string, error) {
+	return GetLatestMergeRequestVersionSHA(ctx, s.client, s.projectID, mrIID, isAvailable)
+}
+
+// FileSource reads GitLab data from a directory written by Export
+type FileSource struct {
+	dir   string
+	iids  []int
+	cache map[int]*ExportedMergeRequest
+}
+
+// NewFileSource creates a source reading from an export directory
+func NewFileSource(dir string) (*FileSource, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, exportMergeRequestsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+
+	var iids []int
+	for _, entry := range entries {
+		var iid int
+		if _, err := fmt.Sscanf(entry.Name(), "%d.json", &iid); err != nil {
+			continue
+		}
+		iids = append(iids, iid)
+	}
+	slices.Sort(iids)
+
+	return &FileSource{
+		dir:   dir,
+		iids:  iids,
+		cache: make(map[int]*ExportedMergeRequest),
+	}, nil
+}
+
+// GetMergeRequests retrieves a page of exported merge requests ordered by IID
+func (s *FileSource) GetMergeRequests(_ context.Context, page int) ([]*gitlab.BasicMergeRequest, error) {
+	from := (page - 1) * exportPageSize
+	if from >= len(s.iids) {
+		return nil, nil
+	}
+	to := from + exportPageSize
+	if to > len(s.iids) {
+		to = len(s.iids)
+	}
+
+	var mrs []*gitlab.BasicMergeRequest
+	for _, iid := range s.iids[from:to] {
+		exported, err := s.load(iid)
+		if err != nil {
+			return nil, err
+		}
+		mrs = append(mrs, &exported.MergeRequest.BasicMergeRequest)
+	}
+	return mrs, nil
+}
+
+// GetMergeRequest retrieves an exported merge request
+func (s *FileSource) GetMergeRequest(_ context.Context, mrIID int) (*gitlab.MergeRequest, error) {
+	exported, err := s.load(mrIID)
+	if err != nil {
+		return nil, err
+	}
+	return exported.MergeRequest, nil
+}
+
+// HasMergeRequestDiffs checks if the exported merge request has diffs
+func (s *FileSource) HasMergeRequestDiffs(_ context.Context, mrIID int) (bool, error) {
+	exported, err := s.load(mrIID)
+	if err != nil {
+		return false, err
+	}
+	return exported.HasDiffs, nil
+}
+
+// GetMergeRequestApprovals retrieves exported approval information
+func (s *FileSource) GetMergeRequestApprovals(_ context.Context, mrIID int) ([]ApprovalInfo, error) {
+	exported, err := s.load(mrIID)
+	if err != nil {
+		return nil, err
+	}
+	return exported.Approvals, nil
+}
+
+// GetMergeRequestDiscussions retrieves exported discussions
+func (s *FileSource) GetMergeRequestDiscussions(_ context.Context, mrIID int, maxDiscussions int, order string) ([]*gitlab.Discussion, error) {
+	exported, err := s.load(mrIID)
+	if err != nil {
+		return nil, err
+	}
+	return LimitDiscussions(exported.Discussions, maxDiscussions, order), nil
+}
+
+// GetLatestMergeRequestVersionSHA returns the head SHA of the newest exported diff version accepted by isAvailable
+func (s *FileSource) GetLatestMergeRequestVersionSHA(_ context.Context, mrIID int, isAvailable func(sha string) bool) (string, error) {
+	exported, err := s.load(mrIID)
+	if err != nil {
+		return "", err
+	}
+	return latestVersionSHA(exported.Versions, isAvailable), nil
+}
+
+// load reads an exported merge request file
+func (s *FileSource) load(mrIID int) (*ExportedMergeRequest, error) {
+	if exported, ok := s.cache[mrIID]; ok {
+		return exported, nil
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, exportMergeRequestsDir, fmt.Sprintf("%d.json", mrIID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported MR: %w", err)
+	}
+	var exported ExportedMergeRequest
+	if err := json.Unmarshal(b, &exported); err != nil {
+		return nil, fmt.Errorf("failed to parse exported MR: %w, mr.IID=%d", err, mrIID)
+	}
+	s.cache[mrIID] = &exported
+	return &exported, nil
+}
+
+// ExportMergeRequest writes all migration data of a merge request to the export directory
+func ExportMergeRequest(ctx context.Context, client *gitlab.Client, projectID string, mrIID int, dir string) error {
+	mr, _, err := client.MergeRequests.GetMergeRequest(projectID, int64(mrIID), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to get MR: %w", classifyError(err))
+	}
+	hasDiffs, err := HasMergeRequestDiffs(ctx, client, projectID, mrIID)
+	if err != nil {
+		return err
+	}
+	approvals, err := GetMergeRequestApprovals(ctx, client, projectID, mrIID)
+	if err != nil {
+		return err
+	}
+	events, err := GetMergeRequestEvents(ctx, client, projectID, mrIID)
+	if err != nil {
+		return err
+	}
+	discussions, err := GetMergeRequestDiscussions(ctx, client, projectID, mrIID, 0, DiscussionOrderOldest)
+	if err != nil {
+		return fmt.Errorf("failed to get discussions: %w", err)
+	}
+	versions, err := GetMergeRequestDiffVersions(ctx, client, projectID, mrIID)
+	if err != nil {
+		return err
+	}
+
+	exported := &ExportedMergeRequest{
+		MergeRequest: mr,
+		HasDiffs:     hasDiffs,
+		Approvals:    approvals,
+		Events:       events,
+		Discussions:  discussions,
+		Versions:     versions,
+	}
+	return writeJSON(filepath.Join(dir, exportMergeRequestsDir, fmt.Sprintf("%d.json", mrIID)), exported)
+}
+
+// ExportIssues writes all issues of the project to the export directory
+func ExportIssues(ctx context.Context, client *gitlab.Client, projectID string, dir string) error {
+	opts := &gitlab.ListProjectIssuesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var allIssues []*gitlab.Issue
+	for {
+		issues, resp, err := client.Issues.ListProjectIssues(projectID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to list GitLab issues: %w", classifyError(err))
+		}
+		allIssues = append(allIssues, issues...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return writeJSON(filepath.Join(dir, exportIssuesFile), allIssues)
+}
+
+// writeJSON writes the value as indented JSON, creating parent directories as needed
+func writeJSON(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal json: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAPISource calls the adapted go-gitlab options and helpers of APISource against a fake GitLab
+func TestAPISource(t *testing.T) {
+	const prefix = "/api/v4/projects/group%2Fproject/merge_requests"
+	var stateEventPages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch strings.TrimPrefix(r.URL.EscapedPath(), prefix) {
+		case "":
+			// 作成日時の昇順にページを指定して取得すること
+			if query.Get("order_by") != "created_at" || query.Get("sort") != "asc" || query.Get("page") != "2" || query.Get("per_page") != "100" {
+				t.Errorf("merge requests query = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[{"id":101,"iid":1,"title":"Add login page","state":"merged"}]`)
+		case "/1":
+			fmt.Fprint(w, `{"id":101,"iid":1,"title":"Add login page","diff_refs":{"base_sha":"base","head_sha":"head"}}`)
+		case "/1/diffs":
+			fmt.Fprint(w, `[{"new_path":"main.go"}]`)
+		case "/1/discussions":
+			fmt.Fprint(w, `[{"id":"d1","notes":[{"id":10,"body":"LGTM","author":{"username":"bob"},"resolvable":true,"resolved":true,"position":{"new_path":"main.go","new_line":3}}]}]`)
+		case "/1/versions":
+			fmt.Fprint(w, `[{"id":1,"head_commit_sha":"old"},{"id":2,"head_commit_sha":"new"}]`)
+		case "/1/approvals":
+			fmt.Fprint(w, `{"approvals_required":1}`)
+		case "/1/approval_state":
+			fmt.Fprint(w, `{"rules":[{"approved_by":[{"username":"bob"}]}]}`)
+		case "/1/resource_state_events":
+			stateEventPages = append(stateEventPages, query.Get("page"))
+			// 2ページ目まで辿ること
+			if query.Get("page") == "" || query.Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"id":1,"state":"opened","user":{"username":"alice"},"created_at":"2024-01-01T00:00:00Z"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"id":2,"state":"approved","user":{"username":"bob"},"created_at":"2024-01-02T03:04:05Z"}]`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient("token", srv.URL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := NewAPISource(client, "group/project")
+	ctx := context.Background()
+
+	mrs, err := source.GetMergeRequests(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetMergeRequests() error = %v", err)
+	}
+	if len(mrs) != 1 || mrs[0].IID != 1 || mrs[0].State != "merged" {
+		t.Errorf("GetMergeRequests() = %+v", mrs)
+	}
+
+	mr, err := source.GetMergeRequest(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetMergeRequest() error = %v", err)
+	}
+	if mr.DiffRefs.BaseSha != "base" || mr.DiffRefs.HeadSha != "head" {
+		t.Errorf("GetMergeRequest() diff refs = %+v", mr.DiffRefs)
+	}
+
+	hasDiffs, err := source.HasMergeRequestDiffs(ctx, 1)
+	if err != nil || !hasDiffs {
+		t.Errorf("HasMergeRequestDiffs() = %v, %v, want true", hasDiffs, err)
+	}
+
+	discussions, err := source.GetMergeRequestDiscussions(ctx, 1, 0, DiscussionOrderOldest)
+	if err != nil {
+		t.Fatalf("GetMergeRequestDiscussions() error = %v", err)
+	}
+	if len(discussions) != 1 || len(discussions[0].Notes) != 1 {
+		t.Fatalf("GetMergeRequestDiscussions() = %+v", discussions)
+	}
+	if note := discussions[0].Notes[0]; note.ID != 10 || !note.Resolved || note.Position == nil || note.Position.NewLine != 3 {
+		t.Errorf("note = %+v", note)
+	}
+
+	sha, err := source.GetLatestMergeRequestVersionSHA(ctx, 1, func(sha string) bool { return true })
+	if err != nil || sha != "new" {
+		t.Errorf("GetLatestMergeRequestVersionSHA() = %q, %v, want new", sha, err)
+	}
+
+	approvals, err := source.GetMergeRequestApprovals(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetMergeRequestApprovals() error = %v", err)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if len(approvals) != 1 || approvals[0].User != "bob" || !approvals[0].CreatedAt.Equal(want) {
+		t.Errorf("GetMergeRequestApprovals() = %+v, want bob at %s from the resource state events", approvals, want)
+	}
+	if len(stateEventPages) != 2 {
+		t.Errorf("resource state event pages = %q, want 2 pages", stateEventPages)
+	}
+}
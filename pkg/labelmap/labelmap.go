@@ -0,0 +1,75 @@
+package labelmap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LabelMap maps GitLab labels to GitHub labels
+type LabelMap map[string]string
+
+// Load reads a label map CSV file
+// 各行は "gitlab_label,github_label" の形式とし、空行や "#" で始まる行は無視する
+// 複数のGitLabラベルを同じGitHubラベルにまとめることができる
+func Load(path string) (LabelMap, error) {
+	m := LabelMap{}
+	if path == "" {
+		return m, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open label map: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label map: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid label map line: %v", record)
+		}
+		gitlabLabel := strings.TrimSpace(record[0])
+		githubLabel := strings.TrimSpace(record[1])
+		if gitlabLabel == "" || githubLabel == "" {
+			continue
+		}
+		m[gitlabLabel] = githubLabel
+	}
+	return m, nil
+}
+
+// Resolve returns the GitHub label mapped to the GitLab label, or the GitLab label itself if unmapped
+func (m LabelMap) Resolve(gitlabLabel string) string {
+	if githubLabel, ok := m[gitlabLabel]; ok {
+		return githubLabel
+	}
+	return gitlabLabel
+}
+
+// ResolveAll returns the GitHub labels mapped to the GitLab labels without duplicates
+func (m LabelMap) ResolveAll(gitlabLabels []string) []string {
+	var githubLabels []string
+	seen := map[string]bool{}
+	for _, gitlabLabel := range gitlabLabels {
+		githubLabel := m.Resolve(gitlabLabel)
+		if seen[githubLabel] {
+			continue
+		}
+		seen[githubLabel] = true
+		githubLabels = append(githubLabels, githubLabel)
+	}
+	return githubLabels
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -103,6 +104,75 @@ func Fatal(msg string, keysAndValues ...interface{}) {
 	logEvent(event, msg, keysAndValues...)
 }
 
+// Logger is a scoped logger which includes persistent key-value pairs in every log line
+type Logger struct {
+	zl zerolog.Logger
+}
+
+// contextKey is the context key for the scoped logger
+type contextKey struct{}
+
+// Default returns a scoped logger without additional fields
+func Default() *Logger {
+	return &Logger{zl: log}
+}
+
+// With returns a scoped logger which includes the key-value pairs in every log line
+func With(keysAndValues ...interface{}) *Logger {
+	return Default().With(keysAndValues...)
+}
+
+// NewContext returns a context carrying the scoped logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the scoped logger carried by the context, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// With returns a child logger which additionally includes the key-value pairs
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	zctx := l.zl.With()
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprintf("%v", keysAndValues[i])
+		}
+		zctx = zctx.Interface(key, keysAndValues[i+1])
+	}
+	return &Logger{zl: zctx.Logger()}
+}
+
+// Debug logs a debug message with optional key-value pairs
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	logEvent(l.zl.Debug(), msg, keysAndValues...)
+}
+
+// Info logs an info message with optional key-value pairs
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	logEvent(l.zl.Info(), msg, keysAndValues...)
+}
+
+// Warn logs a warning message with optional key-value pairs
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	logEvent(l.zl.Warn(), msg, keysAndValues...)
+}
+
+// Error logs an error message with optional key-value pairs
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	logEvent(l.zl.Error(), msg, keysAndValues...)
+}
+
+// Fatal logs a fatal message with optional key-value pairs and then exits
+func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
+	logEvent(l.zl.Fatal(), msg, keysAndValues...)
+}
+
 // logEvent adds key-value pairs to the event and sends it
 func logEvent(event *zerolog.Event, msg string, keysAndValues ...interface{}) {
 	// Process key-value pairs
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// githubCodeownersPaths are the CODEOWNERS locations recognized by GitHub
+var githubCodeownersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// MigrateApprovalRules reflects the GitLab approval requirement as branch protection of the GitHub default branch
+func MigrateApprovalRules(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, g *git.Git, cfg config.GlobalConfig, opts *MigrationOptions) error {
+	if !opts.MigrateApprovalRules {
+		return nil
+	}
+
+	requirement, err := gitlab.GetProjectApprovalRequirement(ctx, gitlabClient, cfg.GitLabProject)
+	if err != nil {
+		return err
+	}
+
+	// GitHubはCODEOWNERSが存在しない場合にcode ownerのレビューを要求できないため、ファイルの有無で判定する
+	hasCodeowners := false
+	for _, p := range githubCodeownersPaths {
+		if g.FileExists(p) {
+			hasCodeowners = true
+			break
+		}
+	}
+	if requirement.RequiredApprovals == 0 && !hasCodeowners {
+		logger.FromContext(ctx).Info("No approval requirement to migrate")
+		return nil
+	}
+	if requirement.HasCodeOwnerRule && !hasCodeowners {
+		logger.FromContext(ctx).Warn("GitLab requires code owner approval but no CODEOWNERS is found at a location recognized by GitHub")
+	}
+
+	repository, err := getGitHubRepository(ctx, cfg, githubClient)
+	if err != nil {
+		return err
+	}
+	if repository == nil {
+		return fmt.Errorf("GitHub repository %s/%s does not exist", cfg.GitHubOwner, cfg.GitHubRepo)
+	}
+	branch := repository.GetDefaultBranch()
+	if err := githubClient.ProtectBranch(ctx, cfg.GitHubOwner, cfg.GitHubRepo, branch, &github.BranchProtectionOptions{
+		RequiredApprovingReviewCount: requirement.RequiredApprovals,
+		RequireCodeOwnerReviews:      hasCodeowners,
+		DismissStaleReviews:          requirement.ResetApprovalsOnPush,
+	}); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Migrated approval rules as branch protection",
+		"branch", branch,
+		"required_approvals", requirement.RequiredApprovals,
+		"require_code_owner_reviews", hasCodeowners)
+	return nil
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// authorClient returns the client authenticated as the GitHub user mapped to the note author and the login of the user
+func authorClient(opts *MigrationOptions, note *gitlablib.Note) (*github.Client, string, bool) {
+	if len(opts.AuthorClients) == 0 || note.Author.Username == "" {
+		return nil, "", false
+	}
+	login, ok := opts.UserMap.Lookup(note.Author.Username)
+	if !ok {
+		return nil, "", false
+	}
+	client, ok := opts.AuthorClients[login]
+	return client, login, ok
+}
+
+// createAsAuthor runs the operation with the client authenticated as the GitHub user mapped to the note author
+// --user-tokensでtokenが指定されていないユーザーや、リポジトリへの権限が無いユーザーの場合は、移行用のclientで作成する
+func createAsAuthor(ctx context.Context, opts *MigrationOptions, githubClient *github.Client, note *gitlablib.Note, operation func(client *github.Client) error) error {
+	if client, login, ok := authorClient(opts, note); ok {
+		err := operation(client)
+		if err == nil || !github.IsPermissionError(err) {
+			return err
+		}
+		logger.FromContext(ctx).Warn("Mapped user cannot create the comment, falling back to the migration identity", "note", note.ID, "user", login, "error", err)
+	}
+	return operation(githubClient)
+}
@@ -0,0 +1,181 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// BackfillComments migrates the comments of merge requests whose PRs already exist, without creating branches or PRs
+// 既に移行済みのnoteを含むdiscussionはスキップするため、何度実行しても重複して作成しない
+func BackfillComments(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions) (*Report, error) {
+	report := NewReport()
+	start := time.Now()
+	defer func() {
+		report.AddPhaseDuration(PhaseComments, time.Since(start))
+	}()
+	g := git.NewGit(cfg.WorkingDir, cfg.GitHubOwner, cfg.GitHubRepo, cfg.GitLabURL, cfg.GitLabProject)
+	g.SetLogger(logger.FromContext(ctx))
+	g.SetSubdirectory(opts.Subdirectory)
+
+	closedPRs, err := githubClient.GetClosedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return report, err
+	}
+	openedPRs, err := githubClient.GetOpenedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return report, fmt.Errorf("failed to get opened PRs: %w", err)
+	}
+	migratedPRs := make(map[int]*githublib.PullRequest)
+	for _, pr := range append(closedPRs, openedPRs...) {
+		if _, owned := parseOwnedMRIID(opts, pr); !owned {
+			continue
+		}
+		if mrIID, ok := parseMigratedMRIID(opts, pr); ok {
+			migratedPRs[mrIID] = pr
+		}
+	}
+
+	for page := 1; ; page++ {
+		mrs, err := source.GetMergeRequests(ctx, page)
+		if err != nil {
+			return report, fmt.Errorf("failed to get merge requests: %w", err)
+		}
+		if len(mrs) == 0 {
+			break
+		}
+		for _, mr := range mrs {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			default:
+			}
+			if !isBackfillTarget(opts, mr) {
+				report.AddSkippedMergeRequest()
+				continue
+			}
+			pr, ok := migratedPRs[int(mr.IID)]
+			if !ok {
+				logger.FromContext(ctx).Warn("Skipping MR without migrated PR", "id", mr.IID, "title", mr.Title)
+				report.AddSkippedMergeRequest()
+				continue
+			}
+
+			entry := report.AddMergeRequest(mr)
+			entry.PRNumber = pr.GetNumber()
+			entry.PRURL = pr.GetHTMLURL()
+			if err := backfillMergeRequestComments(ctx, source, githubClient, cfg, opts, mr, pr, g, entry); err != nil {
+				logger.FromContext(ctx).Warn("Failed to backfill comments", "id", mr.IID, "pr", pr.GetNumber(), "error", err)
+				entry.Status = MergeRequestStatusFailed
+				entry.Error = err.Error()
+				continue
+			}
+			entry.Status = MergeRequestStatusMigrated
+		}
+	}
+
+	migrated, failed := report.Counts()
+	logger.FromContext(ctx).Info("Comment backfill completed",
+		"merge_requests", migrated,
+		"failed", failed,
+		"skipped", report.SkippedMergeRequests)
+	return report, nil
+}
+
+// isBackfillTarget checks if the comments of the merge request are backfilled
+// MR IDが指定されている場合は、その状態に関わらず対象とする
+func isBackfillTarget(opts *MigrationOptions, mr *gitlablib.BasicMergeRequest) bool {
+	if opts.ContinueFromID > 0 && int(mr.IID) < opts.ContinueFromID {
+		return false
+	}
+	if len(opts.FilterMergeReqIDs) > 0 {
+		return slices.Contains(opts.FilterMergeReqIDs, int(mr.IID))
+	}
+	return opts.migratesState(mr.State)
+}
+
+// backfillMergeRequestComments creates the discussions of the merge request not yet migrated to the PR
+// mergeされていないclosedのPRは、コメントを作成する間だけreopenする
+func backfillMergeRequestComments(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, basic *gitlablib.BasicMergeRequest, pr *githublib.PullRequest, g *git.Git, entry *MergeRequestReport) error {
+	mr, err := source.GetMergeRequest(ctx, int(basic.IID))
+	if err != nil {
+		return fmt.Errorf("failed to get merge request: %w", err)
+	}
+	discussions, err := source.GetMergeRequestDiscussions(ctx, int(mr.IID), opts.MaxDiscussions, opts.DiscussionOrder)
+	if err != nil {
+		return fmt.Errorf("failed to get discussions: %w on mr.IID=%d", err, mr.IID)
+	}
+	migrated, err := migratedNoteIDs(ctx, githubClient, cfg, pr.GetNumber())
+	if err != nil {
+		return err
+	}
+
+	var missing []*gitlablib.Discussion
+	for _, discussion := range discussions {
+		if !hasMigratedNote(discussion, migrated) {
+			missing = append(missing, discussion)
+		}
+	}
+	if len(missing) == 0 {
+		logger.FromContext(ctx).Debug("All comments already migrated", "id", mr.IID, "pr", pr.GetNumber())
+		return nil
+	}
+
+	if pr.GetState() == "closed" && pr.MergedAt == nil {
+		if err := githubClient.ReopenPullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber()); err != nil {
+			return err
+		}
+		defer func() {
+			if err := githubClient.ClosePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber()); err != nil {
+				logger.FromContext(ctx).Warn("Failed to close reopened PR", "pr", pr.GetNumber(), "error", err)
+			}
+		}()
+	}
+
+	logger.FromContext(ctx).Info("Backfilling comments", "id", mr.IID, "pr", pr.GetNumber(), "discussions", len(missing))
+	entry.Comments = migratePullRequestComments(ctx, githubClient, cfg, opts, mr, pr, missing, g)
+	return nil
+}
+
+// migratedNoteIDs returns the GitLab note IDs of the note markers in the issue and review comments of the PR
+func migratedNoteIDs(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, prNumber int) (map[int]bool, error) {
+	issueComments, err := githubClient.ListIssueComments(ctx, cfg.GitHubOwner, cfg.GitHubRepo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	prComments, err := githubClient.ListPRComments(ctx, cfg.GitHubOwner, cfg.GitHubRepo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	migrated := make(map[int]bool)
+	for _, comment := range issueComments {
+		for _, noteID := range parseNoteMarkers(comment.GetBody()) {
+			migrated[noteID] = true
+		}
+	}
+	for _, comment := range prComments {
+		for _, noteID := range parseNoteMarkers(comment.GetBody()) {
+			migrated[noteID] = true
+		}
+	}
+	return migrated, nil
+}
+
+// hasMigratedNote checks if any note of the discussion has already been migrated
+// 一部のreplyのみが不足している場合も、先頭のコメントを重複して作成しないよう移行済みとする
+func hasMigratedNote(discussion *gitlablib.Discussion, migrated map[int]bool) bool {
+	for _, note := range discussion.Notes {
+		if migrated[int(note.ID)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/usermap"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// codeownersPath is the path the converted CODEOWNERS is committed to
+const codeownersPath = ".github/CODEOWNERS"
+
+// gitlabCodeownersPaths are the CODEOWNERS locations recognized by GitLab, in lookup order
+var gitlabCodeownersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// codeownersSectionPattern matches a GitLab CODEOWNERS section header such as `^[Docs][2] @owner`
+var codeownersSectionPattern = regexp.MustCompile(`^(\^?\[[^\]]+\](?:\[\d+\])?)\s*(.*)$`)
+
+// MigrateCodeowners commits a CODEOWNERS converted from the GitLab CODEOWNERS or approval rules
+func MigrateCodeowners(ctx context.Context, gitlabClient *gitlablib.Client, g *git.Git, cfg config.GlobalConfig, opts *MigrationOptions) error {
+	if !opts.MigrateCodeowners {
+		return nil
+	}
+	// GitHubはリポジトリのルート基準のCODEOWNERSしか扱えないため、subdirectoryへの移行では変換しない
+	if opts.Subdirectory != "" {
+		logger.FromContext(ctx).Warn("Skipping CODEOWNERS migration into a subdirectory")
+		return nil
+	}
+
+	var content string
+	for _, p := range gitlabCodeownersPaths {
+		if !g.FileExists(p) {
+			continue
+		}
+		b, err := g.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		content = fmt.Sprintf("# Converted from GitLab %s\n\n%s", p, string(b))
+		break
+	}
+	if content == "" {
+		rules, err := gitlab.GetProjectApprovalRules(ctx, gitlabClient, cfg.GitLabProject)
+		if err != nil {
+			return err
+		}
+		content = codeownersFromApprovalRules(rules)
+	}
+	if content == "" {
+		logger.FromContext(ctx).Info("No CODEOWNERS to migrate")
+		return nil
+	}
+
+	converted, unmapped := convertCodeowners(content, opts.UserMap)
+	if len(unmapped) > 0 {
+		logger.FromContext(ctx).Warn("CODEOWNERS contains owners not found in the user map", "owners", strings.Join(unmapped, ","))
+	}
+
+	currentBranch, err := g.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if err := g.WriteFile(codeownersPath, []byte(converted)); err != nil {
+		return err
+	}
+	if err := g.Add(codeownersPath); err != nil {
+		return err
+	}
+	if err := g.Commit("Add CODEOWNERS converted from GitLab"); err != nil {
+		return err
+	}
+	if err := g.PushBranchOrigins(currentBranch); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Migrated CODEOWNERS", "path", codeownersPath, "branch", currentBranch, "unmapped_owners", len(unmapped))
+	return nil
+}
+
+// codeownersFromApprovalRules builds a CODEOWNERS assigning the approvers of all approval rules to every file
+func codeownersFromApprovalRules(rules []*gitlablib.ProjectApprovalRule) string {
+	var names, owners []string
+	seen := map[string]bool{}
+	addOwner := func(owner string) {
+		if !seen[owner] {
+			seen[owner] = true
+			owners = append(owners, owner)
+		}
+	}
+	for _, rule := range rules {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			continue
+		}
+		names = append(names, rule.Name)
+		for _, user := range rule.Users {
+			addOwner("@" + user.Username)
+		}
+		for _, group := range rule.Groups {
+			addOwner("@" + group.FullPath)
+		}
+	}
+	if len(owners) == 0 {
+		return ""
+	}
+
+	// GitHubでは最後にマッチした行のみが有効となるため、ruleごとではなく1行にまとめる
+	var b strings.Builder
+	b.WriteString("# Generated from GitLab approval rules\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "# - %s\n", name)
+	}
+	fmt.Fprintf(&b, "\n* %s\n", strings.Join(owners, " "))
+	return b.String()
+}
+
+// convertCodeowners rewrites GitLab usernames in the CODEOWNERS to GitHub logins and returns the owners not found in the user map
+// GitHubはsectionをサポートしていないため、sectionの見出しはコメントとして残し、sectionのデフォルトのownerは各行に展開する
+func convertCodeowners(content string, userMap usermap.UserMap) (string, []string) {
+	unmappedSet := map[string]bool{}
+	convertOwners := func(owners []string) []string {
+		converted := make([]string, 0, len(owners))
+		for _, owner := range owners {
+			// メールアドレスはGitHubでもそのまま利用できる
+			if !strings.HasPrefix(owner, "@") {
+				converted = append(converted, owner)
+				continue
+			}
+			name := strings.TrimPrefix(owner, "@")
+			if githubUser, ok := userMap.Lookup(name); ok {
+				converted = append(converted, "@"+githubUser)
+				continue
+			}
+			unmappedSet[owner] = true
+			converted = append(converted, owner)
+		}
+		return converted
+	}
+
+	var lines []string
+	var defaultOwners []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, line)
+			continue
+		}
+		if m := codeownersSectionPattern.FindStringSubmatch(trimmed); m != nil {
+			defaultOwners = convertOwners(strings.Fields(m[2]))
+			lines = append(lines, "# "+m[1])
+			continue
+		}
+
+		pattern, owners := splitCodeownersLine(trimmed)
+		if len(owners) == 0 {
+			lines = append(lines, strings.Join(append([]string{pattern}, defaultOwners...), " "))
+			continue
+		}
+		lines = append(lines, strings.Join(append([]string{pattern}, convertOwners(owners)...), " "))
+	}
+
+	unmapped := make([]string, 0, len(unmappedSet))
+	for owner := range unmappedSet {
+		unmapped = append(unmapped, owner)
+	}
+	sort.Strings(unmapped)
+	return strings.Join(lines, "\n"), unmapped
+}
+
+// splitCodeownersLine splits a CODEOWNERS entry into the path pattern and owners, keeping escaped spaces in the pattern
+func splitCodeownersLine(line string) (string, []string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == ' ' || line[i] == '\t' {
+			end = i
+			break
+		}
+	}
+	return line[:end], strings.Fields(line[end:])
+}
@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// mergeRequestDump holds the GitLab data and branches of a merge request migration, written to --debug-dump-dir
+type mergeRequestDump struct {
+	MergeRequest *gitlablib.MergeRequest `json:"merge_request"`
+	Discussions  []*gitlablib.Discussion `json:"discussions"`
+	SourceBranch string                  `json:"source_branch"`
+	SourceSHA    string                  `json:"source_sha,omitempty"` // 作成できなかった場合は空
+	TargetBranch string                  `json:"target_branch"`
+	TargetSHA    string                  `json:"target_sha,omitempty"` // 作成できなかった場合は空
+	Error        string                  `json:"error,omitempty"`
+}
+
+// writeDebugDump writes the dump of the merge request to --debug-dump-dir if the migration failed or the log level is debug
+// 失敗したMRを後から再現できるよう、GitLabから取得した内容と実際に作成したブランチのcommitを残す
+func writeDebugDump(ctx context.Context, cfg config.GlobalConfig, opts *MigrationOptions, g *git.Git, dump *mergeRequestDump, migrateErr error) {
+	if opts.DebugDumpDir == "" || (migrateErr == nil && cfg.LogLevel != "debug") {
+		return
+	}
+	if migrateErr != nil {
+		dump.Error = migrateErr.Error()
+	}
+	dump.SourceSHA, _ = g.ResolveCommit(dump.SourceBranch)
+	dump.TargetSHA, _ = g.ResolveCommit(dump.TargetBranch)
+
+	path := filepath.Join(opts.DebugDumpDir, fmt.Sprintf("%d.json", dump.MergeRequest.IID))
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(path, b)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to write debug dump", "mr", dump.MergeRequest.IID, "error", err)
+		return
+	}
+	logger.FromContext(ctx).Debug("Wrote debug dump", "mr", dump.MergeRequest.IID, "path", path)
+}
@@ -0,0 +1,130 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+)
+
+// DeleteMigratedResult holds the PRs closed, the branches deleted and the migration states reset by DeleteMigrated
+type DeleteMigratedResult struct {
+	PullRequests  []int    // closeしたPRの番号
+	Branches      []string // 削除したブランチ
+	MergeRequests []int    // 移行状態を削除したMRのIID
+}
+
+// DeleteMigrated closes the PRs migrated from GitLab and deletes their branches, so that the merge requests can be migrated again
+// PRはAPIで削除できないため、--failed-title-tagを付与してcloseし、次回の移行で移行済みとして扱われないようにする
+// --resumeで再びスキップされないよう、opts.Stateが指定されている場合はcloseしたPRのMRの移行状態も削除する
+// confirmがfalseの場合は、対象のPRとブランチを列挙するのみとする
+func DeleteMigrated(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, confirm bool) (*DeleteMigratedResult, error) {
+	result := &DeleteMigratedResult{PullRequests: []int{}, Branches: []string{}, MergeRequests: []int{}}
+
+	openedPRs, err := githubClient.GetOpenedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opened PRs: %w", err)
+	}
+	closedPRs, err := githubClient.GetClosedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range append(openedPRs, closedPRs...) {
+		if !isDeletableMigratedPR(opts, pr) {
+			continue
+		}
+		result.PullRequests = append(result.PullRequests, pr.GetNumber())
+		if mrIID, ok := parseMRBranchIID(opts, pr.GetHead().GetRef()); ok && opts.State != nil {
+			if _, saved := opts.State.Get(mrIID); saved {
+				result.MergeRequests = append(result.MergeRequests, mrIID)
+			}
+		}
+		if !confirm {
+			logger.FromContext(ctx).Info("Would close migrated PR", "pr", pr.GetNumber(), "title", pr.GetTitle())
+			continue
+		}
+		logger.FromContext(ctx).Info("Closing migrated PR", "pr", pr.GetNumber(), "title", pr.GetTitle())
+		if !strings.HasPrefix(pr.GetTitle(), opts.FailedTitleTag+" ") {
+			newTitle := fmt.Sprintf("%s %s", opts.FailedTitleTag, pr.GetTitle())
+			if err := githubClient.UpdatePullRequestTitle(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), newTitle); err != nil {
+				return result, err
+			}
+		}
+		if pr.GetState() == "open" {
+			if err := githubClient.ClosePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber()); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if confirm && len(result.MergeRequests) > 0 {
+		logger.FromContext(ctx).Info("Resetting migration state", "mr_ids", result.MergeRequests)
+		if err := opts.State.Remove(result.MergeRequests...); err != nil {
+			return result, fmt.Errorf("failed to reset migration state: %w", err)
+		}
+	} else if len(result.MergeRequests) > 0 {
+		logger.FromContext(ctx).Info("Would reset migration state", "mr_ids", result.MergeRequests)
+	}
+
+	branches, err := githubClient.ListBranches(ctx, cfg.GitHubOwner, cfg.GitHubRepo, mrBranchPrefix(opts)+"-")
+	if err != nil {
+		return result, err
+	}
+	for _, branch := range branches {
+		if !isMRBranch(opts, branch) {
+			continue
+		}
+		result.Branches = append(result.Branches, branch)
+		if !confirm {
+			logger.FromContext(ctx).Info("Would delete MR branch", "branch", branch)
+			continue
+		}
+		logger.FromContext(ctx).Info("Deleting MR branch", "branch", branch)
+		if err := githubClient.DeleteBranch(ctx, cfg.GitHubOwner, cfg.GitHubRepo, branch); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// isDeletableMigratedPR checks if the PR was created by the migration and is not closed as failed yet
+func isDeletableMigratedPR(opts *MigrationOptions, pr *githublib.PullRequest) bool {
+	if !isMRBranch(opts, pr.GetHead().GetRef()) {
+		return false
+	}
+	// openのPRは移行途中のものであるため、移行済みと判定できなくても対象とする
+	if pr.GetState() == "open" {
+		return true
+	}
+	_, migrated := parseMigratedMRIID(opts, pr)
+	return migrated
+}
+
+// isMRBranch checks if the branch is a source or target branch created for a merge request of the current project
+func isMRBranch(opts *MigrationOptions, branch string) bool {
+	_, ok := parseMRBranchIID(opts, branch)
+	return ok
+}
+
+// parseMRBranchIID resolves the GitLab MR IID from a source or target branch created for a merge request of the current project
+// monorepoの場合に他のプロジェクトのブランチを対象としないよう、IIDの部分が数値であることも確認する
+func parseMRBranchIID(opts *MigrationOptions, branch string) (int, bool) {
+	rest, ok := strings.CutPrefix(branch, mrBranchPrefix(opts)+"-")
+	if !ok {
+		return 0, false
+	}
+	iid, kind, ok := strings.Cut(rest, "-")
+	if !ok || (kind != "source" && kind != "target") {
+		return 0, false
+	}
+	mrIID, err := strconv.Atoi(iid)
+	if err != nil {
+		return 0, false
+	}
+	return mrIID, true
+}
@@ -0,0 +1,129 @@
+package migration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// commentFilterBodyPrefix restricts a comment filter to the note body
+	commentFilterBodyPrefix = "body:"
+	// commentFilterAuthorPrefix restricts a comment filter to the note author username
+	commentFilterAuthorPrefix = "author:"
+)
+
+// CommentFilter drops GitLab notes matching the pattern
+type CommentFilter struct {
+	Pattern *regexp.Regexp
+	Body    bool // noteの本文にマッチさせる
+	Author  bool // noteの作者のユーザー名にマッチさせる
+}
+
+// ParseCommentFilters parses comment filter expressions such as "author:^ci-bot$" or "body:Coverage report"
+// prefixが無い場合は、本文と作者のユーザー名のどちらかにマッチしたnoteを除外する
+func ParseCommentFilters(exprs []string) ([]*CommentFilter, error) {
+	var filters []*CommentFilter
+	for _, expr := range exprs {
+		filter := &CommentFilter{Body: true, Author: true}
+		if strings.HasPrefix(expr, commentFilterBodyPrefix) {
+			expr = strings.TrimPrefix(expr, commentFilterBodyPrefix)
+			filter.Author = false
+		} else if strings.HasPrefix(expr, commentFilterAuthorPrefix) {
+			expr = strings.TrimPrefix(expr, commentFilterAuthorPrefix)
+			filter.Body = false
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid comment filter %q: %w", expr, err)
+		}
+		filter.Pattern = pattern
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// matches checks if the note should be dropped by the filter
+func (f *CommentFilter) matches(note *gitlablib.Note) bool {
+	if f.Body && f.Pattern.MatchString(note.Body) {
+		return true
+	}
+	return f.Author && f.Pattern.MatchString(note.Author.Username)
+}
+
+// filterDiscussion returns the discussion without the notes matching the comment filters and the number of dropped notes
+// 先頭のnoteが除外された場合は、replyのみを移行しても文脈が分からないため、discussion全体を除外してnilを返す
+func filterDiscussion(opts *MigrationOptions, discussion *gitlablib.Discussion) (*gitlablib.Discussion, int) {
+	if len(opts.CommentFilters) == 0 || len(discussion.Notes) == 0 {
+		return discussion, 0
+	}
+	if isFilteredNote(opts, discussion.Notes[0]) {
+		return nil, len(discussion.Notes)
+	}
+
+	filtered := *discussion
+	filtered.Notes = []*gitlablib.Note{discussion.Notes[0]}
+	for _, note := range discussion.Notes[1:] {
+		if isFilteredNote(opts, note) {
+			continue
+		}
+		filtered.Notes = append(filtered.Notes, note)
+	}
+	return &filtered, len(discussion.Notes) - len(filtered.Notes)
+}
+
+// isFilteredNote checks if the note matches any comment filter
+func isFilteredNote(opts *MigrationOptions, note *gitlablib.Note) bool {
+	for _, filter := range opts.CommentFilters {
+		if filter.matches(note) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDiscussionSince returns the discussion without the notes created before --comment-since and the number of dropped notes
+// 先頭のnoteのみが古い場合は、新しいreplyを先頭とし、元の先頭のnoteを引用して文脈が分かるようにする
+func filterDiscussionSince(opts *MigrationOptions, discussion *gitlablib.Discussion) (*gitlablib.Discussion, int) {
+	if opts.CommentSince.IsZero() || len(discussion.Notes) == 0 {
+		return discussion, 0
+	}
+
+	var notes []*gitlablib.Note
+	for _, note := range discussion.Notes {
+		// 作成日時が分からないnoteは移行する
+		if note.CreatedAt == nil || !note.CreatedAt.Before(opts.CommentSince) {
+			notes = append(notes, note)
+		}
+	}
+	dropped := len(discussion.Notes) - len(notes)
+	if len(notes) == 0 {
+		return nil, dropped
+	}
+	if dropped == 0 {
+		return discussion, 0
+	}
+
+	filtered := *discussion
+	filtered.Notes = notes
+	headNote := discussion.Notes[0]
+	if notes[0] != headNote && !headNote.System && !notes[0].System {
+		newHead := *notes[0]
+		newHead.Body = formatQuotedNote(headNote) + newHead.Body
+		filtered.Notes = append([]*gitlablib.Note{&newHead}, notes[1:]...)
+	}
+	return &filtered, dropped
+}
+
+// formatQuotedNote quotes the first line of the note a reply was written to
+func formatQuotedNote(note *gitlablib.Note) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(note.Body), "\n", 2)[0])
+	date := ""
+	if note.CreatedAt != nil {
+		date = " at " + note.CreatedAt.Format(time.DateOnly)
+	}
+	return fmt.Sprintf("> In reply to `%s`%s: %s\n\n", note.Author.Username, date, line)
+}
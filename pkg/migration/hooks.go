@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"context"
+	"errors"
+
+	githublib "github.com/google/go-github/v70/github"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ErrSkip is returned by MigrationOptions.BeforeMR to skip the merge request without failing the migration
+var ErrSkip = errors.New("merge request skipped by hook")
+
+// BeforeMRFunc is called before a merge request is migrated
+type BeforeMRFunc func(ctx context.Context, mr *gitlablib.MergeRequest) error
+
+// AfterMRFunc is called after a merge request is migrated, with the created PR (nil if not created) and the error of the migration
+type AfterMRFunc func(ctx context.Context, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, err error)
+
+// runBeforeMR calls the BeforeMR hook if set
+func runBeforeMR(ctx context.Context, opts *MigrationOptions, mr *gitlablib.MergeRequest) error {
+	if opts.BeforeMR == nil {
+		return nil
+	}
+	return opts.BeforeMR(ctx, mr)
+}
+
+// runAfterMR calls the AfterMR hook if set
+func runAfterMR(ctx context.Context, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, err error) {
+	if opts.AfterMR == nil {
+		return
+	}
+	opts.AfterMR(ctx, mr, pr, err)
+}
@@ -0,0 +1,115 @@
+//go:build integration
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+)
+
+// TestIntegrationMigrate migrates a fixture GitLab project into a throwaway GitHub repository
+// go test -tags integration ./pkg/migration/ で実行し、以下の環境変数が無い場合はskipする
+//   - IT_GITLAB_TOKEN, IT_GITLAB_PROJECT (closedまたはmergedのMRとコメントを持つfixtureのプロジェクト)
+//   - IT_GITHUB_TOKEN (repo, delete_repo), IT_GITHUB_OWNER
+//   - IT_GITLAB_URL (省略時は https://gitlab.com)
+func TestIntegrationMigrate(t *testing.T) {
+	cfg := integrationConfig(t)
+	ctx := github.NewRetryContext(logger.NewContext(context.Background(), logger.Default()), github.RetryConfig{
+		MaxRetries:    5,
+		InitialDelay:  time.Second,
+		MaxDelay:      30 * time.Second,
+		BackoffFactor: 2,
+	})
+
+	gitlabClient, err := gitlab.NewClient(cfg.GitLabToken, cfg.GitLabURL, config.DefaultHTTPTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	githubClient := github.NewClientByPAT(cfg.GitHubApiToken, config.DefaultHTTPTimeout)
+	// 失敗した場合もリポジトリが残らないよう、作成前に削除を登録する
+	t.Cleanup(func() {
+		if err := github.DeleteRepository(context.Background(), githubClient, cfg.GitHubOwner, cfg.GitHubRepo); err != nil {
+			t.Errorf("failed to delete %s/%s: %v", cfg.GitHubOwner, cfg.GitHubRepo, err)
+		}
+	})
+
+	state := NewStateStore(filepath.Join(t.TempDir(), "state.json"))
+	opts := &MigrationOptions{
+		MRAttempts: 1,
+		State:      state,
+	}
+	g := git.NewGit(cfg.WorkingDir, cfg.GitHubOwner, cfg.GitHubRepo, cfg.GitLabURL, cfg.GitLabProject)
+	g.SetLogger(logger.FromContext(ctx))
+	g.SetContext(ctx)
+	g.SetIdentity(cfg.GitAuthorName, cfg.GitAuthorEmail)
+	if err := MirrorRepository(ctx, g, cfg, githubClient, opts); err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+
+	source := gitlab.NewAPISource(gitlabClient, cfg.GitLabProject)
+	report, err := MigrateMergeRequests(ctx, source, githubClient, cfg, opts)
+	if err != nil {
+		t.Fatalf("MigrateMergeRequests() error = %v", err)
+	}
+	migrated, failed := report.Counts()
+	if migrated == 0 || failed != 0 {
+		t.Fatalf("migrated = %d, failed = %d, want all merge requests of the fixture migrated", migrated, failed)
+	}
+
+	// 移行したPRがcloseされ、各MRのnoteがコメントとして作成されていることを確認する
+	prs, err := githubClient.GetClosedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != migrated {
+		t.Errorf("closed PRs = %d, want %d", len(prs), migrated)
+	}
+	verify, err := VerifyMigration(ctx, source, githubClient, cfg, opts)
+	if err != nil {
+		t.Fatalf("VerifyMigration() error = %v", err)
+	}
+	if verify.Discrepancies() != 0 {
+		t.Errorf("missing merge requests = %v, under migrated = %d", verify.MissingMergeRequests, len(verify.UnderMigrated))
+	}
+}
+
+// integrationConfig builds the config of the integration test from the environment, skipping the test without tokens
+func integrationConfig(t *testing.T) config.GlobalConfig {
+	t.Helper()
+	env := func(key string) string {
+		v := os.Getenv(key)
+		if v == "" {
+			t.Skipf("%s is not set", key)
+		}
+		return v
+	}
+	gitlabURL := os.Getenv("IT_GITLAB_URL")
+	if gitlabURL == "" {
+		gitlabURL = "https://gitlab.com"
+	}
+	githubToken := env("IT_GITHUB_TOKEN")
+	return config.GlobalConfig{
+		GitLabToken:    env("IT_GITLAB_TOKEN"),
+		GitLabURL:      gitlabURL,
+		GitLabProject:  env("IT_GITLAB_PROJECT"),
+		GitHubGitToken: githubToken,
+		GitHubApiToken: githubToken,
+		GitHubOwner:    env("IT_GITHUB_OWNER"),
+		GitHubRepo:     fmt.Sprintf("gitlab-2-github-it-%d", time.Now().Unix()),
+		WorkingDir:     t.TempDir(),
+		Limits:         utils.DefaultLimits(),
+		GitAuthorName:  "gitlab-2-github",
+		GitAuthorEmail: "gitlab-2-github@example.com",
+	}
+}
@@ -0,0 +1,204 @@
+package migration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// MarkdownFixNone disables all markdown fixes
+const MarkdownFixNone = "none"
+
+// MarkdownFix converts a GitLab flavored markdown construct GitHub does not render
+type MarkdownFix struct {
+	Name  string
+	apply func(cfg config.GlobalConfig, body string) string
+}
+
+// markdownFixes are the built-in markdown fixes, in the order they are applied
+var markdownFixes = []*MarkdownFix{
+	{Name: "toc", apply: fixTableOfContents},
+	{Name: "tasklist", apply: fixTaskLists},
+	{Name: "blockquote", apply: fixMultilineBlockquotes},
+	{Name: "inline-diff", apply: fixInlineDiffs},
+	{Name: "uploads", apply: fixUploadLinks},
+}
+
+// tocPattern matches a GitLab table of contents tag
+var tocPattern = regexp.MustCompile(`^\s*(\[\[_TOC_\]\]|\[TOC\])\s*$`)
+
+// taskListItemPattern matches a GitLab task list item such as `- [X] done`, `1. [ ] todo` or `* [~] inapplicable`
+var taskListItemPattern = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)\[([ xX~])\](?:\s+(.*))?$`)
+
+// inlineDiffAddedPattern and inlineDiffRemovedPattern match GitLab inline diffs such as `{+ added +}` and `[- removed -]`
+var (
+	inlineDiffAddedPattern   = regexp.MustCompile(`\{\+(.+?)\+\}`)
+	inlineDiffRemovedPattern = regexp.MustCompile(`\[-(.+?)-\]`)
+)
+
+// uploadLinkPattern matches a link to a file uploaded to the GitLab project, which GitLab resolves relative to the project
+var uploadLinkPattern = regexp.MustCompile(`(\]\(|src=")(/uploads/[0-9a-f]{32}/)`)
+
+// MarkdownFixNames returns the names of the built-in markdown fixes
+func MarkdownFixNames() []string {
+	names := make([]string, 0, len(markdownFixes))
+	for _, fix := range markdownFixes {
+		names = append(names, fix.Name)
+	}
+	return names
+}
+
+// ParseMarkdownFixes returns the built-in markdown fixes enabled by the names
+// 指定された順序に関わらず、組み込みの順序で適用する
+func ParseMarkdownFixes(names []string) ([]*MarkdownFix, error) {
+	enabled := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == MarkdownFixNone || name == "" {
+			continue
+		}
+		if !isMarkdownFixName(name) {
+			return nil, fmt.Errorf("unknown markdown fix %q, must be one of %s or %s", name, strings.Join(MarkdownFixNames(), ", "), MarkdownFixNone)
+		}
+		enabled[name] = true
+	}
+
+	var fixes []*MarkdownFix
+	for _, fix := range markdownFixes {
+		if enabled[fix.Name] {
+			fixes = append(fixes, fix)
+		}
+	}
+	return fixes, nil
+}
+
+// isMarkdownFixName checks if the name is a built-in markdown fix
+func isMarkdownFixName(name string) bool {
+	for _, fix := range markdownFixes {
+		if fix.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transformBody converts a GitLab description or note body for GitHub and returns the labels added by quick actions
+func transformBody(cfg config.GlobalConfig, opts *MigrationOptions, body string) (string, []string) {
+	body, labels := applyQuickActions(opts.QuickActions, body)
+	for _, fix := range opts.MarkdownFixes {
+		body = fix.apply(cfg, body)
+	}
+	body = applyTeamMentions(cfg, opts.TeamMap, body)
+	return body, labels
+}
+
+// transformDiscussion returns the discussion whose note bodies are converted for GitHub and the labels added by quick actions
+// 元のnoteはsourceにキャッシュされているため、変更する場合はコピーする
+func transformDiscussion(cfg config.GlobalConfig, opts *MigrationOptions, discussion *gitlablib.Discussion) (*gitlablib.Discussion, []string) {
+	if (opts.QuickActions == "" || opts.QuickActions == QuickActionsKeep) && len(opts.MarkdownFixes) == 0 && len(opts.TeamMap) == 0 {
+		return discussion, nil
+	}
+
+	var labels []string
+	transformed := *discussion
+	transformed.Notes = make([]*gitlablib.Note, 0, len(discussion.Notes))
+	for _, note := range discussion.Notes {
+		// systemコメントはGitLabが生成したものであるため、そのまま移行する
+		if note.System {
+			transformed.Notes = append(transformed.Notes, note)
+			continue
+		}
+		body, noteLabels := transformBody(cfg, opts, note.Body)
+		labels = append(labels, noteLabels...)
+		copied := *note
+		copied.Body = body
+		transformed.Notes = append(transformed.Notes, &copied)
+	}
+	return &transformed, labels
+}
+
+// mapLinesOutsideCodeFences applies the function to each line of the body outside code blocks
+func mapLinesOutsideCodeFences(body string, f func(line string) (string, bool)) string {
+	var lines []string
+	inCodeFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeFence = !inCodeFence
+			lines = append(lines, line)
+			continue
+		}
+		if inCodeFence {
+			lines = append(lines, line)
+			continue
+		}
+		if mapped, keep := f(line); keep {
+			lines = append(lines, mapped)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fixTableOfContents removes the GitLab table of contents tag, since GitHub shows the outline of markdown on its own
+func fixTableOfContents(_ config.GlobalConfig, body string) string {
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		return line, !tocPattern.MatchString(line)
+	})
+}
+
+// fixTaskLists normalizes GitLab task list items to the `[ ]` and `[x]` checkboxes GitHub renders
+// GitLabの「該当なし」(`[~]`)はGitHubに存在しないため、チェック済みとして取り消し線で表示する
+func fixTaskLists(_ config.GlobalConfig, body string) string {
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		m := taskListItemPattern.FindStringSubmatch(line)
+		if m == nil {
+			return line, true
+		}
+		marker, state, text := m[1], m[2], strings.TrimRight(m[3], " \t")
+		checkbox := "[ ]"
+		if state != " " {
+			checkbox = "[x]"
+		}
+		if text == "" {
+			return marker + checkbox, true
+		}
+		if state == "~" {
+			text = "~~" + text + "~~"
+		}
+		return marker + checkbox + " " + text, true
+	})
+}
+
+// fixMultilineBlockquotes rewrites GitLab `>>>` fenced blockquotes as `>` prefixed lines
+func fixMultilineBlockquotes(_ config.GlobalConfig, body string) string {
+	inBlockquote := false
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		if strings.TrimSpace(line) == ">>>" {
+			inBlockquote = !inBlockquote
+			return "", false
+		}
+		if inBlockquote {
+			return "> " + line, true
+		}
+		return line, true
+	})
+}
+
+// fixInlineDiffs rewrites GitLab inline diffs as `<ins>` and `<del>` tags
+func fixInlineDiffs(_ config.GlobalConfig, body string) string {
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		line = inlineDiffAddedPattern.ReplaceAllString(line, "<ins>$1</ins>")
+		return inlineDiffRemovedPattern.ReplaceAllString(line, "<del>$1</del>"), true
+	})
+}
+
+// fixUploadLinks rewrites links to files uploaded to GitLab as absolute URLs, since GitHub resolves them against its own host
+func fixUploadLinks(cfg config.GlobalConfig, body string) string {
+	projectURL := gitlab.ProjectWebURL(cfg.GitLabURL, cfg.GitLabProject)
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		return uploadLinkPattern.ReplaceAllString(line, "${1}"+projectURL+"${2}"), true
+	})
+}
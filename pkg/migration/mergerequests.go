@@ -11,26 +11,42 @@ import (
 	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
 	"github.com/krrrr38/gitlab-2-github/pkg/utils"
-	gitlablib "github.com/xanzy/go-gitlab"
-	"sort"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // MigrateMergeRequests migrates GitLab merge requests to GitHub pull requests
-func MigrateMergeRequests(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions) error {
+// 途中で失敗した場合も、それまでの結果を含むreportを返す
+func MigrateMergeRequests(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions) (*Report, error) {
+	report := NewReport()
+	start := time.Now()
+	// 途中で失敗した場合も、それまでの所要時間を記録する
+	defer func() {
+		report.AddPhaseDuration(PhaseMergeRequests, time.Since(start))
+	}()
 	g := git.NewGit(cfg.WorkingDir, cfg.GitHubOwner, cfg.GitHubRepo, cfg.GitLabURL, cfg.GitLabProject)
-	// 移行済みのものは、closedとなっているかつ、PRのタイトルに "GL#<mr.IID> " が含まれているものとする
-	allClosedPRTitles, err := githubClient.GetClosedPullRequestTitles(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	g.SetLogger(logger.FromContext(ctx))
+	g.SetSubdirectory(opts.Subdirectory)
+	// 移行済みのものは、closedとなっているかつ、PRのタイトルに "GL#<mr.IID> " が含まれている、
+	// もしくはPRの本文に "<!-- gl-mr:<mr.IID> -->" が含まれているものとする (タイトルが変更されても重複移行しないように)
+	allClosedPRs, err := githubClient.GetClosedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
 	if err != nil {
-		return err
+		return report, err
 	}
 	migratedMRIIDs := make(map[int]struct{})
-	for _, title := range allClosedPRTitles {
-		// "GL#<mr.IID> " で始まっているものがあれば、migratedMRIIDsに追加
-		if strings.HasPrefix(title, "GL#") {
-			mrIIDStr := strings.Split(strings.TrimPrefix(title, "GL#"), " ")[0]
-			mrIID, _ := strconv.Atoi(mrIIDStr)
+	for _, pr := range allClosedPRs {
+		// monorepoの場合は他のプロジェクトから移行されたPRを除外する
+		if _, owned := parseOwnedMRIID(opts, pr); !owned {
+			continue
+		}
+		if mrIID, ok := parseMigratedMRIID(opts, pr); ok {
 			migratedMRIIDs[mrIID] = struct{}{}
 		}
 	}
@@ -38,56 +54,95 @@ func MigrateMergeRequests(ctx context.Context, gitlabClient *gitlablib.Client, g
 	// 前回移行MR失敗した残存PRがOpenで残っているため、中途半端にならないようにcloseさせる
 	openedPRs, err := githubClient.GetOpenedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
 	if err != nil {
-		return fmt.Errorf("failed to get opened PRs: %w", err)
+		return report, fmt.Errorf("failed to get opened PRs: %w", err)
 	}
 	for _, pr := range openedPRs {
+		if _, owned := parseOwnedMRIID(opts, pr); !owned {
+			continue
+		}
+		// --leave-prs-openや--close-statesにより、移行に成功したPRもOpenのまま残るため、それらは移行済みとする
+		mrIID, migrated, err := isMigratedOpenPR(ctx, source, opts, pr)
+		if err != nil {
+			return report, err
+		}
+		if migrated {
+			migratedMRIIDs[mrIID] = struct{}{}
+			continue
+		}
 		// migrationが失敗したため、"GL#" prefixにならないようにしてからcloseする
-		newTitle := fmt.Sprintf("[Failed] %s", pr.GetTitle())
+		newTitle := fmt.Sprintf("%s %s", opts.FailedTitleTag, pr.GetTitle())
 		if err = githubClient.UpdatePullRequestTitle(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), newTitle); err != nil {
-			return err
+			return report, err
 		}
 		if err = githubClient.ClosePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber()); err != nil {
-			return err
+			return report, err
 		}
 	}
 
 	page := 1
-	var totalProcessed, totalSucceeded, totalFailed int
+	var totalProcessed, totalSucceeded, totalFailed, totalSkipped int
+	// 再実行時にほとんどのMRがスキップされた場合も結果が分かるよう、移行を試みなかったMRを数える
+	skip := func() {
+		totalSkipped++
+		report.AddSkippedMergeRequest()
+	}
+	// MRの移行に失敗すると移行全体を打ち切るため、それまでの統計情報を残しておく
+	fail := func(err error) (*Report, error) {
+		totalProcessed++
+		totalFailed++
+		logger.FromContext(ctx).Warn("Migration stopped by a failed MR",
+			"processed", totalProcessed,
+			"succeeded", totalSucceeded,
+			"failed", totalFailed,
+			"skipped", totalSkipped)
+		return report, err
+	}
 	for {
 		// Get all merge requests or filter by IDs
-		mrs, err := gitlab.GetMergeRequests(gitlabClient, cfg.GitLabProject, page)
+		mrs, err := source.GetMergeRequests(ctx, page)
 		if err != nil {
-			return fmt.Errorf("failed to get merge requests: %w", err)
+			return report, fmt.Errorf("failed to get merge requests: %w", err)
 		}
 		if len(mrs) == 0 {
 			break
 		}
 
-		targetMRs := make([]*gitlablib.MergeRequest, 0)
+		targetMRs := make([]*gitlablib.BasicMergeRequest, 0)
 		for _, mr := range mrs {
-			if opts.ContinueFromID > 0 && mr.IID < opts.ContinueFromID {
-				logger.Debug("Skipping MR (before continue-from point)", "iid", mr.IID, "title", mr.Title)
+			if opts.ContinueFromID > 0 && int(mr.IID) < opts.ContinueFromID {
+				logger.FromContext(ctx).Debug("Skipping MR (before continue-from point)", "iid", mr.IID, "title", mr.Title)
+				skip()
 				continue
 			}
 			if len(opts.FilterMergeReqIDs) > 0 {
-				for _, id := range opts.FilterMergeReqIDs {
-					if mr.IID == id {
-						targetMRs = append(targetMRs, mr)
-						break
-					}
+				if slices.Contains(opts.FilterMergeReqIDs, int(mr.IID)) {
+					targetMRs = append(targetMRs, mr)
+				} else {
+					skip()
 				}
 				continue
 			}
 
+			// resumeの場合は、前回までに移行に成功したMRをスキップする (失敗したMRは再試行する)
+			if opts.Resume && opts.State != nil {
+				if state, ok := opts.State.Get(int(mr.IID)); ok && state.Status == StateSucceeded {
+					logger.FromContext(ctx).Debug("Skipping MR succeeded in a previous run", "id", mr.IID, "title", mr.Title)
+					skip()
+					continue
+				}
+			}
+
 			// 既に GitHub 側でプルリクエストが存在するかを確認して、あればスキップする
-			_, alreadyMigrated := migratedMRIIDs[mr.IID]
+			_, alreadyMigrated := migratedMRIIDs[int(mr.IID)]
 			if alreadyMigrated {
-				logger.Debug("Skipping already migrated MR", "id", mr.IID, "title", mr.Title)
+				logger.FromContext(ctx).Debug("Skipping already migrated MR", "id", mr.IID, "title", mr.Title)
+				skip()
 				continue
 			}
 
-			if mr.State == "opened" {
-				continue // OpenになっているMRは移行対象外
+			if !opts.migratesState(mr.State) {
+				skip()
+				continue // --mr-statesに含まれない状態のMRは移行対象外 (デフォルトではOpenのMRは移行しない)
 			}
 
 			targetMRs = append(targetMRs, mr)
@@ -98,113 +153,348 @@ func MigrateMergeRequests(ctx context.Context, gitlabClient *gitlablib.Client, g
 			// コンテキストが既にキャンセルされていないか確認
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return report, ctx.Err()
 			default:
 				// 処理を継続
 			}
 
-			logger.Info("Migrating MR", "id", mr.IID, "title", mr.Title)
+			logger.FromContext(ctx).Info("Migrating MR", "id", mr.IID, "title", mr.Title)
+			entry := report.AddMergeRequest(mr)
 
 			// Get detailed MR information
-			detailedMR, _, err := gitlabClient.MergeRequests.GetMergeRequest(cfg.GitLabProject, mr.IID, nil)
+			detailedMR, err := source.GetMergeRequest(ctx, int(mr.IID))
+			// 一覧の取得後に削除されたMRは移行できないため、移行全体を止めずにスキップする
+			var notFoundErr *gitlab.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				logger.FromContext(ctx).Warn("Skipping MR deleted on GitLab", "id", mr.IID, "title", mr.Title)
+				entry.Status = MergeRequestStatusSkipped
+				entry.Error = err.Error()
+				skip()
+				continue
+			}
 			if err != nil {
-				logger.Warn("Failed to get detailed info for MR", "id", mr.IID, "error", err)
-				return err
+				logger.FromContext(ctx).Warn("Failed to get detailed info for MR", "id", mr.IID, "error", err)
+				entry.Status = MergeRequestStatusFailed
+				entry.Error = err.Error()
+				return fail(err)
 			}
 
 			// Create branches and PR in GitHub
-			err = processMergeRequest(ctx, gitlabClient, githubClient, cfg, opts, detailedMR, g)
+			err = processMergeRequestWithRetry(ctx, source, githubClient, cfg, opts, detailedMR, g, entry)
+			if errors.Is(err, ErrSkip) {
+				logger.FromContext(ctx).Info("Skipping MR by hook", "id", mr.IID, "title", mr.Title)
+				entry.Status = MergeRequestStatusSkipped
+				skip()
+				continue
+			}
+			// コメントの作成などの失敗は警告に留めているため、GitHubが停止していると判断された場合はここで移行を打ち切る
+			if err == nil {
+				err = github.CircuitBreakerError(ctx)
+			}
+			report.AddPhaseDuration(PhaseComments, time.Duration(entry.CommentsSeconds*float64(time.Second)))
 			if err != nil {
-				logger.Warn("Failed to migrate MR", "id", mr.IID, "error", err)
-				return err
+				logger.FromContext(ctx).Warn("Failed to migrate MR", "id", mr.IID, "error", err)
+				entry.Status = MergeRequestStatusFailed
+				entry.Error = err.Error()
+				if opts.State != nil {
+					if serr := opts.State.MarkFailed(int(mr.IID), err); serr != nil {
+						logger.FromContext(ctx).Warn("Failed to save migration state", "id", mr.IID, "error", serr)
+					}
+				}
+				return fail(err)
 			} else {
+				entry.Status = MergeRequestStatusMigrated
+				if opts.State != nil {
+					if serr := opts.State.MarkSucceeded(int(mr.IID), entry.PRNumber); serr != nil {
+						logger.FromContext(ctx).Warn("Failed to save migration state", "id", mr.IID, "error", serr)
+					}
+				}
 				totalProcessed++
 				totalSucceeded++
 			}
 
 		}
 		// 進捗状況を表示
-		logger.Info("Progress",
+		logger.FromContext(ctx).Info("Progress",
 			"processed", totalProcessed,
 			"target", len(targetMRs),
 			"succeeded", totalSucceeded,
 			"failed", totalFailed,
+			"skipped", totalSkipped,
 			"page", page)
 		page += 1
 	}
 
 	// 最終の統計情報を表示
-	logger.Info("Migration completed",
+	fields := append([]interface{}{
 		"processed", totalProcessed,
 		"succeeded", totalSucceeded,
-		"failed", totalFailed)
+		"failed", totalFailed,
+		"skipped", totalSkipped,
+		"merge_requests_duration", time.Since(start).Round(time.Millisecond).String(),
+	}, report.PhaseLogFields()...)
+	logger.FromContext(ctx).Info("Migration completed", fields...)
 
-	return nil
+	return report, nil
+}
+
+// mrMarkerPattern matches the hidden marker embedded in migrated PR bodies
+var mrMarkerPattern = regexp.MustCompile(`<!-- gl-mr:(\d+) -->`)
+
+// formatMRMarker returns the hidden marker embedded in the body of a migrated PR
+func formatMRMarker(mrIID int) string {
+	return fmt.Sprintf("<!-- gl-mr:%d -->", mrIID)
+}
+
+// noteMarkerPattern matches the hidden marker embedded in migrated comment bodies
+var noteMarkerPattern = regexp.MustCompile(`<!-- gl-note:(\d+) -->`)
+
+// formatNoteMarker returns the hidden marker embedded in the body of a migrated comment
+// verifyコマンドでGitLabのnoteが移行されたかを確認するために利用する
+func formatNoteMarker(noteID int) string {
+	return fmt.Sprintf("<!-- gl-note:%d -->", noteID)
+}
+
+// parseNoteMarkers returns the GitLab note IDs embedded in a migrated comment body
+func parseNoteMarkers(body string) []int {
+	var noteIDs []int
+	for _, m := range noteMarkerPattern.FindAllStringSubmatch(body, -1) {
+		if noteID, err := strconv.Atoi(m[1]); err == nil {
+			noteIDs = append(noteIDs, noteID)
+		}
+	}
+	return noteIDs
+}
+
+// parseMigratedMRIID resolves the GitLab MR IID from a migrated PR title or body
+func parseMigratedMRIID(opts *MigrationOptions, pr *githublib.PullRequest) (int, bool) {
+	title := pr.GetTitle()
+	// 移行失敗としてcloseされたPRは移行済みとして扱わない
+	if strings.HasPrefix(title, opts.FailedTitleTag+" ") {
+		return 0, false
+	}
+	// "GL#<mr.IID> " で始まっているもの
+	if strings.HasPrefix(title, "GL#") {
+		mrIIDStr := strings.Split(strings.TrimPrefix(title, "GL#"), " ")[0]
+		if mrIID, err := strconv.Atoi(mrIIDStr); err == nil {
+			return mrIID, true
+		}
+	}
+	// タイトルが変更されていても、本文のmarkerから判定する
+	if m := mrMarkerPattern.FindStringSubmatch(pr.GetBody()); m != nil {
+		if mrIID, err := strconv.Atoi(m[1]); err == nil {
+			return mrIID, true
+		}
+	}
+	return 0, false
+}
+
+// isMigratedOpenPR resolves the GitLab MR IID of an open PR and checks if it was left open by a successful migration
+// 移行状態に記録がある場合は、同じPRで成功している場合のみ移行済みとする
+// 記録が無い場合は、MRの状態が--close-statesに含まれない (移行してもOpenのまま残る) 場合に移行済みとする (--leave-prs-openでは移行状態の記録を必須とする)
+func isMigratedOpenPR(ctx context.Context, source GitLabSource, opts *MigrationOptions, pr *githublib.PullRequest) (int, bool, error) {
+	mrIID, ok := parseMigratedMRIID(opts, pr)
+	if !ok {
+		return 0, false, nil
+	}
+	if opts.State != nil {
+		if state, ok := opts.State.Get(mrIID); ok {
+			return mrIID, state.Status == StateSucceeded && state.PRNumber == pr.GetNumber(), nil
+		}
+	}
+	if opts.LeavePRsOpen {
+		return mrIID, false, nil
+	}
+	mr, err := source.GetMergeRequest(ctx, mrIID)
+	var notFoundErr *gitlab.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return mrIID, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get merge request of open PR #%d: %w", pr.GetNumber(), err)
+	}
+	return mrIID, !opts.closesState(mr.State), nil
+}
+
+// mrBranchName returns the name of the source or target branch created for a merge request
+// monorepoの場合はプロジェクト間でブランチ名が衝突しないよう、subdirectoryをブランチ名に含める
+func mrBranchName(opts *MigrationOptions, mrIID int, kind string) string {
+	return fmt.Sprintf("%s-%d-%s", mrBranchPrefix(opts), mrIID, kind)
+}
+
+// mrBranchPrefix returns the common prefix of branches created for merge requests
+func mrBranchPrefix(opts *MigrationOptions) string {
+	if opts.Subdirectory == "" {
+		return "gitlab-mr"
+	}
+	return "gitlab-mr-" + unsafePathChars.ReplaceAllString(strings.Trim(opts.Subdirectory, "/"), "-")
+}
+
+// parseOwnedMRIID resolves the GitLab MR IID from the head branch of a PR created for the current project
+// subdirectoryが指定されていない場合は、すべてのPRを対象とする
+func parseOwnedMRIID(opts *MigrationOptions, pr *githublib.PullRequest) (int, bool) {
+	if opts.Subdirectory == "" {
+		return 0, true
+	}
+	ref := pr.GetHead().GetRef()
+	prefix := mrBranchPrefix(opts) + "-"
+	if !strings.HasPrefix(ref, prefix) || !strings.HasSuffix(ref, "-source") {
+		return 0, false
+	}
+	mrIID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(ref, prefix), "-source"))
+	if err != nil {
+		return 0, false
+	}
+	return mrIID, true
 }
 
 // processMergeRequest handles the migration of a single merge request
-func processMergeRequest(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, g *git.Git) error {
+func processMergeRequest(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, g *git.Git, entry *MergeRequestReport) (err error) {
+	// callbackは移行の前後に呼び、AfterMRはworktreeの削除などの後処理がすべて終わってから呼ぶ
+	if err := runBeforeMR(ctx, opts, mr); err != nil {
+		return err
+	}
+	var pr *githublib.PullRequest
+	defer func() {
+		runAfterMR(ctx, opts, mr, pr, err)
+	}()
+
 	// Prepare unique branch names for both source and target
-	sourceBranch := fmt.Sprintf("gitlab-mr-%d-source", mr.IID)
-	targetBranch := fmt.Sprintf("gitlab-mr-%d-target", mr.IID)
+	sourceBranch := mrBranchName(opts, int(mr.IID), "source")
+	targetBranch := mrBranchName(opts, int(mr.IID), "target")
+	dump := &mergeRequestDump{MergeRequest: mr, SourceBranch: sourceBranch, TargetBranch: targetBranch}
+	defer func() {
+		writeDebugDump(ctx, cfg, opts, g, dump, err)
+	}()
 	defer func() {
 		//// Delete source branch
 		//err := githubClient.DeleteBranch(ctx, cfg.GitHubOwner, cfg.GitHubRepo, sourceBranch)
 		//if err != nil {
-		//	logger.Warn("Failed to delete source branch", "branch", sourceBranch, "error", err)
+		//	logger.FromContext(ctx).Warn("Failed to delete source branch", "branch", sourceBranch, "error", err)
 		//}
 		//err = githubClient.DeleteBranch(ctx, cfg.GitHubOwner, cfg.GitHubRepo, targetBranch)
 		//if err != nil {
-		//	logger.Warn("Failed to delete temporary target branch", "branch", targetBranch, "error", err)
+		//	logger.FromContext(ctx).Warn("Failed to delete temporary target branch", "branch", targetBranch, "error", err)
 		//}
 		// 検証のためにコメントアウト
 	}()
 
-	hasDiffs, err := gitlab.HasMergeRequestDiffs(gitlabClient, cfg.GitLabProject, mr.IID)
+	hasDiffs, err := source.HasMergeRequestDiffs(ctx, int(mr.IID))
 	if err != nil {
 		return fmt.Errorf("failed to check if MR has diffs: %w", err)
 	}
 
-	pr, err := createPullRequest(ctx, gitlabClient, githubClient, cfg, mr, sourceBranch, targetBranch, g, hasDiffs)
+	// MRごとにworktreeを作成し、ブランチ操作が他のMRのHEADに影響しないようにする
+	worktree, err := g.AddWorktree(fmt.Sprintf("mr-%d", mr.IID))
+	if err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
+	}
+	defer func() {
+		if err := g.RemoveWorktree(worktree); err != nil {
+			logger.FromContext(ctx).Warn("Failed to remove worktree", "mr", mr.IID, "error", err)
+		}
+	}()
+
+	// マージリクエストの承認情報を取得
+	approvals, err := source.GetMergeRequestApprovals(ctx, int(mr.IID))
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get MR approvals", "error", err)
+		// エラーがあっても処理は続行
+	}
+
+	// discussionはPRの説明文と移行するコメントの両方で利用するため、一度だけ取得する
+	discussions, discussionsErr := source.GetMergeRequestDiscussions(ctx, int(mr.IID), opts.MaxDiscussions, opts.DiscussionOrder)
+	if discussionsErr != nil {
+		discussionsErr = fmt.Errorf("failed to get discussions: %w on mr.IID=%d", discussionsErr, mr.IID)
+	}
+	dump.Discussions = discussions
+
+	pr, err = createPullRequest(ctx, source, githubClient, cfg, opts, mr, approvals, isAutoMerged(mr, discussions), sourceBranch, targetBranch, worktree, hasDiffs)
 	if err != nil {
 		return fmt.Errorf("failed to create PR: %w", err)
 	}
 	if pr == nil {
 		return nil
 	}
-	if err := migratePullRequestComments(ctx, gitlabClient, githubClient, cfg, opts, mr, pr); err != nil {
-		logger.Warn("Failed to migrate some comments", "error", err)
+	entry.PRNumber = pr.GetNumber()
+	entry.PRURL = pr.GetHTMLURL()
+
+	commentsStart := time.Now()
+	var commentsResult CommentsResult
+	if discussionsErr != nil {
+		logger.FromContext(ctx).Warn("Failed to migrate some comments", "error", discussionsErr)
 		// Continue despite comment migration errors
+	} else {
+		commentsResult = migratePullRequestComments(ctx, githubClient, cfg, opts, mr, pr, discussions, worktree)
 	}
+	entry.CommentsSeconds = time.Since(commentsStart).Seconds()
+	entry.Comments = commentsResult
 
-	if mr.State == "closed" {
-		err = githubClient.AddLabelsToIssue(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), []string{"closed"})
-		if err != nil {
-			logger.Warn("Failed to add pr closed labels", "error", err)
+	if opts.SummaryComment {
+		if err := createSummaryComment(ctx, githubClient, cfg, mr, pr, approvals, commentsResult.Created); err != nil {
+			logger.FromContext(ctx).Warn("Failed to create summary comment", "error", err)
 		}
-	} else if mr.State == "merged" {
-		err = githubClient.AddLabelsToIssue(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), []string{"merged"})
+	}
+
+	// GitLabのラベルは --label-map に従って変換し、MRの状態を表すラベルと合わせて付与する
+	// --quick-actions=translate の場合は、説明文やコメントの "/label" のラベルも付与する
+	_, descriptionLabels := applyQuickActions(opts.QuickActions, mr.Description)
+	gitlabLabels := append(append(append([]string{}, mr.Labels...), descriptionLabels...), commentsResult.QuickActionLabels...)
+	labels := opts.LabelMap.ResolveAll(gitlabLabels)
+	if mr.State != "opened" {
+		labels = append(labels, mr.State)
+	}
+	if len(labels) > 0 {
+		err = githubClient.AddLabelsToIssue(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), labels)
 		if err != nil {
-			logger.Warn("Failed to add pr merged labels", "error", err)
+			logger.FromContext(ctx).Warn("Failed to add pr labels", "error", err)
+		}
+	}
+
+	if err := migratePipelineStatus(ctx, githubClient, cfg, opts, mr, pr); err != nil {
+		logger.FromContext(ctx).Warn("Failed to migrate pipeline status", "error", err)
+	}
+
+	// OpenのままのPRにはteamをreviewerとしてリクエストする (通知を抑制する場合はリクエストしない)
+	if !opts.closesState(mr.State) && len(opts.TeamReviewers) > 0 && !githubClient.QuietNotifications() {
+		if err := githubClient.RequestTeamReviewers(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), opts.TeamReviewers); err != nil {
+			logger.FromContext(ctx).Warn("Failed to request team reviewers", "error", err)
 		}
 	}
 
-	// 4. Close the PR if the original MR was closed/merged
-	if mr.State == "closed" || mr.State == "merged" {
+	// --leave-prs-open の場合は、レビューしてからcloseできるよう、ラベルのみ付与してOpenのまま残す
+	if opts.LeavePRsOpen {
+		return nil
+	}
+
+	// 4. Close the PR if the original MR is in --close-states (closed/merged by default)
+	// mergedのMRは、可能であれば実際にmergeしてGitHub上でもmergedとして表示させる
+	// mergeの対象はMRごとに作成したtargetブランチのため、デフォルトブランチには影響しない
+	if mr.State == "merged" && opts.ReallyMerge {
+		message := fmt.Sprintf("Merge GitLab MR !%d: %s", mr.IID, mr.Title)
+		if err := githubClient.MergePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), message); err != nil {
+			// mergeできない場合は、これまで通りmergedラベルを付与してcloseする
+			logger.FromContext(ctx).Warn("Failed to merge PR, closing it instead", "number", pr.GetNumber(), "error", err)
+		} else {
+			logger.FromContext(ctx).Debug("Merged GitHub PR", "number", pr.GetNumber())
+			return nil
+		}
+	}
+	if opts.closesState(mr.State) {
 		err = github.RetryableOperation(ctx, func() error {
 			return githubClient.ClosePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber())
 		})
 
 		if err != nil {
-			logger.Warn("Failed to close PR", "error", err)
+			logger.FromContext(ctx).Warn("Failed to close PR", "error", err)
 		} else {
-			logger.Debug("Closed GitHub PR", "number", pr.GetNumber())
+			logger.FromContext(ctx).Debug("Closed GitHub PR", "number", pr.GetNumber())
 		}
 	}
 	return nil
 }
 
-func preparePullRequestBranches(g *git.Git, mr *gitlablib.MergeRequest, sourceBranch, targetBranch string, hasDiffs bool) error {
+func preparePullRequestBranches(ctx context.Context, source GitLabSource, opts *MigrationOptions, g *git.Git, mr *gitlablib.MergeRequest, sourceBranch, targetBranch string, hasDiffs bool) error {
 	fallbackNoDiffPR := !hasDiffs
 	hasCreatedTargetBranch := false
 
@@ -214,8 +504,7 @@ func preparePullRequestBranches(g *git.Git, mr *gitlablib.MergeRequest, sourceBr
 				// not our refとなっているMRはGitLab上でも壊れてno diffとなってしまっているため、diff無しでPRを作成する
 				fallbackNoDiffPR = true
 			} else {
-				logger.Warn("Failed to create target branch", "error", err, "branch", targetBranch, "sha", mr.DiffRefs.BaseSha)
-				return nil
+				return fmt.Errorf("failed to create target branch %s at %s: %w", targetBranch, mr.DiffRefs.BaseSha, err)
 			}
 		} else {
 			hasCreatedTargetBranch = true
@@ -230,11 +519,24 @@ func preparePullRequestBranches(g *git.Git, mr *gitlablib.MergeRequest, sourceBr
 		}
 		if err := g.CreateBranch(sourceBranch, sourceBranchSha); err != nil {
 			if strings.Contains(err.Error(), "not our ref") {
-				// not our refとなっているMRはGitLab上でも壊れてno diffとなってしまっているため、diff無しでPRを作成する
-				fallbackNoDiffPR = true
+				// force pushで消えたhead shaの場合、取得可能な最新のdiff versionのhead shaを利用する
+				recoveredSha, verr := source.GetLatestMergeRequestVersionSHA(ctx, int(mr.IID), func(sha string) bool {
+					return sha != sourceBranchSha && g.FetchCommit(sha) == nil
+				})
+				if verr != nil {
+					logger.FromContext(ctx).Warn("Failed to get MR diff versions", "error", verr, "mr", mr.IID)
+				}
+				if recoveredSha != "" {
+					if err := g.CreateBranch(sourceBranch, recoveredSha); err != nil {
+						return fmt.Errorf("failed to create source branch from diff version: %w", err)
+					}
+					logger.FromContext(ctx).Info("Recovered MR head from diff version", "mr", mr.IID, "head_sha", sourceBranchSha, "sha", recoveredSha)
+				} else {
+					// not our refとなっているMRはGitLab上でも壊れてno diffとなってしまっているため、diff無しでPRを作成する
+					fallbackNoDiffPR = true
+				}
 			} else {
-				logger.Warn("Failed to create source branch", "error", err, "branch", targetBranch, "sha", sourceBranchSha)
-				return nil
+				return fmt.Errorf("failed to create source branch %s at %s: %w", sourceBranch, sourceBranchSha, err)
 			}
 		}
 	}
@@ -249,7 +551,20 @@ func preparePullRequestBranches(g *git.Git, mr *gitlablib.MergeRequest, sourceBr
 		if err := g.CreateBranch(sourceBranch, ""); err != nil {
 			return fmt.Errorf("failed to create fallback no diff source branch: %w", err)
 		}
-		if err := g.Commit("sync no diff merge request", "--allow-empty"); err != nil {
+		if opts.NoDiffNote {
+			notePath := path.Join(strings.Trim(opts.Subdirectory, "/"), noDiffNoteFile)
+			if err := g.WriteFile(notePath, []byte(formatNoDiffNote(mr))); err != nil {
+				return err
+			}
+			if err := g.Add(notePath); err != nil {
+				return err
+			}
+		}
+		message := opts.NoDiffCommitMessage
+		if message == "" {
+			message = DefaultNoDiffCommitMessage
+		}
+		if err := g.Commit(message, "--allow-empty"); err != nil {
 			return fmt.Errorf("failed to create fallback no diff source branch empty commit: %w", err)
 		}
 	}
@@ -260,40 +575,49 @@ func preparePullRequestBranches(g *git.Git, mr *gitlablib.MergeRequest, sourceBr
 	return nil
 }
 
-func createPullRequest(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, cfg config.GlobalConfig, mr *gitlablib.MergeRequest, sourceBranch, targetBranch string, g *git.Git, hasDiffs bool) (*githublib.PullRequest, error) {
-	logger.Debug("Creating unique branches for migration", "mr", mr.IID, "source", sourceBranch, "target", targetBranch)
+// DefaultNoDiffCommitMessage is the message of the commit created for a merge request without diff when --no-diff-commit-message is not given
+const DefaultNoDiffCommitMessage = "sync no diff merge request"
 
-	err := preparePullRequestBranches(g, mr, sourceBranch, targetBranch, hasDiffs)
+// noDiffNoteFile is the file committed by --no-diff-note to the branch of a merge request without diff
+const noDiffNoteFile = "MIGRATION_NOTE.md"
+
+// formatNoDiffNote renders the note explaining why the PR of the merge request has no changes of GitLab
+func formatNoDiffNote(mr *gitlablib.MergeRequest) string {
+	return fmt.Sprintf("# GitLab MR !%d had no diff\n\n"+
+		"The merge request [%s](%s) had no diff on GitLab, or its commits could not be fetched.\n"+
+		"This file was added by gitlab-2-github so that the pull request has a change to show.\n"+
+		"Its description and discussions were migrated to the pull request.\n",
+		mr.IID, mr.Title, mr.WebURL)
+}
+
+func createPullRequest(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, approvals []gitlab.ApprovalInfo, autoMerged bool, sourceBranch, targetBranch string, g *git.Git, hasDiffs bool) (*githublib.PullRequest, error) {
+	logger.FromContext(ctx).Debug("Creating unique branches for migration", "mr", mr.IID, "source", sourceBranch, "target", targetBranch)
+
+	err := preparePullRequestBranches(ctx, source, opts, g, mr, sourceBranch, targetBranch, hasDiffs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare branches: %w", err)
 	}
 
+	// push直後のブランチがGitHub APIから参照できるようになるまで待つ
+	if opts.RefPollAttempts > 0 {
+		if err := githubClient.WaitForBranches(ctx, cfg.GitHubOwner, cfg.GitHubRepo, opts.RefPollAttempts, opts.RefPollInterval, sourceBranch, targetBranch); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create GitHub PR
 	// Prepare PR title (移行済みかどうかのmappingのために "GL#<mr.IID> " を付与)
+	// draft状態はGitHubのdraftフラグで表現するため、タイトルからGitLabのdraft prefixは除去する
+	mrTitle := stripDraftPrefix(mr.Title)
 	var title string
-	if mr.State == "closed" {
-		title = fmt.Sprintf("GL#%d [Closed] %s", mr.IID, mr.Title)
+	if mr.State == "closed" && opts.ClosedTitleTag != "" {
+		title = fmt.Sprintf("GL#%d %s %s", mr.IID, opts.ClosedTitleTag, mrTitle)
 	} else {
-		title = fmt.Sprintf("GL#%d %s", mr.IID, mr.Title)
-	}
-	truncatedTitle := utils.TruncateText(title, utils.MaxPRTitleLength)
-	// マージリクエストの承認情報を取得
-	approvals, err := gitlab.GetMergeRequestApprovals(gitlabClient, cfg.GitLabProject, mr.IID)
-	if err != nil {
-		logger.Warn("Failed to get MR approvals", "error", err)
-		// エラーがあっても処理は続行
+		title = fmt.Sprintf("GL#%d %s", mr.IID, mrTitle)
 	}
-
+	truncatedTitle := utils.TruncateTextWithOptions(title, cfg.Limits.PRTitle, cfg.Limits.Truncate)
 	// 承認情報をフォーマット
-	var approvalsText string
-	if len(approvals) > 0 {
-		approvalsText = ""
-		for _, approval := range approvals {
-			approvalsText += fmt.Sprintf("- Approved by `%s` on %s\n",
-				approval.User,
-				approval.CreatedAt.Format("2006-01-02 15:04:05"))
-		}
-	}
+	approvalsText := formatApprovals(approvals)
 
 	// 日時情報の取得
 	createdAt := ""
@@ -301,23 +625,29 @@ func createPullRequest(ctx context.Context, gitlabClient *gitlablib.Client, gith
 		createdAt = mr.CreatedAt.Format("2006-01-02 15:04:05 MST")
 	}
 
-	// Leave room for header (around 200-300 chars)
-	description := utils.TruncateText(mr.Description, utils.MaxPRDescriptionLength-300)
+	// auto-mergeによるmergeは、GitHub上では分からないため説明文に残す
+	mergedVia := ""
+	if autoMerged {
+		mergedVia = " (merged via auto-merge)"
+	}
 
 	// 説明文にメタデータを含めたヘッダーを追加
-	body := fmt.Sprintf("<details><summary>%s Created GitLab Merge Request</summary>\n\n"+
-		"**Original MR:** %s/%s/merge_requests/%d\n"+
+	header := fmt.Sprintf("%s\n<details><summary>%s Created GitLab Merge Request</summary>\n\n"+
+		"**Original MR:** %s\n"+
 		"**Created:** %s\n"+
-		"**Status:** %s\n"+
-		"**Approvals:** \n%s\n</details>\n\n%s",
+		"**Target branch:** %s\n"+
+		"**Status:** %s%s\n"+
+		"**Approvals:** \n%s\n</details>\n\n",
+		formatMRMarker(int(mr.IID)),
 		mr.Author.Username,
-		cfg.GitLabURL, cfg.GitLabProject, mr.IID,
+		gitlab.MergeRequestWebURL(cfg.GitLabURL, cfg.GitLabProject, int(mr.IID)),
 		createdAt,
+		opts.targetBranchName(mr),
 		mr.State,
-		approvalsText,
-		description)
+		mergedVia,
+		approvalsText)
 
-	body = utils.TruncateText(body, utils.MaxPRDescriptionLength)
+	body := formatPullRequestBody(cfg, opts, header, mr.Description)
 
 	// Create the PR
 	var pr *githublib.PullRequest
@@ -328,7 +658,7 @@ func createPullRequest(ctx context.Context, gitlabClient *gitlablib.Client, gith
 			Body:                body,
 			Head:                sourceBranch,
 			Base:                targetBranch,
-			Draft:               mr.WorkInProgress,
+			Draft:               shouldBeDraft(mr),
 			MaintainerCanModify: true,
 		})
 		return err
@@ -338,115 +668,376 @@ func createPullRequest(ctx context.Context, gitlabClient *gitlablib.Client, gith
 		// Special handling for no diff error
 		var noDiffErr *github.NoDiffError
 		if errors.As(err, &noDiffErr) {
-			logger.Debug("No difference ignored", "source", noDiffErr.Head, "target", noDiffErr.Base)
-		} else {
-			return nil, fmt.Errorf("failed to create GitHub PR: %w, source=%s, base=%s", err, sourceBranch, targetBranch)
+			// PRは作成されていないため、呼び出し元ではコメントの移行やcloseを行わない
+			logger.FromContext(ctx).Debug("No difference ignored", "source", noDiffErr.Head, "target", noDiffErr.Base)
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to create GitHub PR: %w, source=%s, base=%s", err, sourceBranch, targetBranch)
 	}
 
-	logger.Info("Created GitHub PR", "number", pr.GetNumber(), "url", pr.GetHTMLURL(), "mr", mr.WebURL)
+	logger.FromContext(ctx).Info("Created GitHub PR", "number", pr.GetNumber(), "url", pr.GetHTMLURL(), "mr", mr.WebURL)
 	return pr, nil
 }
 
-// migrateComments migrates comments from a GitLab merge request to a GitHub pull request
-func migratePullRequestComments(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest) error {
-	// Get discussions from GitLab MR to track comment relationships
-	discussions, err := gitlab.GetMergeRequestDiscussions(gitlabClient, cfg.GitLabProject, mr.IID, opts.MaxDiscussions)
+// formatPullRequestBody converts the MR description for GitHub and appends it to the header within the PR description limit
+// ヘッダーの閉じタグが切り詰められないよう、実際のヘッダー長を差し引いた長さで説明文のみを切り詰める
+func formatPullRequestBody(cfg config.GlobalConfig, opts *MigrationOptions, header, mrDescription string) string {
+	descriptionLength := cfg.Limits.PRDescription - utf8.RuneCountInString(header)
+	if descriptionLength < 0 {
+		descriptionLength = 0
+	}
+	description, _ := transformBody(cfg, opts, mrDescription)
+	description = utils.TruncateTextWithOptions(description, descriptionLength, cfg.Limits.Truncate)
+	return utils.TruncateTextWithOptions(header+description, cfg.Limits.PRDescription, cfg.Limits.Truncate)
+}
+
+// shouldBeDraft checks if the PR of the merge request is created as a draft
+// closedやmergedのMRはdraftの状態が残っていてもレビュー中ではないため、draftとしない
+// OpenのMRはGitLabの現在のdraft状態に従い、ready後もタイトルに残ったprefixや古いWIPフラグは参照しない
+func shouldBeDraft(mr *gitlablib.MergeRequest) bool {
+	if mr.State != "opened" {
+		return false
+	}
+	return mr.Draft
+}
+
+// draftPrefixPattern matches GitLab draft title prefixes such as "Draft:", "WIP:", "[Draft]" and "[WIP]"
+var draftPrefixPattern = regexp.MustCompile(`(?i)^\s*(\[(draft|wip)\]|\(draft\)|(draft|wip):)\s*`)
+
+// stripDraftPrefix removes GitLab draft prefixes from a merge request title
+func stripDraftPrefix(title string) string {
+	stripped := draftPrefixPattern.ReplaceAllString(title, "")
+	if stripped == "" {
+		return title
+	}
+	return stripped
+}
+
+// formatApprovals renders the approval list of a merge request
+func formatApprovals(approvals []gitlab.ApprovalInfo) string {
+	var approvalsText string
+	for _, approval := range approvals {
+		approvalsText += fmt.Sprintf("- Approved by `%s` on %s\n",
+			approval.User,
+			approval.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return approvalsText
+}
+
+// createSummaryComment posts and pins a comment summarizing the original merge request
+func createSummaryComment(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, approvals []gitlab.ApprovalInfo, migratedComments int) error {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04:05 MST")
+	}
+	approvalsText := formatApprovals(approvals)
+	if approvalsText == "" {
+		approvalsText = "-\n"
+	}
+	body := fmt.Sprintf("**Migrated from GitLab Merge Request**\n\n"+
+		"**Original MR:** %s\n"+
+		"**Author:** `%s`\n"+
+		"**Created:** %s\n"+
+		"**Merged:** %s\n"+
+		"**Migrated comments:** %d\n"+
+		"**Approvals:** \n%s",
+		gitlab.MergeRequestWebURL(cfg.GitLabURL, cfg.GitLabProject, int(mr.IID)),
+		mr.Author.Username,
+		formatTime(mr.CreatedAt),
+		formatTime(mr.MergedAt),
+		migratedComments,
+		approvalsText)
+
+	comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), body, false)
 	if err != nil {
-		return fmt.Errorf("failed to get discussions: %w on mr.IID=%d", err, mr.IID)
+		return err
 	}
+	// pinできなくてもsummaryコメント自体は残っているため、警告に留める
+	if err := githubClient.PinIssueComment(ctx, comment.GetNodeID()); err != nil {
+		logger.FromContext(ctx).Warn("Failed to pin summary comment", "error", err, "comment", comment.GetHTMLURL())
+	}
+	return nil
+}
 
-	// Create corresponding comments in GitHub PR
-	processedCount := 0
+// migrateComments migrates comments from a GitLab merge request to a GitHub pull request
+// 失敗したdiscussionは後から再移行できるよう、IDを結果に含める
+func migratePullRequestComments(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, discussions []*gitlablib.Discussion, g *git.Git) CommentsResult {
+	var result CommentsResult
 
+	var targets []*gitlablib.Discussion
 	for _, discussion := range discussions {
-		err = createGitHubDiscussion(ctx, githubClient, cfg, mr, pr, discussion)
+		discussion, older := filterDiscussionSince(opts, discussion)
+		result.Filtered += older
+		if discussion == nil {
+			continue
+		}
+		discussion, filtered := filterDiscussion(opts, discussion)
+		result.Filtered += filtered
+		if discussion == nil {
+			continue
+		}
+		discussion, labels := transformDiscussion(cfg, opts, discussion)
+		result.QuickActionLabels = append(result.QuickActionLabels, labels...)
+		targets = append(targets, discussion)
+	}
+
+	// Create corresponding comments in GitHub PR
+	// discussionごとに並行して作成し、replyは各discussion内で先頭のコメントの後に順番に作成する
+	type discussionResult struct {
+		notes int
+		err   error
+	}
+	results := make([]discussionResult, len(targets))
+	concurrency := opts.CommentConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, discussion := range targets {
+		// 並行数が1の場合にGitLabでの順序どおりに作成されるよう、goroutineの起動前に枠を確保する
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, discussion *gitlablib.Discussion) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			notes, err := createGitHubDiscussion(ctx, githubClient, cfg, opts, mr, pr, discussion, g)
+			results[i] = discussionResult{notes: notes, err: err}
+		}(i, discussion)
+	}
+	wg.Wait()
+
+	var succeeded []*gitlablib.Discussion
+	for i, discussion := range targets {
+		notes, err := results[i].notes, results[i].err
+		result.Notes += notes
 		if err != nil {
-			logger.Warn(fmt.Sprintf("Failed to create comment: %v", discussion), "error", err)
+			logger.FromContext(ctx).Warn(fmt.Sprintf("Failed to create comment: %v", discussion), "error", err)
+			result.Failed++
+			result.FailedDiscussionIDs = append(result.FailedDiscussionIDs, discussion.ID)
 			continue
 		}
+		succeeded = append(succeeded, discussion)
+		// 無視したsystemコメントのみのdiscussionは移行数に含めない
+		if notes > 0 {
+			result.Created++
+		}
 	}
 
-	logger.Debug("Completed migration of comments", "count", processedCount, "mr_id", mr.IID)
-	return nil
+	// 失敗したdiscussionは再実行時に作成されるよう、timelineに含めない
+	if opts.MRSystemTimeline {
+		if err := createTimelineComments(ctx, githubClient, cfg, opts, mr, pr, succeeded); err != nil {
+			logger.FromContext(ctx).Warn("Failed to create MR timeline", "mr_id", mr.IID, "error", err)
+		}
+	}
+
+	if result.Filtered > 0 {
+		logger.FromContext(ctx).Info("Filtered comments", "filtered", result.Filtered, "mr_id", mr.IID)
+	}
+	logger.FromContext(ctx).Debug("Completed migration of comments", "created", result.Created, "notes", result.Notes, "failed", result.Failed, "mr_id", mr.IID)
+	return result
 }
 
 // createGitHubComments creates a GitHub comment from a GitLab note
-func createGitHubDiscussion(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, discussion *gitlablib.Discussion) error {
+// 移行したnoteの数を返す (無視したsystemコメントは含まない)
+func createGitHubDiscussion(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, discussion *gitlablib.Discussion, g *git.Git) (int, error) {
 	headNote := discussion.Notes[0]
 	tailNotes := discussion.Notes[1:]
+	// resolveはthread単位の状態のため、noteごとのフラグではなくthread全体から一度だけ判定し、先頭のコメントにのみ適用する
+	resolved := isDiscussionResolved(discussion)
 
 	if headNote.System {
 		// 以下のようなcommit hashを持つsystem commentの場合、そのcommitにPRへのリンクをコメントする
 		// この対応を行わないと、移行に際してcommitから参考となるPRが引けなくなるため。
 		// "mentioned in commit 21bff6b64c0ecaacb0cecf09b9f1c662f9e62b21"
-		if strings.Contains(headNote.Body, "mentioned in commit ") {
-			commitHash := strings.TrimPrefix(headNote.Body, "mentioned in commit ")
-			body := fmt.Sprintf("Related PR: [%s](%s)", pr.GetTitle(), pr.GetHTMLURL())
-			err := githubClient.CreateCommitComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, commitHash, body)
+		// commit commentはcommitの作者に通知されるため、通知を抑制する場合は作成しない
+		if m := mentionedCommitPattern.FindStringSubmatch(strings.TrimSpace(headNote.Body)); m != nil && !githubClient.QuietNotifications() {
+			project, commitHash := m[1], m[2]
+			// GitLabは短縮されたhashを利用することがあるが、GitHubのcommit comment APIは完全なSHAのみを受け付ける
+			// 他のプロジェクトのcommitはリポジトリに存在しないため、Issue Commentとする
+			var err error
+			if project == "" {
+				var fullHash string
+				if fullHash, err = g.ResolveCommit(commitHash); err == nil {
+					commitHash = fullHash
+				}
+			} else {
+				err = fmt.Errorf("commit %s belongs to another project %s", commitHash, project)
+			}
+			body := formatCommitMentionBody(cfg, mr, pr, headNote, project, commitHash)
+			if err == nil {
+				err = githubClient.CreateCommitComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, commitHash, body)
+			}
 			if err != nil {
+				logger.FromContext(ctx).Debug("Failed to comment on mentioned commit, creating an issue comment instead", "note", headNote.ID, "error", err)
 				// エラーが出た場合は、Issue Commentとする
-				_, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), body, headNote.Resolved)
+				comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), formatNoteMarker(int(headNote.ID))+"\n"+body, resolved)
 				if err != nil {
-					return err
+					return 0, err
 				}
-				return nil
+				recordNotes(ctx, opts, int(mr.IID), comment.GetHTMLURL(), int(headNote.ID))
+				return 1, nil
 			}
 		}
 
-		// ignore unused system comment
-		if strings.Contains(headNote.Body, "closed") || strings.Contains(headNote.Body, "reset approvals ") || strings.Contains(headNote.Body, "assigned to") || strings.Contains(headNote.Body, "Changed title") || strings.Contains(headNote.Body, "Assignee ") || strings.Contains(headNote.Body, "Status changed") || strings.Contains(headNote.Body, "mentioned in ") || strings.Contains(headNote.Body, "canceled the automatic merge") || strings.Contains(headNote.Body, "changed the description") || strings.Contains(headNote.Body, "enabled an automatic merge") || strings.Contains(headNote.Body, "Added ") || strings.Contains(headNote.Body, "added ") || strings.Contains(headNote.Body, "changed title from") || strings.Contains(headNote.Body, "marked the checklist item") || strings.Contains(headNote.Body, "approved this merge request") || strings.Contains(headNote.Body, "requested review") || strings.Contains(headNote.Body, "resolved all threads") || strings.Contains(headNote.Body, "mentioned in commit ") {
-			return nil
+		// ignore unused system comment (監査などですべて残す必要がある場合は移行する)
+		if !opts.IncludeSystemComments && isIgnoredSystemNote(headNote.Body) {
+			return 0, nil
 		}
 
-		body := fmt.Sprintf("【system】%s", headNote.Body)
-		_, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), body, headNote.Resolved)
+		body := formatNoteMarker(int(headNote.ID)) + "\n" + formatSystemNoteBody(opts.SystemCommentPrefix, headNote.Body)
+		comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), body, resolved)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		recordNotes(ctx, opts, int(mr.IID), comment.GetHTMLURL(), int(headNote.ID))
 
-		return nil
+		return 1, nil
 	}
 
 	var headCommentID int64
+	var headCommentURL string
 	var hasPRComment bool
 	if discussion.IndividualNote || headNote.Position == nil {
 		// 個別のコメントの場合は、そのままIssueCommentとする
-		comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), formatGitHubCommentBody(headNote), headNote.Resolved)
+		var comment *githublib.IssueComment
+		err := createAsAuthor(ctx, opts, githubClient, headNote, func(client *github.Client) error {
+			var err error
+			comment, err = client.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), formatDiffNoteLocation(headNote)+formatGitHubCommentBody(headNote, cfg.Limits, false), resolved)
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("failed to create head issue comment: %w, note=%v", err, headNote)
+			return 0, fmt.Errorf("failed to create head issue comment: %w, note=%v", err, headNote)
 		}
-		headCommentID = comment.GetID()
+		headCommentID, headCommentURL = comment.GetID(), comment.GetHTMLURL()
 	} else {
 		// Review Commentの場合は、対象のファイルや位置情報を持つ
 		// Discussionの先頭となるコメントを作成　(スレが無いコメントの場合、こちらのみ作成される)
-		headNoteStartLine, headNoteEndLine := resolveCommentLineRanges(headNote)
+		anchor := resolveDiffNoteAnchor(headNote)
+		// suggestionを含む場合は、suggestionが置き換える範囲にコメントし、GitHub上で適用できるようにする
+		suggestionApplicable := false
+		if hasSuggestion(headNote.Body) {
+			if start, last, ok := resolveSuggestionLineRanges(headNote); ok {
+				anchor.StartSide, anchor.StartLine = github.SideRight, start
+				anchor.Side, anchor.Line = github.SideRight, last
+				suggestionApplicable = true
+			}
+		}
+		commentPath := anchor.Path
+		commentSha := mr.DiffRefs.HeadSha
+		if opts.Subdirectory != "" {
+			// monorepoの場合、ファイルはsubdirectory以下にあり、PRのhead commitもGitLabのcommitとは異なる
+			commentPath = path.Join(strings.Trim(opts.Subdirectory, "/"), commentPath)
+			commentSha = pr.GetHead().GetSHA()
+		}
+		// --graphql-review-threads の場合は、threadとreplyをまとめて作成し、resolveの状態もGitHubのthreadとして移行する
+		// 何も作成されずに失敗した場合は、REST APIでの作成にfallbackする
+		if opts.GraphQLReviewThreads {
+			threadInput := &github.CreateReviewThreadInput{
+				PullRequestNodeID: pr.GetNodeID(),
+				Sha1:              commentSha,
+				Path:              commentPath,
+				StartSide:         anchor.StartSide,
+				StartLine:         anchor.StartLine,
+				Side:              anchor.Side,
+				LastLine:          anchor.Line,
+				Body:              formatGitHubCommentBody(headNote, cfg.Limits, suggestionApplicable),
+				Resolved:          resolved,
+			}
+			threadNotes := []*gitlablib.Note{headNote}
+			for _, note := range tailNotes {
+				if !note.System {
+					threadInput.Replies = append(threadInput.Replies, formatGitHubCommentBody(note, cfg.Limits, false))
+					threadNotes = append(threadNotes, note)
+				}
+			}
+			// review threadは1つのreviewとして作成されるため、replyも含めて先頭のコメントの作成者として作成する
+			var urls []string
+			err := createAsAuthor(ctx, opts, githubClient, headNote, func(client *github.Client) error {
+				var err error
+				urls, err = client.CreateReviewThread(ctx, threadInput)
+				return err
+			})
+			if err == nil {
+				for i, commentURL := range urls {
+					recordNotes(ctx, opts, int(mr.IID), commentURL, int(threadNotes[i].ID))
+				}
+				return len(urls), nil
+			}
+			if !errors.Is(err, github.ErrReviewThreadNotCreated) {
+				return 0, err
+			}
+			logger.FromContext(ctx).Warn("Failed to create review thread with GraphQL, falling back to REST", "note", headNote.ID, "error", err)
+		}
 		headCommentInput := &github.CreatePRCommentInput{
 			Owner:     cfg.GitHubOwner,
 			Repo:      cfg.GitHubRepo,
 			PrNumber:  pr.GetNumber(),
-			Body:      formatGitHubCommentBody(headNote),
-			Path:      headNote.Position.NewPath,
-			Sha1:      mr.DiffRefs.HeadSha,
-			Resolved:  headNote.Resolved,
-			StartLine: headNoteStartLine,
-			LastLine:  headNoteEndLine,
-		}
-		headComment, err := githubClient.CreatePRComment(ctx, headCommentInput)
+			Body:      formatGitHubCommentBody(headNote, cfg.Limits, suggestionApplicable),
+			Path:      commentPath,
+			Sha1:      commentSha,
+			Resolved:  resolved,
+			StartSide: anchor.StartSide,
+			StartLine: anchor.StartLine,
+			Side:      anchor.Side,
+			LastLine:  anchor.Line,
+		}
+		var headComment *githublib.PullRequestComment
+		err := createAsAuthor(ctx, opts, githubClient, headNote, func(client *github.Client) error {
+			var err error
+			headComment, err = client.CreatePRComment(ctx, headCommentInput)
+			return err
+		})
+		// PRのdiff hunk外のコメントなどはエラーになってしまうため、--out-of-hunk-strategyに従って扱う
 		if err != nil {
-			// PRのdiff hunk外のコメントなどはエラーになってしまうため、Issue Commentにfallbackさせる
-			comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), formatGitHubCommentBody(headNote), headNote.Resolved)
+			switch opts.OutOfHunkStrategy {
+			case OutOfHunkSkip:
+				// diff外以外のエラーはfallbackせず、MRの移行の失敗とする
+				if !github.IsOutsideDiffError(err) {
+					return 0, fmt.Errorf("failed to create head review comment: %w, note=%v", err, headNote)
+				}
+				logger.FromContext(ctx).Warn("Skipping review comment outside the PR diff", "note", headNote.ID, "error", err)
+				return 0, nil
+			case OutOfHunkNearestLine:
+				relocated, rerr := relocateToNearestLine(ctx, githubClient, cfg, headCommentInput)
+				if rerr != nil {
+					logger.FromContext(ctx).Debug("Failed to relocate review comment, creating an issue comment instead", "note", headNote.ID, "error", rerr)
+					break
+				}
+				// 移動先の行ではsuggestionを適用できないため、通常のコードブロックとする
+				relocated.Body = formatRelocatedNote(*headCommentInput.LastLine) + formatGitHubCommentBody(headNote, cfg.Limits, false)
+				err = createAsAuthor(ctx, opts, githubClient, headNote, func(client *github.Client) error {
+					var err error
+					headComment, err = client.CreatePRComment(ctx, relocated)
+					return err
+				})
+			}
+		}
+		if err != nil {
+			// Issue Commentにfallbackさせる
+			// どのコードに対するコメントだったか分かるよう、ファイルと行を先頭に付与する
+			var comment *githublib.IssueComment
+			err := createAsAuthor(ctx, opts, githubClient, headNote, func(client *github.Client) error {
+				var err error
+				comment, err = client.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), formatDiffNoteLocation(headNote)+formatGitHubCommentBody(headNote, cfg.Limits, false), resolved)
+				return err
+			})
 			if err != nil {
-				return fmt.Errorf("failed to create head issue comment: %w, note=%v", err, headNote)
+				return 0, fmt.Errorf("failed to create head issue comment: %w, note=%v", err, headNote)
 			}
-			headCommentID = comment.GetID()
+			headCommentID, headCommentURL = comment.GetID(), comment.GetHTMLURL()
 		} else {
-			headCommentID = headComment.GetID()
+			headCommentID, headCommentURL = headComment.GetID(), headComment.GetHTMLURL()
 			hasPRComment = true
 		}
 	}
+	recordNotes(ctx, opts, int(mr.IID), headCommentURL, int(headNote.ID))
 
-	var replyIssueComment = ""
+	// 先頭のコメントは作成済み
+	createdNotes := 1
+	var replyIssueBodies []string
 	for _, note := range tailNotes {
 		if note.System {
 			continue
@@ -458,61 +1049,215 @@ func createGitHubDiscussion(ctx context.Context, githubClient *github.Client, cf
 				Owner:     cfg.GitHubOwner,
 				Repo:      cfg.GitHubRepo,
 				PrNumber:  pr.GetNumber(),
-				Body:      formatGitHubCommentBody(note),
-				Resolved:  note.Resolved,
+				Body:      formatGitHubCommentBody(note, cfg.Limits, false),
 				CommentID: headCommentID, // reply先となるコメント
 			}
-			if err := githubClient.CreatePRCommentReply(ctx, replyInput); err != nil {
+			var reply *githublib.PullRequestComment
+			if err := createAsAuthor(ctx, opts, githubClient, note, func(client *github.Client) error {
+				var err error
+				reply, err = client.CreatePRCommentReply(ctx, replyInput)
 				return err
+			}); err != nil {
+				return createdNotes, err
 			}
+			recordNotes(ctx, opts, int(mr.IID), reply.GetHTMLURL(), int(note.ID))
+			createdNotes++
 		} else {
 			// そうでないなら、replyは出来ないため、集約してIssueCommentとする
-			replyIssueComment += formatGitHubCommentBody(note) + "\n\n----\n"
+			replyIssueBodies = append(replyIssueBodies, formatGitHubCommentBody(note, cfg.Limits, false)+replySeparator)
 		}
 	}
-	if !hasPRComment && replyIssueComment != "" {
-		commentText := utils.TruncateText(replyIssueComment, utils.MaxCommentLength)
-		_, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), commentText, true)
+	// 長いdiscussionでは切り詰めると大半のreplyが失われるため、上限を超える場合は複数のIssueCommentに分けて順に作成する
+	// 複数の作成者のreplyを集約するため、作成者のtokenは使わず移行用のclientで作成する (各replyには作成者が記載される)
+	for _, chunk := range chunkReplyBodies(replyIssueBodies, cfg.Limits.Comment) {
+		commentText := utils.TruncateTextWithOptions(strings.Join(chunk, ""), cfg.Limits.Comment, cfg.Limits.Truncate)
+		comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), commentText, false)
 		if err != nil {
-			return fmt.Errorf("failed to create tail issue comments: %w, note=%v", err, headNote)
+			return createdNotes, fmt.Errorf("failed to create tail issue comments: %w, note=%v", err, headNote)
 		}
+		recordNotes(ctx, opts, int(mr.IID), comment.GetHTMLURL(), parseNoteMarkers(commentText)...)
+		createdNotes += len(chunk)
 	}
-	return nil
+	return createdNotes, nil
 }
 
-func resolveCommentLineRanges(note *gitlablib.Note) (*int, *int) {
-	var numbers []int
-	if note.Position != nil && note.Position.LineRange != nil {
-		if note.Position.LineRange.StartRange != nil {
-			if note.Position.LineRange.StartRange.OldLine != 0 {
-				numbers = append(numbers, note.Position.LineRange.StartRange.OldLine)
-			}
-			if note.Position.LineRange.StartRange.NewLine != 0 {
-				numbers = append(numbers, note.Position.LineRange.StartRange.NewLine)
-			}
+// replySeparator separates the replies aggregated into an issue comment
+const replySeparator = "\n\n----\n"
+
+// chunkReplyBodies groups the reply bodies in order so that each group fits in a comment of the limit
+// 1つのreplyのみで上限を超える場合は、そのreplyだけのグループとする
+func chunkReplyBodies(bodies []string, limit int) [][]string {
+	var chunks [][]string
+	var chunk []string
+	size := 0
+	for _, body := range bodies {
+		length := utf8.RuneCountInString(body)
+		if len(chunk) > 0 && size+length > limit {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, body)
+		size += length
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// isDiscussionResolved checks if the GitLab thread is resolved
+// GitLabと同様に、resolve可能なnoteがすべてresolveされている場合のみresolve済みとする (resolve可能なnoteが無い場合はresolveされていない)
+func isDiscussionResolved(discussion *gitlablib.Discussion) bool {
+	resolvable := false
+	for _, note := range discussion.Notes {
+		if !note.Resolvable {
+			continue
 		}
-		if note.Position.LineRange.EndRange != nil {
-			if note.Position.LineRange.EndRange.OldLine != 0 {
-				numbers = append(numbers, note.Position.LineRange.EndRange.OldLine)
+		if !note.Resolved {
+			return false
+		}
+		resolvable = true
+	}
+	return resolvable
+}
+
+// formatDiffNoteLocation renders the file and line a diff note referred to, or an empty string for other notes
+func formatDiffNoteLocation(note *gitlablib.Note) string {
+	if note.Position == nil {
+		return ""
+	}
+	// 削除された行へのコメントはnew_pathやnew_lineを持たないため、old側を利用する
+	filePath, line := note.Position.NewPath, note.Position.NewLine
+	if line == 0 && note.Position.OldLine != 0 {
+		filePath, line = note.Position.OldPath, note.Position.OldLine
+	}
+	if filePath == "" {
+		filePath = note.Position.OldPath
+	}
+	if filePath == "" {
+		return ""
+	}
+	if line == 0 {
+		return fmt.Sprintf("On `%s`:\n\n", filePath)
+	}
+	return fmt.Sprintf("On `%s:%d`:\n\n", filePath, line)
+}
+
+// isAutoMerged checks if the merged merge request was merged by GitLab auto-merge ("merge when pipeline succeeds")
+// 最後に有効化されたauto-mergeがキャンセルされていない場合に、auto-mergeによりmergeされたと判断する
+func isAutoMerged(mr *gitlablib.MergeRequest, discussions []*gitlablib.Discussion) bool {
+	if mr.State != "merged" {
+		return false
+	}
+	autoMerge := mr.MergeWhenPipelineSucceeds
+	// discussionは作成順に並んでいる
+	for _, discussion := range discussions {
+		for _, note := range discussion.Notes {
+			if !note.System {
+				continue
 			}
-			if note.Position.LineRange.EndRange.NewLine != 0 {
-				numbers = append(numbers, note.Position.LineRange.EndRange.NewLine)
+			switch {
+			case strings.Contains(note.Body, "enabled an automatic merge"), strings.Contains(note.Body, "set to be merged automatically"):
+				autoMerge = true
+			case strings.Contains(note.Body, "canceled the automatic merge"), strings.Contains(note.Body, "aborted the automatic merge"):
+				autoMerge = false
 			}
 		}
 	}
-	sort.SliceStable(numbers, func(i, j int) bool {
-		return numbers[i] < numbers[j]
-	})
-	if len(numbers) > 0 {
-		from := numbers[0]
-		to := numbers[len(numbers)-1]
-		return &from, &to
+	return autoMerge
+}
+
+// isIgnoredSystemNote checks if the system note is not worth migrating
+func isIgnoredSystemNote(body string) bool {
+	return strings.Contains(body, "closed") ||
+		strings.Contains(body, "reset approvals ") ||
+		strings.Contains(body, "assigned to") ||
+		strings.Contains(body, "Changed title") ||
+		strings.Contains(body, "Assignee ") ||
+		strings.Contains(body, "Status changed") ||
+		strings.Contains(body, "mentioned in ") ||
+		strings.Contains(body, "canceled the automatic merge") ||
+		strings.Contains(body, "changed the description") ||
+		strings.Contains(body, "enabled an automatic merge") ||
+		strings.Contains(body, "Added ") ||
+		strings.Contains(body, "added ") ||
+		strings.Contains(body, "changed title from") ||
+		strings.Contains(body, "marked the checklist item") ||
+		strings.Contains(body, "approved this merge request") ||
+		strings.Contains(body, "requested review") ||
+		strings.Contains(body, "resolved all threads") ||
+		strings.Contains(body, "mentioned in commit ")
+}
+
+// mentionedCommitPattern matches a GitLab system note mentioning a commit, optionally of another project such as "mentioned in commit group/project@21bff6b6"
+var mentionedCommitPattern = regexp.MustCompile(`^mentioned in commit (?:(\S+)@)?([0-9a-f]{7,40})$`)
+
+// formatCommitMentionBody renders the comment linking a commit mentioned in the merge request to the migrated PR
+// 誰がいつcommitに言及したかと、GitLab上のcommitへのリンクを残す
+func formatCommitMentionBody(cfg config.GlobalConfig, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, note *gitlablib.Note, project, commitHash string) string {
+	projectURL, _, _ := strings.Cut(mr.WebURL, "/-/")
+	if project != "" {
+		projectURL = strings.TrimSuffix(cfg.GitLabURL, "/") + "/" + project
+	}
+	date := ""
+	if note.CreatedAt != nil {
+		date = " at " + note.CreatedAt.Format("2006-01-02 15:04:05 MST")
+	}
+	return fmt.Sprintf("Related PR: [%s](%s)\n\nMentioned in GitLab MR !%d by `%s`%s: %s/-/commit/%s",
+		pr.GetTitle(), pr.GetHTMLURL(), mr.IID, note.Author.Username, date, projectURL, commitHash)
+}
+
+// formatSystemNoteBody renders a GitLab system note body with the configured prefix
+func formatSystemNoteBody(prefix, body string) string {
+	if prefix == "" {
+		return body
+	}
+	return fmt.Sprintf("%s %s", prefix, body)
+}
+
+// diffNoteAnchor is the file, diff side and line range a GitHub review comment for a GitLab diff note is anchored to
+type diffNoteAnchor struct {
+	Path      string
+	StartSide string
+	StartLine *int
+	Side      string
+	Line      *int
+}
+
+// resolveDiffNoteAnchor returns where the GitHub review comment for the diff note is anchored
+// 削除された行(old_lineのみを持つ行)はLEFT、それ以外はRIGHTの行として扱い、renameされたファイルでもold側の行に正しくコメントできるようにする
+func resolveDiffNoteAnchor(note *gitlablib.Note) diffNoteAnchor {
+	// 追加・削除されたファイルでは片方のpathが空の場合がある
+	anchor := diffNoteAnchor{Path: note.Position.NewPath}
+	if anchor.Path == "" {
+		anchor.Path = note.Position.OldPath
+	}
+
+	lineRange := note.Position.LineRange
+	if lineRange != nil && lineRange.StartRange != nil && lineRange.EndRange != nil {
+		anchor.StartSide, anchor.StartLine = diffLineSide(int(lineRange.StartRange.OldLine), int(lineRange.StartRange.NewLine))
+		anchor.Side, anchor.Line = diffLineSide(int(lineRange.EndRange.OldLine), int(lineRange.EndRange.NewLine))
+	} else {
+		anchor.Side, anchor.Line = diffLineSide(int(note.Position.OldLine), int(note.Position.NewLine))
+		anchor.StartSide, anchor.StartLine = anchor.Side, anchor.Line
+	}
+	return anchor
+}
+
+// diffLineSide returns the GitHub diff side and line of a GitLab diff line, or a nil line if it has none
+func diffLineSide(oldLine, newLine int) (string, *int) {
+	if newLine != 0 {
+		return github.SideRight, &newLine
+	}
+	if oldLine != 0 {
+		return github.SideLeft, &oldLine
 	}
-	return nil, nil
+	return github.SideRight, nil
 }
 
-func formatGitHubCommentBody(note *gitlablib.Note) string {
-	commentText := utils.TruncateText(note.Body, utils.MaxCommentLength)
+// GitLabのsuggestionは、GitHubで適用可能な場合はsuggestionとして、そうでない場合はコードブロックとして残す
+func formatGitHubCommentBody(note *gitlablib.Note, limits utils.Limits, suggestionApplicable bool) string {
+	commentText := utils.TruncateTextWithOptions(formatSuggestions(note.Body, suggestionApplicable), limits.Comment, limits.Truncate)
 	commentDate := ""
 	if !note.CreatedAt.IsZero() {
 		commentDate = note.CreatedAt.Format("2006-01-02 15:04:05 MST")
@@ -522,7 +1267,9 @@ func formatGitHubCommentBody(note *gitlablib.Note) string {
 	if note.Author.Name != "" {
 		authorName = fmt.Sprintf("%s (%s)", note.Author.Name, note.Author.Username)
 	}
-	commentBody := fmt.Sprintf("%s\nby `%s` at `%s`",
+	// 長いコメントが切り詰められてもmarkerが残るよう、先頭に付与する
+	commentBody := fmt.Sprintf("%s\n%s\nby `%s` at `%s`",
+		formatNoteMarker(int(note.ID)),
 		commentText,
 		authorName,
 		commentDate,
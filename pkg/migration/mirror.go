@@ -2,75 +2,238 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	githublib "github.com/google/go-github/v70/github"
 	"github.com/krrrr38/gitlab-2-github/pkg/config"
 	"github.com/krrrr38/gitlab-2-github/pkg/git"
 	githubClient "github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
 	"net/url"
+	"strings"
 )
 
-// checkGitHubRepositoryExists checks if the GitHub repository exists
-func checkGitHubRepositoryExists(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client) (bool, error) {
+// migratedRepositoryDescriptionPrefix is the description prefix set on repositories created by this tool
+const migratedRepositoryDescriptionPrefix = "Migrated from GitLab:"
+
+// getGitHubRepository returns the GitHub repository, or nil if it does not exist
+func getGitHubRepository(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client) (*githublib.Repository, error) {
 	// リポジトリの存在確認
-	var exists bool
+	var repository *githublib.Repository
 	err := githubClient.RetryableOperation(ctx, func() error {
-		_, resp, err := gh.GetInner().Repositories.Get(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+		repo, resp, err := gh.GetInner().Repositories.Get(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
 		if err != nil {
 			if resp != nil && resp.StatusCode == 404 {
 				// 404の場合はリポジトリが存在しないだけなのでエラーとしない
-				exists = false
+				repository = nil
 				return nil
 			}
 			return err
 		}
-		exists = true
+		repository = repo
 		return nil
 	})
 
 	if err != nil {
-		return false, fmt.Errorf("failed to check GitHub repository: %w", err)
+		return nil, fmt.Errorf("failed to check GitHub repository: %w", err)
+	}
+
+	return repository, nil
+}
+
+// isGitHubRepositoryEmpty checks if the GitHub repository has no branches
+func isGitHubRepositoryEmpty(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client) (bool, error) {
+	var empty bool
+	err := githubClient.RetryableOperation(ctx, func() error {
+		branches, resp, err := gh.GetInner().Repositories.ListBranches(ctx, cfg.GitHubOwner, cfg.GitHubRepo, &githublib.BranchListOptions{
+			ListOptions: githublib.ListOptions{PerPage: 1},
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == 409 {
+				// 409の場合は空のリポジトリ
+				empty = true
+				return nil
+			}
+			return err
+		}
+		empty = len(branches) == 0
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list GitHub repository branches: %w", err)
 	}
+	return empty, nil
+}
 
-	return exists, nil
+// repositoryDescription returns the description of repositories created by this tool
+func repositoryDescription(cfg config.GlobalConfig) string {
+	if cfg.RepoDescription != "" {
+		return cfg.RepoDescription
+	}
+	return fmt.Sprintf("%s %s", migratedRepositoryDescriptionPrefix, cfg.GitLabProject)
+}
+
+// repositoryHomepage returns the homepage of repositories created by this tool, or nil to omit it
+func repositoryHomepage(cfg config.GlobalConfig) (*url.URL, error) {
+	switch cfg.RepoHomepage {
+	case config.RepoHomepageNone:
+		return nil, nil
+	case "":
+		// 未指定の場合はGitLabのプロジェクトURLとする
+		return url.Parse(gitlab.ProjectWebURL(cfg.GitLabURL, cfg.GitLabProject))
+	default:
+		return url.Parse(cfg.RepoHomepage)
+	}
+}
+
+// isCreatedByTool checks if the repository description was set by this tool
+func isCreatedByTool(cfg config.GlobalConfig, repository *githublib.Repository) bool {
+	description := repository.GetDescription()
+	return strings.HasPrefix(description, migratedRepositoryDescriptionPrefix) ||
+		(cfg.RepoDescription != "" && description == cfg.RepoDescription)
 }
 
 // createGitHubRepository creates a new GitHub repository
 func createGitHubRepository(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client) error {
-	description := fmt.Sprintf("Migrated from GitLab: %s", cfg.GitLabProject)
-	gitlabProjectUrl, _ := url.Parse(fmt.Sprintf("%s/%s", cfg.GitLabURL, cfg.GitLabProject))
-	err := githubClient.RetryableOperation(ctx, func() error {
-		return githubClient.CreateRepository(ctx, gh, cfg.GitHubOwner, cfg.GitHubRepo, description, gitlabProjectUrl)
+	homepage, err := repositoryHomepage(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid repository homepage: %w", err)
+	}
+	opts := &githubClient.CreateRepositoryOptions{
+		Description: repositoryDescription(cfg),
+		Homepage:    homepage,
+	}
+	err = githubClient.RetryableOperation(ctx, func() error {
+		return githubClient.CreateRepository(ctx, gh, cfg.GitHubOwner, cfg.GitHubRepo, opts)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create GitHub repository: %w", err)
 	}
 
-	logger.Info("Created new GitHub repository", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
+	logger.FromContext(ctx).Info("Created new GitHub repository", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
 	return nil
 }
 
 // MirrorRepository mirrors a GitLab repository to GitHub
-func MirrorRepository(g *git.Git, cfg config.GlobalConfig, gh *githubClient.Client) error {
-	ctx := context.Background()
+func MirrorRepository(ctx context.Context, g *git.Git, cfg config.GlobalConfig, gh *githubClient.Client, opts *MigrationOptions) error {
+	// IaCなどで事前に作成されたリポジトリの場合は、存在確認や作成を行わずにcloneする
+	if opts.AssumeRepoExists {
+		logger.FromContext(ctx).Info("Assuming the GitHub repository exists", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
+		err := g.Init(cfg.GitHubGitToken, cfg.GitLabToken)
+		var cloneErr *git.CloneError
+		if errors.As(err, &cloneErr) {
+			return fmt.Errorf("GitHub repository %s/%s must be created beforehand with --assume-repo-exists: %w", cfg.GitHubOwner, cfg.GitHubRepo, err)
+		}
+		if err != nil {
+			return err
+		}
+		mapRenamedDefaultBranch(ctx, g, opts)
+		updateDefaultBranch(ctx, cfg, gh, opts)
+		return nil
+	}
 
 	// GitHubリポジトリの存在確認
-	exists, err := checkGitHubRepositoryExists(ctx, cfg, gh)
+	repository, err := getGitHubRepository(ctx, cfg, gh)
 	if err != nil {
 		return err
 	}
 
-	// リポジトリが存在しない場合は作成
-	if !exists {
-		logger.Info("GitHub repository does not exist, creating...", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
+	if opts.AppendMode {
+		// 既存のリポジトリに対してMRなどのみを移行するため、リポジトリの作成や上書きは行わない
+		if repository == nil {
+			return fmt.Errorf("GitHub repository %s/%s does not exist, --append-mode requires an existing repository", cfg.GitHubOwner, cfg.GitHubRepo)
+		}
+		logger.FromContext(ctx).Info("Appending to the existing GitHub repository without mirroring", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
+	} else if repository == nil {
+		// リポジトリが存在しない場合は作成
+		logger.FromContext(ctx).Info("GitHub repository does not exist, creating...", "owner", cfg.GitHubOwner, "repo", cfg.GitHubRepo)
 		if err := createGitHubRepository(ctx, cfg, gh); err != nil {
 			return err
 		}
+	} else if !opts.ForceMirror && !isCreatedByTool(cfg, repository) {
+		// 本ツール以外で作成されたリポジトリに内容がある場合、force pushで上書きしてしまわないように中断する
+		empty, err := isGitHubRepositoryEmpty(ctx, cfg, gh)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("GitHub repository %s/%s already has content and was not created by this tool, use --force-mirror to overwrite it", cfg.GitHubOwner, cfg.GitHubRepo)
+		}
 	}
 
 	if err = g.Init(cfg.GitHubGitToken, cfg.GitLabToken); err != nil {
 		return err
 	}
+	mapRenamedDefaultBranch(ctx, g, opts)
+	updateDefaultBranch(ctx, cfg, gh, opts)
+
+	return nil
+}
+
+// mapRenamedDefaultBranch maps the GitLab default branch to the name given by --rename-default-branch
+// MRの対象ブランチが元のデフォルトブランチ (masterなど) の場合に、移行先の名前 (mainなど) で扱えるようにする
+func mapRenamedDefaultBranch(ctx context.Context, g *git.Git, opts *MigrationOptions) {
+	if opts.RenameDefaultBranch == "" || opts.AppendMode {
+		return
+	}
+	branch, err := g.GitLabDefaultBranch()
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to resolve the GitLab default branch, MR target branches are not renamed", "error", err)
+		return
+	}
+	if branch != opts.RenameDefaultBranch {
+		opts.BranchRenames = map[string]string{branch: opts.RenameDefaultBranch}
+		logger.FromContext(ctx).Info("Renaming the target branch of MRs", "from", branch, "to", opts.RenameDefaultBranch)
+	}
+}
 
+// updateDefaultBranch sets the branch pushed by --rename-default-branch as the default branch of the GitHub repository
+// GitLabにブランチが無い場合などはpushされていないため、失敗しても警告に留める
+func updateDefaultBranch(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client, opts *MigrationOptions) {
+	if opts.RenameDefaultBranch == "" || opts.AppendMode {
+		return
+	}
+	if err := gh.SetDefaultBranch(ctx, cfg.GitHubOwner, cfg.GitHubRepo, opts.RenameDefaultBranch); err != nil {
+		logger.FromContext(ctx).Warn("Failed to set the default branch of the GitHub repository", "branch", opts.RenameDefaultBranch, "error", err)
+		return
+	}
+	logger.FromContext(ctx).Info("Set the default branch of the GitHub repository", "branch", opts.RenameDefaultBranch)
+}
+
+// WarnMissingMappedTeams warns about teams of the team map that do not exist in the GitHub organization
+// 存在しないteamへのメンションは通知されないだけのため、移行は止めない
+func WarnMissingMappedTeams(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client, opts *MigrationOptions) {
+	var missing []string
+	for _, team := range opts.TeamMap.Teams() {
+		exists, err := gh.TeamExists(ctx, cfg.GitHubOwner, team)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to check teams of the team map", "error", err)
+			return
+		}
+		if !exists {
+			missing = append(missing, team)
+		}
+	}
+	if len(missing) > 0 {
+		logger.FromContext(ctx).Warn("Teams of the team map do not exist in the GitHub organization", "org", cfg.GitHubOwner, "teams", strings.Join(missing, ","))
+	}
+}
+
+// ValidateTeamReviewers checks that all team reviewers exist in the GitHub organization
+func ValidateTeamReviewers(ctx context.Context, cfg config.GlobalConfig, gh *githubClient.Client, opts *MigrationOptions) error {
+	var missing []string
+	for _, team := range opts.TeamReviewers {
+		exists, err := gh.TeamExists(ctx, cfg.GitHubOwner, team)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			missing = append(missing, team)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("teams %s do not exist in GitHub organization %s", strings.Join(missing, ", "), cfg.GitHubOwner)
+	}
 	return nil
 }
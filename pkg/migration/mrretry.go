@@ -0,0 +1,94 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// mrRetryDelay is the wait before retrying the migration of a merge request, doubled on each retry
+const mrRetryDelay = 10 * time.Second
+
+// processMergeRequestWithRetry migrates the merge request, retrying the whole migration up to --mr-attempts times on transient failures
+// 再試行の前に途中まで作成されたPRを片付け、最初からやり直す (ローカルのブランチは作り直し、GitHubのブランチはforce pushで上書きする)
+// BeforeMRとAfterMRは試行ごとに呼ばれる
+func processMergeRequestWithRetry(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, g *git.Git, entry *MergeRequestReport) error {
+	attempts := opts.MRAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := mrRetryDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = processMergeRequest(ctx, source, githubClient, cfg, opts, mr, g, entry); err == nil {
+			return nil
+		}
+		if attempt == attempts || !isTransientMRError(err) {
+			break
+		}
+		logger.FromContext(ctx).Warn("Failed to migrate MR, retrying", "id", mr.IID, "attempt", attempt, "delay", delay, "error", err)
+		if cerr := closePartialPullRequests(ctx, githubClient, cfg, opts, mr); cerr != nil {
+			// 片付けられない場合に再試行すると、同じブランチのPRが重複して作成できずに失敗するため打ち切る
+			return fmt.Errorf("%w (failed to clean up before retrying: %v)", err, cerr)
+		}
+		entry.PRNumber, entry.PRURL, entry.Comments, entry.CommentsSeconds = 0, "", CommentsResult{}, 0
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+// isTransientMRError checks if the migration of a merge request may succeed when retried
+// GitHubやGitLabのサーバーエラー、通信エラー、gitのpushの失敗のみを再試行の対象とする
+// バリデーションや権限のエラーは再試行しても結果が変わらず、試行ごとにPRのcloseが増えるだけのため再試行しない
+func isTransientMRError(err error) bool {
+	// 中断やGitHubの停止は、それ以降のMRも含めて打ち切る
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, github.ErrGitHubUnavailable) {
+		return false
+	}
+	var pushErr *git.PushError
+	if errors.As(err, &pushErr) {
+		return true
+	}
+	var gitlabErr *gitlablib.ErrorResponse
+	if errors.As(err, &gitlabErr) && gitlabErr.Response != nil {
+		return gitlabErr.Response.StatusCode >= http.StatusInternalServerError
+	}
+	return github.IsUnavailableError(err)
+}
+
+// closePartialPullRequests closes the open PRs created from the source branch of the merge request by a failed attempt
+// 移行済みのPRと区別できるよう、起動時の片付けと同様に失敗時のタグを付与してからcloseする
+func closePartialPullRequests(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest) error {
+	openedPRs, err := githubClient.GetOpenedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return fmt.Errorf("failed to get opened PRs: %w", err)
+	}
+	sourceBranch := mrBranchName(opts, int(mr.IID), "source")
+	for _, pr := range openedPRs {
+		if pr.GetHead().GetRef() != sourceBranch {
+			continue
+		}
+		logger.FromContext(ctx).Info("Closing PR of the failed attempt", "id", mr.IID, "pr", pr.GetNumber())
+		newTitle := fmt.Sprintf("%s %s", opts.FailedTitleTag, pr.GetTitle())
+		if err := githubClient.UpdatePullRequestTitle(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), newTitle); err != nil {
+			return err
+		}
+		if err := githubClient.ClosePullRequest(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestIsTransientMRError(t *testing.T) {
+	githubError := func(status int) error {
+		return fmt.Errorf("failed to create GitHub PR: %w", &githublib.ErrorResponse{Response: &http.Response{StatusCode: status}, Message: http.StatusText(status)})
+	}
+	gitlabError := func(status int) error {
+		return fmt.Errorf("failed to get discussions: %w", &gitlablib.ErrorResponse{Response: &http.Response{StatusCode: status}, Message: http.StatusText(status)})
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"GitHub server error", githubError(http.StatusBadGateway), true},
+		{"GitHub network failure", fmt.Errorf("failed to close PR: %w", &url.Error{Op: "Patch", URL: "https://api.github.com", Err: errors.New("connection reset")}), true},
+		{"GitHub GraphQL server error", errors.New(`failed to create review thread: non-200 OK status code: 502 Bad Gateway body: ""`), true},
+		{"GitLab server error", gitlabError(http.StatusServiceUnavailable), true},
+		{"git push failure", fmt.Errorf("failed to push branches: %w", &git.PushError{Err: errors.New("remote: Internal Server Error")}), true},
+		{"GitHub validation error", githubError(http.StatusUnprocessableEntity), false},
+		{"GitHub permission error", githubError(http.StatusForbidden), false},
+		{"GitHub not found", githubError(http.StatusNotFound), false},
+		{"GitLab not found", &gitlab.NotFoundError{Err: gitlabError(http.StatusNotFound)}, false},
+		{"GitLab forbidden", gitlabError(http.StatusForbidden), false},
+		{"no diff", &github.NoDiffError{Head: "source", Base: "target"}, false},
+		{"skipped by a hook", fmt.Errorf("before MR: %w", ErrSkip), false},
+		{"GitHub unavailable", fmt.Errorf("%w: 10 consecutive operations failed after retries", github.ErrGitHubUnavailable), false},
+		{"canceled", fmt.Errorf("failed to push branches: %w", context.Canceled), false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"unknown error", errors.New("failed to create branch"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientMRError(tt.err); got != tt.want {
+				t.Errorf("isTransientMRError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+)
+
+// noteMapHeader is the header of the note map CSV file
+var noteMapHeader = []string{"gitlab_mr_iid", "gitlab_note_id", "github_comment_url"}
+
+// NoteMap writes the GitHub comment URL of each migrated GitLab note to a CSV file
+// 移行が中断されても作成済みのコメントが失われないよう、1行ずつ追記する
+type NoteMap struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+// OpenNoteMap opens the note map file for appending, writing the header if the file is new
+func OpenNoteMap(path string) (*NoteMap, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open note map: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat note map: %w", err)
+	}
+	m := &NoteMap{f: f, w: csv.NewWriter(f)}
+	if info.Size() == 0 {
+		if err := m.write(noteMapHeader); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Record appends the GitHub comment URL of the GitLab note
+func (m *NoteMap) Record(mrIID, noteID int, url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.write([]string{strconv.Itoa(mrIID), strconv.Itoa(noteID), url})
+}
+
+// write writes the record and flushes it to the file
+func (m *NoteMap) write(record []string) error {
+	if err := m.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write note map: %w", err)
+	}
+	m.w.Flush()
+	if err := m.w.Error(); err != nil {
+		return fmt.Errorf("failed to write note map: %w", err)
+	}
+	return nil
+}
+
+// Close closes the note map file
+func (m *NoteMap) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.f.Close()
+}
+
+// recordNotes records the GitHub comment created from the GitLab notes to the note map
+// note mapの書き込みに失敗しても移行は続行する
+func recordNotes(ctx context.Context, opts *MigrationOptions, mrIID int, url string, noteIDs ...int) {
+	if opts.NoteMap == nil || url == "" {
+		return
+	}
+	for _, noteID := range noteIDs {
+		if err := opts.NoteMap.Record(mrIID, noteID, url); err != nil {
+			logger.FromContext(ctx).Warn("Failed to record migrated note", "note", noteID, "url", url, "error", err)
+		}
+	}
+}
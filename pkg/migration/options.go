@@ -1,11 +1,143 @@
 package migration
 
+import (
+	"slices"
+	"time"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/labelmap"
+	"github.com/krrrr38/gitlab-2-github/pkg/teammap"
+	"github.com/krrrr38/gitlab-2-github/pkg/usermap"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// DefaultMRStates are the GitLab merge request states migrated when --mr-states is not given
+var DefaultMRStates = []string{"closed", "merged"}
+
+// DefaultCloseStates are the GitLab merge request states whose PRs are closed when --close-states is not given
+var DefaultCloseStates = []string{"closed", "merged"}
+
 // MigrationOptions はマイグレーションのオプション設定を含む構造体
 type MigrationOptions struct {
 	// 特定のMR IDから再開する場合に指定
 	ContinueFromID int
 	// 特定のMR IDのみを対象とする場合に指定
 	FilterMergeReqIDs []int
+	// 移行するMRの状態 (空の場合はDefaultMRStates)
+	MRStates []string
+	// 移行したPRをcloseするMRの状態 (空の場合はDefaultCloseStates)
+	CloseStates []string
 	// 1つのMRに対するディスカッションの移行数の上限
 	MaxDiscussions int
+	// 上限を超えた場合に古いものと新しいもののどちらを残すか (oldest, newest)
+	DiscussionOrder string
+	// 1つのMRのdiscussionを並行して作成する数
+	CommentConcurrency int
+	// snippetの移行方法 (gist, repo, none)
+	Snippets string
+	// 本ツール以外で作成された内容のあるリポジトリへのミラーリングを許可する
+	ForceMirror bool
+	// ミラーリングを行わず、既存のリポジトリにMRなどのみを移行する
+	AppendMode bool
+	// GitLabのデフォルトブランチをこの名前でpushし、GitHubリポジトリのデフォルトブランチとする (空の場合は変更しない)
+	RenameDefaultBranch string
+	// GitLabのブランチ名から移行先のブランチ名への対応 (--rename-default-branchの場合にミラーリング時に設定される)
+	BranchRenames map[string]string
+	// GitHubリポジトリの存在確認と作成を行わず、事前に作成されたリポジトリを利用する
+	AssumeRepoExists bool
+	// 移行するsystemコメントに付与するprefix (空の場合は付与しない)
+	SystemCommentPrefix string
+	// 移行したPRにsummaryコメントを作成してpinする
+	SummaryComment bool
+	// PR作成前にブランチがGitHubから参照できるか確認する回数 (0の場合は確認しない)
+	RefPollAttempts int
+	// ブランチの確認間隔
+	RefPollInterval time.Duration
+	// GitLabプロジェクトを配置するGitHubリポジトリ内のsubdirectory (空の場合はリポジトリのルート)
+	Subdirectory string
+	// closedのMRから作成したPRのタイトルに付与するタグ (空の場合は付与しない)
+	ClosedTitleTag string
+	// 移行に失敗してcloseしたPRのタイトルに付与するタグ
+	FailedTitleTag string
+	// 失敗したMR (debugログの場合はすべてのMR) の取得内容とブランチを書き出すディレクトリ (空の場合は書き出さない)
+	DebugDumpDir string
+	// 一時的な失敗の場合に、1つのMRの移行を最初からやり直す回数 (最初の実行を含む)
+	MRAttempts int
+	// MRごとの移行状態を保存するstore (nilの場合は保存しない)
+	State *StateStore
+	// 移行したnoteとGitHubのコメントのURLの対応を書き出すfile (nilの場合は書き出さない)
+	NoteMap *NoteMap
+	// 保存された移行状態から、成功済みのMRをスキップして再開する
+	Resume bool
+	// OpenのままのPRにreviewerとしてリクエストするteamのslug
+	TeamReviewers []string
+	// 無視しているsystemコメントも含め、すべてのsystemコメントを移行する
+	IncludeSystemComments bool
+	// 移行しないsystemコメントを、MRごとに折りたたんだtimelineのコメントとして残す
+	MRSystemTimeline bool
+	// GitLabの承認設定をGitHubのデフォルトブランチのbranch protectionとして移行する
+	MigrateApprovalRules bool
+	// GitLabのCODEOWNERSまたは承認ルールから変換したCODEOWNERSをcommitする
+	MigrateCodeowners bool
+	// diffへのdiscussionをGraphQL APIでreview threadとして作成し、resolveの状態も移行する
+	GraphQLReviewThreads bool
+	// PRのdiff外の行へのreviewコメントの扱い (issue-comment, nearest-line, skip)
+	OutOfHunkStrategy string
+	// diffの無いMRのブランチに作成するcommitのメッセージ (空の場合はDefaultNoDiffCommitMessage)
+	NoDiffCommitMessage string
+	// diffの無いMRのブランチに、diffが無かったことを説明するファイルをcommitする
+	NoDiffNote bool
+	// MRのhead pipelineの状態をPRのhead commitのcommit statusとして作成する
+	PipelineStatus bool
+	// pipelineの状態を作成するcommit statusのcontext (空の場合はDefaultPipelineStatusContext)
+	PipelineStatusContext string
+	// mergedのMRから作成したPRをcloseせずに、実際にmergeする
+	ReallyMerge bool
+	// 移行したPRをcloseやmergeせずにOpenのまま残す
+	LeavePRsOpen bool
+	// GitLabのquick actionの扱い (keep, strip, translate)
+	QuickActions string
+	// 説明文やコメントに適用するmarkdownの変換
+	MarkdownFixes []*MarkdownFix
+	// この日時より前に作成されたnoteは移行しない (ゼロ値の場合はすべて移行する)
+	CommentSince time.Time
+	// 移行しないnoteの条件
+	CommentFilters []*CommentFilter
+	// GitLabのユーザー名からGitHubのユーザー名へのマッピング
+	UserMap usermap.UserMap
+	// GitLabのラベルからGitHubのラベルへのマッピング
+	LabelMap labelmap.LabelMap
+	// GitLabのgroupからGitHubのteamへのマッピング
+	TeamMap teammap.TeamMap
+	// GitHubのloginごとの、そのユーザーとして認証されたclient (コメントを移行用のユーザーではなく作者として作成する)
+	AuthorClients map[string]*github.Client
+	// MRの移行を始める前に呼ばれるcallback (ErrSkipを返すとそのMRをスキップし、それ以外のエラーでは移行失敗とする)
+	// --mr-attemptsで再試行する場合は、試行ごとに呼ばれる
+	BeforeMR BeforeMRFunc
+	// MRの移行が終わった後に、作成したPRと移行のエラーを渡して呼ばれるcallback (試行ごとに呼ばれる)
+	AfterMR AfterMRFunc
+}
+
+// targetBranchName returns the name on GitHub of the branch the merge request targeted on GitLab
+func (opts *MigrationOptions) targetBranchName(mr *gitlablib.MergeRequest) string {
+	if renamed, ok := opts.BranchRenames[mr.TargetBranch]; ok {
+		return renamed
+	}
+	return mr.TargetBranch
+}
+
+// migratesState checks if merge requests in the GitLab state are migrated
+func (opts *MigrationOptions) migratesState(state string) bool {
+	if len(opts.MRStates) == 0 {
+		return slices.Contains(DefaultMRStates, state)
+	}
+	return slices.Contains(opts.MRStates, state)
+}
+
+// closesState checks if PRs migrated from merge requests in the GitLab state are closed
+func (opts *MigrationOptions) closesState(state string) bool {
+	if len(opts.CloseStates) == 0 {
+		return slices.Contains(DefaultCloseStates, state)
+	}
+	return slices.Contains(opts.CloseStates, state)
 }
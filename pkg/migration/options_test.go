@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"testing"
+
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestTargetBranchName(t *testing.T) {
+	tests := []struct {
+		name         string
+		renames      map[string]string
+		targetBranch string
+		want         string
+	}{
+		{"master mapped to main", map[string]string{"master": "main"}, "master", "main"},
+		{"other branch kept", map[string]string{"master": "main"}, "release/1.0", "release/1.0"},
+		{"no renames", nil, "master", "master"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &MigrationOptions{BranchRenames: tt.renames}
+			mr := &gitlablib.MergeRequest{BasicMergeRequest: gitlablib.BasicMergeRequest{TargetBranch: tt.targetBranch}}
+			if got := opts.targetBranchName(mr); got != tt.want {
+				t.Errorf("targetBranchName(%q) = %q, want %q", tt.targetBranch, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+)
+
+const (
+	// OutOfHunkIssueComment creates review comments outside the PR diff as issue comments prefixed with the file and line
+	OutOfHunkIssueComment = "issue-comment"
+	// OutOfHunkNearestLine moves review comments outside the PR diff to the closest line of the diff
+	OutOfHunkNearestLine = "nearest-line"
+	// OutOfHunkSkip drops review comments outside the PR diff
+	OutOfHunkSkip = "skip"
+)
+
+// hunkHeaderPattern matches a unified diff hunk header such as `@@ -10,7 +10,8 @@`
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// diffLines returns the lines of each diff side of the patch a review comment can be anchored to
+// 変更されていない行は両方の側でコメントできる
+func diffLines(patch string) map[string][]int {
+	lines := map[string][]int{}
+	var oldLine, newLine int
+	inHunk := false
+	for _, line := range strings.Split(patch, "\n") {
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[2])
+			inHunk = true
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+		switch line[0] {
+		case '+':
+			lines[github.SideRight] = append(lines[github.SideRight], newLine)
+			newLine++
+		case '-':
+			lines[github.SideLeft] = append(lines[github.SideLeft], oldLine)
+			oldLine++
+		case ' ':
+			lines[github.SideRight] = append(lines[github.SideRight], newLine)
+			lines[github.SideLeft] = append(lines[github.SideLeft], oldLine)
+			newLine++
+			oldLine++
+		}
+	}
+	return lines
+}
+
+// nearestLine returns the line closest to the target, preferring the earlier line on a tie
+func nearestLine(lines []int, target int) (int, bool) {
+	nearest, found := 0, false
+	for _, line := range lines {
+		if !found || abs(line-target) < abs(nearest-target) || (abs(line-target) == abs(nearest-target) && line < nearest) {
+			nearest, found = line, true
+		}
+	}
+	return nearest, found
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// relocateToNearestLine returns a copy of the review comment input moved to the line of the PR diff closest to its last line
+func relocateToNearestLine(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, input *github.CreatePRCommentInput) (*github.CreatePRCommentInput, error) {
+	if input.LastLine == nil {
+		return nil, fmt.Errorf("review comment on %s has no line to relocate", input.Path)
+	}
+	files, err := githubClient.ListPRFiles(ctx, cfg.GitHubOwner, cfg.GitHubRepo, input.PrNumber)
+	if err != nil {
+		return nil, err
+	}
+	var file *githublib.CommitFile
+	for _, f := range files {
+		if f.GetFilename() == input.Path {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file %s is not changed in the PR", input.Path)
+	}
+	// 大きすぎるdiffなどはpatchが返されないため、コメントできる行が分からない
+	line, ok := nearestLine(diffLines(file.GetPatch())[input.Side], *input.LastLine)
+	if !ok {
+		return nil, fmt.Errorf("file %s has no %s line in the PR diff", input.Path, input.Side)
+	}
+
+	relocated := *input
+	relocated.StartSide, relocated.StartLine = "", nil
+	relocated.LastLine = &line
+	return &relocated, nil
+}
+
+// formatRelocatedNote renders the notice of a review comment moved from a line outside the PR diff
+func formatRelocatedNote(line int) string {
+	return fmt.Sprintf("> Relocated from line %d, which is outside the diff of this PR.\n\n", line)
+}
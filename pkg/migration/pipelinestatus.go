@@ -0,0 +1,62 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// DefaultPipelineStatusContext is the commit status context used when --pipeline-status-context is not given
+const DefaultPipelineStatusContext = "gitlab/pipeline"
+
+// pipelineStatusState returns the GitHub commit status state of a GitLab pipeline status
+// GitHubには中断やスキップを表すstateが無いため、errorとする
+func pipelineStatusState(status string) string {
+	switch status {
+	case "success":
+		return github.StatusStateSuccess
+	case "failed":
+		return github.StatusStateFailure
+	case "canceled", "canceling", "skipped":
+		return github.StatusStateError
+	default:
+		return github.StatusStatePending
+	}
+}
+
+// migratePipelineStatus creates a commit status of the head pipeline of the merge request on the PR head commit
+// statusのリンクからGitLabのpipelineのログを確認できるようにする
+func migratePipelineStatus(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest) error {
+	if !opts.PipelineStatus || mr.HeadPipeline == nil {
+		return nil
+	}
+	sha := pr.GetHead().GetSHA()
+	exists, err := githubClient.CommitExists(ctx, cfg.GitHubOwner, cfg.GitHubRepo, sha)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logger.FromContext(ctx).Warn("Skipping pipeline status of a PR head commit missing on GitHub", "mr", mr.IID, "sha", sha)
+		return nil
+	}
+
+	statusContext := opts.PipelineStatusContext
+	if statusContext == "" {
+		statusContext = DefaultPipelineStatusContext
+	}
+	pipeline := mr.HeadPipeline
+	return githubClient.CreateCommitStatus(ctx, &github.CreateCommitStatusInput{
+		Owner:       cfg.GitHubOwner,
+		Repo:        cfg.GitHubRepo,
+		SHA:         sha,
+		State:       pipelineStatusState(pipeline.Status),
+		Context:     statusContext,
+		TargetURL:   pipeline.WebURL,
+		Description: fmt.Sprintf("GitLab pipeline #%d %s", pipeline.ID, pipeline.Status),
+	})
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// QuickActionsKeep leaves GitLab quick actions in bodies as they are
+	QuickActionsKeep = "keep"
+	// QuickActionsStrip removes GitLab quick action lines from bodies
+	QuickActionsStrip = "strip"
+	// QuickActionsTranslate removes GitLab quick action lines and applies the labels of `/label` to the PR
+	QuickActionsTranslate = "translate"
+)
+
+// quickActionPattern matches a line consisting of a GitLab quick action such as `/assign @user`
+var quickActionPattern = regexp.MustCompile(`^\s*/([a-z_]+)(?:\s+(.*))?$`)
+
+// quickActionLabelPattern matches a label reference of a quick action such as `~bug` or `~"needs review"`
+var quickActionLabelPattern = regexp.MustCompile(`~"([^"]+)"|~([^\s~"]+)`)
+
+// gitLabQuickActions are the GitLab quick actions recognized in merge request descriptions and comments
+// https://docs.gitlab.com/ee/user/project/quick_actions.html
+var gitLabQuickActions = map[string]bool{
+	"approve": true, "unapprove": true, "assign": true, "unassign": true,
+	"assign_reviewer": true, "reviewer": true, "request_review": true, "unassign_reviewer": true, "remove_reviewer": true,
+	"label": true, "unlabel": true, "remove_label": true, "relabel": true,
+	"close": true, "reopen": true, "merge": true, "rebase": true, "draft": true, "ready": true, "wip": true,
+	"milestone": true, "remove_milestone": true, "estimate": true, "remove_estimate": true,
+	"spend": true, "remove_time_spent": true, "due": true, "remove_due_date": true, "weight": true, "clear_weight": true,
+	"title": true, "target_branch": true, "todo": true, "done": true, "subscribe": true, "unsubscribe": true,
+	"award": true, "react": true, "lock": true, "unlock": true, "copy_metadata": true, "cc": true,
+	"submit_review": true, "confidential": true, "duplicate": true, "relate": true, "unrelate": true,
+	"epic": true, "remove_epic": true, "iteration": true, "remove_iteration": true,
+}
+
+// applyQuickActions strips the GitLab quick action lines of the body and returns the labels added by `/label`
+// keepの場合や、コードブロック内の行はそのまま残す
+func applyQuickActions(mode, body string) (string, []string) {
+	if mode == "" || mode == QuickActionsKeep {
+		return body, nil
+	}
+
+	var lines []string
+	var labels []string
+	inCodeFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeFence = !inCodeFence
+		}
+		m := quickActionPattern.FindStringSubmatch(line)
+		if inCodeFence || m == nil || !gitLabQuickActions[m[1]] {
+			lines = append(lines, line)
+			continue
+		}
+		if mode == QuickActionsTranslate && m[1] == "label" {
+			for _, lm := range quickActionLabelPattern.FindAllStringSubmatch(m[2], -1) {
+				labels = append(labels, lm[1]+lm[2])
+			}
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n"), labels
+}
@@ -0,0 +1,206 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// MergeRequestStatusMigrated indicates the merge request was migrated to a PR
+	MergeRequestStatusMigrated = "migrated"
+	// MergeRequestStatusFailed indicates the migration of the merge request failed
+	MergeRequestStatusFailed = "failed"
+	// MergeRequestStatusSkipped indicates the merge request was deleted on GitLab during the migration or skipped by a hook
+	MergeRequestStatusSkipped = "skipped"
+)
+
+const (
+	// PhaseMirror is the phase mirroring the repository
+	PhaseMirror = "mirror"
+	// PhaseSnippets is the phase migrating snippets
+	PhaseSnippets = "snippets"
+	// PhaseMergeRequests is the phase migrating merge requests including their comments
+	PhaseMergeRequests = "merge_requests"
+	// PhaseComments is the total time spent migrating comments of all merge requests
+	PhaseComments = "comments"
+)
+
+// Report holds the result of a migration run
+type Report struct {
+	mu            sync.Mutex
+	MergeRequests []*MergeRequestReport `json:"merge_requests"`
+	PhaseSeconds  map[string]float64    `json:"phase_seconds"` // フェーズごとの所要時間(秒)
+	// 移行済み、移行対象外の状態、IDの指定、hookによるスキップなどにより移行しなかったMRの数
+	SkippedMergeRequests int `json:"skipped_merge_requests"`
+}
+
+// MergeRequestReport holds the result of a single merge request migration
+type MergeRequestReport struct {
+	IID      int            `json:"iid"`
+	Title    string         `json:"title"`
+	Author   string         `json:"author,omitempty"` // MR作成者のGitLabのユーザー名
+	State    string         `json:"state,omitempty"`  // GitLab上のMRの状態
+	Status   string         `json:"status"`
+	PRNumber int            `json:"pr_number,omitempty"`
+	PRURL    string         `json:"pr_url,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Comments CommentsResult `json:"comments"`
+	// コメントの移行にかかった時間(秒)
+	CommentsSeconds float64 `json:"comments_seconds,omitempty"`
+}
+
+// CommentsResult holds the result of migrating the discussions of a merge request
+type CommentsResult struct {
+	Created             int      `json:"created"` // 移行したdiscussionの数
+	Notes               int      `json:"notes"`   // 移行したnoteの数 (replyを含む)
+	Failed              int      `json:"failed"`
+	Filtered            int      `json:"filtered"` // --comment-filterや--comment-sinceにより除外したnoteの数
+	FailedDiscussionIDs []string `json:"failed_discussion_ids,omitempty"`
+	QuickActionLabels   []string `json:"-"` // --quick-actions=translateの場合に、コメントの "/label" から付与するラベル
+}
+
+// NewReport creates an empty report
+func NewReport() *Report {
+	return &Report{MergeRequests: []*MergeRequestReport{}, PhaseSeconds: map[string]float64{}}
+}
+
+// AddMergeRequest appends a new merge request entry to the report
+func (r *Report) AddMergeRequest(mr *gitlablib.BasicMergeRequest) *MergeRequestReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &MergeRequestReport{IID: int(mr.IID), Title: mr.Title, State: mr.State}
+	if mr.Author != nil {
+		entry.Author = mr.Author.Username
+	}
+	r.MergeRequests = append(r.MergeRequests, entry)
+	return entry
+}
+
+// AddSkippedMergeRequest counts a merge request not attempted in the run
+func (r *Report) AddSkippedMergeRequest() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.SkippedMergeRequests++
+}
+
+// AddPhaseDuration adds the wall-clock duration to the phase
+func (r *Report) AddPhaseDuration(phase string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.PhaseSeconds[phase] += d.Seconds()
+}
+
+// PhaseLogFields returns the phase durations as key value pairs for logging
+func (r *Report) PhaseLogFields() []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	phases := make([]string, 0, len(r.PhaseSeconds))
+	for phase := range r.PhaseSeconds {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	fields := make([]interface{}, 0, len(phases)*2)
+	for _, phase := range phases {
+		d := time.Duration(r.PhaseSeconds[phase] * float64(time.Second))
+		fields = append(fields, phase+"_duration", d.Round(time.Millisecond).String())
+	}
+	return fields
+}
+
+// Counts returns the number of migrated and failed merge requests
+func (r *Report) Counts() (migrated, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range r.MergeRequests {
+		switch entry.Status {
+		case MergeRequestStatusMigrated:
+			migrated++
+		case MergeRequestStatusFailed:
+			failed++
+		}
+	}
+	return migrated, failed
+}
+
+// Incomplete reports whether any merge request or discussion failed to migrate
+func (r *Report) Incomplete() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range r.MergeRequests {
+		if entry.Status == MergeRequestStatusSkipped {
+			continue
+		}
+		if entry.Status != MergeRequestStatusMigrated || entry.Comments.Failed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteFile writes the report as JSON
+func (r *Report) WriteFile(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := writeFileAtomic(path, b); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// csvReportHeader is the header of the CSV report
+var csvReportHeader = []string{"gitlab_mr_iid", "title", "author", "state", "github_pr_number", "github_pr_url", "status", "error"}
+
+// WriteCSV writes a row per merge request as CSV, or as TSV if the path ends with ".tsv"
+// 表計算ソフトで確認できるよう、MRとPRの対応のみを書き出す
+func (r *Report) WriteCSV(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		w.Comma = '\t'
+	}
+	if err := w.Write(csvReportHeader); err != nil {
+		return fmt.Errorf("failed to write csv report: %w", err)
+	}
+	for _, entry := range r.MergeRequests {
+		prNumber := ""
+		if entry.PRNumber > 0 {
+			prNumber = strconv.Itoa(entry.PRNumber)
+		}
+		record := []string{strconv.Itoa(entry.IID), entry.Title, entry.Author, entry.State, prNumber, entry.PRURL, entry.Status, entry.Error}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv report: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write csv report: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write csv report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/git"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// SnippetsModeGist はsnippetをGitHubのgistとして移行する
+	SnippetsModeGist = "gist"
+	// SnippetsModeRepo はsnippetを移行先リポジトリの snippets/ ディレクトリにcommitする
+	SnippetsModeRepo = "repo"
+	// SnippetsModeNone はsnippetを移行しない
+	SnippetsModeNone = "none"
+
+	// snippetsBranch はsnippetをcommitするブランチ名
+	snippetsBranch = "gitlab-snippets"
+	// snippetsDir はsnippetをcommitするディレクトリ名
+	snippetsDir = "snippets"
+	// snippetsSummaryTitle は移行したsnippetの一覧を残すissueのタイトル
+	snippetsSummaryTitle = "Migrated GitLab snippets"
+)
+
+// unsafePathChars matches characters replaced when building snippet file paths
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// migratedSnippet holds the result of a single snippet migration
+type migratedSnippet struct {
+	Title  string
+	Author string
+	URL    string
+}
+
+// MigrateSnippets migrates GitLab project snippets to GitHub gists or a repository folder
+func MigrateSnippets(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, g *git.Git, cfg config.GlobalConfig, opts *MigrationOptions) error {
+	if opts.Snippets == "" || opts.Snippets == SnippetsModeNone {
+		return nil
+	}
+	if opts.Snippets != SnippetsModeGist && opts.Snippets != SnippetsModeRepo {
+		return fmt.Errorf("unknown snippets mode: %s", opts.Snippets)
+	}
+
+	// 再実行時にgistや一覧のissueが重複しないよう、一覧のissueがあれば移行済みとする
+	summary, err := githubClient.FindIssueByTitle(ctx, cfg.GitHubOwner, cfg.GitHubRepo, snippetsSummaryTitle)
+	if err != nil {
+		return fmt.Errorf("failed to find snippets summary: %w", err)
+	}
+	if summary != nil {
+		logger.FromContext(ctx).Info("Snippets already migrated, skipping", "summary", summary.GetHTMLURL())
+		return nil
+	}
+
+	snippets, err := gitlab.GetProjectSnippets(ctx, gitlabClient, cfg.GitLabProject)
+	if err != nil {
+		return err
+	}
+	if len(snippets) == 0 {
+		logger.FromContext(ctx).Info("No snippets to migrate")
+		return nil
+	}
+
+	var migrated []migratedSnippet
+	switch opts.Snippets {
+	case SnippetsModeGist:
+		migrated, err = migrateSnippetsAsGists(ctx, gitlabClient, githubClient, cfg, opts, snippets)
+	case SnippetsModeRepo:
+		migrated, err = migrateSnippetsToRepository(ctx, gitlabClient, g, cfg, opts, snippets)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 移行したsnippetの一覧をissueとして残す
+	body := "Snippets migrated from GitLab project " + cfg.GitLabProject + "\n\n"
+	for _, s := range migrated {
+		body += fmt.Sprintf("- [%s](%s) by %s\n", s.Title, s.URL, s.Author)
+	}
+	issue, err := githubClient.CreateIssue(ctx, cfg.GitHubOwner, cfg.GitHubRepo, snippetsSummaryTitle, body)
+	if err != nil {
+		return fmt.Errorf("failed to create snippets summary: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("Migrated snippets", "count", len(migrated), "mode", opts.Snippets, "summary", issue.GetHTMLURL())
+	return nil
+}
+
+// migrateSnippetsAsGists creates a secret gist for each snippet
+func migrateSnippetsAsGists(ctx context.Context, gitlabClient *gitlablib.Client, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, snippets []*gitlablib.Snippet) ([]migratedSnippet, error) {
+	var migrated []migratedSnippet
+	for _, snippet := range snippets {
+		files, err := gitlab.GetProjectSnippetFiles(ctx, gitlabClient, cfg.GitLabProject, snippet)
+		if err != nil {
+			return nil, err
+		}
+
+		gistFiles := make(map[string]string)
+		for _, file := range files {
+			// gistは空のファイルやディレクトリを含むファイル名を扱えない
+			if len(file.Content) == 0 {
+				continue
+			}
+			gistFiles[strings.ReplaceAll(file.Path, "/", "_")] = string(file.Content)
+		}
+		if len(gistFiles) == 0 {
+			logger.FromContext(ctx).Warn("Skipping empty snippet", "id", snippet.ID, "title", snippet.Title)
+			continue
+		}
+
+		author := formatSnippetAuthor(opts, snippet)
+		description := fmt.Sprintf("%s (migrated from %s, author %s)", snippet.Title, snippet.WebURL, author)
+		if snippet.Description != "" {
+			description = fmt.Sprintf("%s: %s", description, snippet.Description)
+		}
+		gist, err := githubClient.CreateGist(ctx, &github.GistOptions{
+			Description: description,
+			Public:      false,
+			Files:       gistFiles,
+		})
+		if err != nil {
+			return nil, err
+		}
+		migrated = append(migrated, migratedSnippet{Title: snippet.Title, Author: author, URL: gist.GetHTMLURL()})
+	}
+	return migrated, nil
+}
+
+// migrateSnippetsToRepository commits all snippets into the snippets folder on a dedicated branch
+func migrateSnippetsToRepository(ctx context.Context, gitlabClient *gitlablib.Client, g *git.Git, cfg config.GlobalConfig, opts *MigrationOptions, snippets []*gitlablib.Snippet) ([]migratedSnippet, error) {
+	currentBranch, err := g.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.CreateBranch(snippetsBranch, ""); err != nil {
+		return nil, err
+	}
+	// 後続のMR移行に影響しないよう、元のブランチに戻しておく
+	defer func() {
+		if err := g.Checkout(currentBranch); err != nil {
+			logger.FromContext(ctx).Warn("Failed to checkout original branch", "branch", currentBranch, "error", err)
+		}
+	}()
+
+	var migrated []migratedSnippet
+	for _, snippet := range snippets {
+		files, err := gitlab.GetProjectSnippetFiles(ctx, gitlabClient, cfg.GitLabProject, snippet)
+		if err != nil {
+			return nil, err
+		}
+
+		dir := path.Join(snippetsDir, fmt.Sprintf("%d-%s", snippet.ID, unsafePathChars.ReplaceAllString(snippet.Title, "-")))
+		author := formatSnippetAuthor(opts, snippet)
+		readme := fmt.Sprintf("# %s\n\n%s\n\n- Author: %s\n- Original: %s\n", snippet.Title, snippet.Description, author, snippet.WebURL)
+		if err := g.WriteFile(path.Join(dir, "README.md"), []byte(readme)); err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			filePath := path.Join(dir, unsafePathChars.ReplaceAllString(file.Path, "_"))
+			if err := g.WriteFile(filePath, file.Content); err != nil {
+				return nil, err
+			}
+		}
+
+		url := fmt.Sprintf("https://github.com/%s/%s/tree/%s/%s", cfg.GitHubOwner, cfg.GitHubRepo, snippetsBranch, dir)
+		migrated = append(migrated, migratedSnippet{Title: snippet.Title, Author: author, URL: url})
+	}
+
+	if err := g.Add(snippetsDir); err != nil {
+		return nil, err
+	}
+	if err := g.Commit("Add migrated GitLab snippets"); err != nil {
+		return nil, err
+	}
+	if err := g.PushBranchOrigins(snippetsBranch); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// formatSnippetAuthor renders the snippet author as a GitHub mention if mapped
+func formatSnippetAuthor(opts *MigrationOptions, snippet *gitlablib.Snippet) string {
+	if githubUser, ok := opts.UserMap.Lookup(snippet.Author.Username); ok {
+		return "@" + githubUser
+	}
+	return fmt.Sprintf("`%s`", snippet.Author.Username)
+}
@@ -0,0 +1,25 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/gitlab"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GitLabSource provides the GitLab data read during a merge request migration
+// ライブのGitLab API (gitlab.APISource) とexport済みのディレクトリ (gitlab.FileSource) のどちらからでも移行できるようにする
+type GitLabSource interface {
+	GetMergeRequests(ctx context.Context, page int) ([]*gitlablib.BasicMergeRequest, error)
+	GetMergeRequest(ctx context.Context, mrIID int) (*gitlablib.MergeRequest, error)
+	HasMergeRequestDiffs(ctx context.Context, mrIID int) (bool, error)
+	GetMergeRequestApprovals(ctx context.Context, mrIID int) ([]gitlab.ApprovalInfo, error)
+	GetMergeRequestDiscussions(ctx context.Context, mrIID, maxDiscussions int, order string) ([]*gitlablib.Discussion, error)
+	GetLatestMergeRequestVersionSHA(ctx context.Context, mrIID int, isAvailable func(sha string) bool) (string, error)
+}
+
+// GitLabクライアントの型が変わった場合にコンパイル時に検出できるよう、実装がinterfaceを満たすことを確認する
+var (
+	_ GitLabSource = (*gitlab.APISource)(nil)
+	_ GitLabSource = (*gitlab.FileSource)(nil)
+)
@@ -0,0 +1,192 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// StateSucceeded indicates the merge request was migrated
+	StateSucceeded = "succeeded"
+	// StateFailed indicates the migration of the merge request failed
+	StateFailed = "failed"
+)
+
+// MergeRequestState holds the persisted migration state of a merge request
+type MergeRequestState struct {
+	Status    string    `json:"status"`
+	PRNumber  int       `json:"pr_number,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultStateFile returns the default state file path of a migration
+// working dirは移行開始時に削除されるため、その外側に保存する
+func DefaultStateFile(gitlabProject, githubOwner, githubRepo string) string {
+	name := unsafePathChars.ReplaceAllString(fmt.Sprintf("%s_%s_%s", githubOwner, githubRepo, gitlabProject), "-")
+	return filepath.Join(".gitlab-2-github", "state", name+".json")
+}
+
+// StateStore persists the migration state of merge requests to a file
+// 複数のgoroutineから更新されても壊れないよう、mutexで保護し、一時ファイルからのrenameで書き換える
+type StateStore struct {
+	mu            sync.Mutex
+	path          string
+	mergeRequests map[int]*MergeRequestState
+}
+
+// stateFile is the JSON layout of the state file
+type stateFile struct {
+	MergeRequests map[int]*MergeRequestState `json:"merge_requests"`
+}
+
+// NewStateStore creates a state store backed by the file
+func NewStateStore(path string) *StateStore {
+	return &StateStore{
+		path:          path,
+		mergeRequests: make(map[int]*MergeRequestState),
+	}
+}
+
+// Load reads the state file, keeping the state empty if the file does not exist
+func (s *StateStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var state stateFile
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.MergeRequests != nil {
+		s.mergeRequests = state.MergeRequests
+	}
+	return nil
+}
+
+// Get returns the state of the merge request
+func (s *StateStore) Get(mrIID int) (MergeRequestState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.mergeRequests[mrIID]
+	if !ok {
+		return MergeRequestState{}, false
+	}
+	return *state, true
+}
+
+// Counts returns the number of succeeded and failed merge requests
+func (s *StateStore) Counts() (succeeded, failed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, state := range s.mergeRequests {
+		switch state.Status {
+		case StateSucceeded:
+			succeeded++
+		case StateFailed:
+			failed++
+		}
+	}
+	return succeeded, failed
+}
+
+// FailedMergeRequests returns the IIDs of the merge requests whose last migration failed, sorted
+func (s *StateStore) FailedMergeRequests() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var iids []int
+	for iid, state := range s.mergeRequests {
+		if state.Status == StateFailed {
+			iids = append(iids, iid)
+		}
+	}
+	slices.Sort(iids)
+	return iids
+}
+
+// MarkSucceeded records the merge request as migrated and saves the state file
+func (s *StateStore) MarkSucceeded(mrIID, prNumber int) error {
+	return s.mark(mrIID, &MergeRequestState{Status: StateSucceeded, PRNumber: prNumber})
+}
+
+// MarkFailed records the merge request as failed and saves the state file
+func (s *StateStore) MarkFailed(mrIID int, cause error) error {
+	state := &MergeRequestState{Status: StateFailed}
+	if cause != nil {
+		state.Error = cause.Error()
+	}
+	return s.mark(mrIID, state)
+}
+
+// Remove deletes the state of the merge requests and saves the state file
+func (s *StateStore) Remove(mrIIDs ...int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mrIID := range mrIIDs {
+		delete(s.mergeRequests, mrIID)
+	}
+	b, err := json.MarshalIndent(stateFile{MergeRequests: s.mergeRequests}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// mark updates the state of the merge request and saves the state file
+func (s *StateStore) mark(mrIID int, state *MergeRequestState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.UpdatedAt = time.Now()
+	s.mergeRequests[mrIID] = state
+	b, err := json.MarshalIndent(stateFile{MergeRequests: s.mergeRequests}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// writeFileAtomic writes the file through a temporary file and a rename so a crash never leaves it half written
+func writeFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package migration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStateStoreConcurrentMarks(t *testing.T) {
+	const (
+		workers = 16
+		perMR   = 20 // 1つのMRの状態を更新する回数
+	)
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+	store := NewStateStore(path)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(mrIID int) {
+			defer wg.Done()
+			for i := 0; i < perMR; i++ {
+				var err error
+				// 最後の更新の結果がMRごとに決まるよう、偶数のMRは成功、奇数のMRは失敗で終える
+				if (i+mrIID)%2 == perMR%2 {
+					err = store.MarkFailed(mrIID, errors.New("failed"))
+				} else {
+					err = store.MarkSucceeded(mrIID, mrIID*10)
+				}
+				if err != nil {
+					t.Errorf("mark MR %d: %v", mrIID, err)
+				}
+				// 書き込み中の読み込みも競合しないこと
+				store.Get(mrIID)
+				store.Counts()
+			}
+		}(worker + 1)
+	}
+	wg.Wait()
+
+	// 保存されたファイルが壊れておらず、すべてのMRの最後の状態を持つこと
+	loaded := NewStateStore(path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	succeeded, failed := loaded.Counts()
+	if succeeded != workers/2 || failed != workers/2 {
+		t.Errorf("Counts() = (%d, %d), want (%d, %d)", succeeded, failed, workers/2, workers/2)
+	}
+	for mrIID := 1; mrIID <= workers; mrIID++ {
+		state, ok := loaded.Get(mrIID)
+		if !ok {
+			t.Errorf("MR %d not saved", mrIID)
+			continue
+		}
+		wantStatus := StateSucceeded
+		if mrIID%2 == 1 {
+			wantStatus = StateFailed
+		}
+		if state.Status != wantStatus {
+			t.Errorf("MR %d status = %s, want %s", mrIID, state.Status, wantStatus)
+		}
+		if state.Status == StateSucceeded && state.PRNumber != mrIID*10 {
+			t.Errorf("MR %d PR number = %d, want %d", mrIID, state.PRNumber, mrIID*10)
+		}
+	}
+
+	// 一時ファイルが残っていないこと
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("files in the state directory = %v, want only the state file", names)
+	}
+}
+
+func TestStateStoreRemove(t *testing.T) {
+	tests := []struct {
+		name          string
+		remove        []int
+		wantRemaining []int
+	}{
+		{"removes the given merge requests", []int{1, 3}, []int{2}},
+		{"ignores merge requests without state", []int{4}, []int{1, 2, 3}},
+		{"removes nothing", nil, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			store := NewStateStore(path)
+			for mrIID := 1; mrIID <= 3; mrIID++ {
+				if err := store.MarkSucceeded(mrIID, mrIID*10); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.Remove(tt.remove...); err != nil {
+				t.Fatalf("Remove() error = %v", err)
+			}
+
+			// 削除した状態がファイルにも保存されていること
+			loaded := NewStateStore(path)
+			if err := loaded.Load(); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			remaining := map[int]bool{}
+			for _, mrIID := range tt.wantRemaining {
+				remaining[mrIID] = true
+			}
+			for mrIID := 1; mrIID <= 4; mrIID++ {
+				if _, ok := loaded.Get(mrIID); ok != remaining[mrIID] {
+					t.Errorf("MR %d saved = %v, want %v", mrIID, ok, remaining[mrIID])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"regexp"
+	"strconv"
+
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// suggestionPattern matches the opening fence of a GitLab suggestion such as "```suggestion:-1+2"
+var suggestionPattern = regexp.MustCompile("(?m)^(\\s*)```suggestion(?::-(\\d+)\\+(\\d+))?[ \\t]*$")
+
+// hasSuggestion checks if the note body contains a GitLab suggestion
+func hasSuggestion(body string) bool {
+	return suggestionPattern.MatchString(body)
+}
+
+// resolveSuggestionLineRanges resolves the line range a GitLab suggestion replaces on the new side of the diff
+// GitHubのsuggestionは変更後(RIGHT)の連続した1つの範囲にしか適用できないため、それ以外の場合はokをfalseとする
+func resolveSuggestionLineRanges(note *gitlablib.Note) (*int, *int, bool) {
+	if note.Position == nil || note.Position.NewLine == 0 {
+		return nil, nil, false
+	}
+	matches := suggestionPattern.FindAllStringSubmatch(note.Body, -1)
+	if len(matches) == 0 {
+		return nil, nil, false
+	}
+
+	var above, below int
+	for i, m := range matches {
+		a, _ := strconv.Atoi(m[2])
+		b, _ := strconv.Atoi(m[3])
+		if i > 0 && (a != above || b != below) {
+			// 範囲の異なる複数のsuggestionは1つのコメントで表現できない
+			return nil, nil, false
+		}
+		above, below = a, b
+	}
+
+	start := int(note.Position.NewLine) - above
+	last := int(note.Position.NewLine) + below
+	if start < 1 {
+		return nil, nil, false
+	}
+	return &start, &last, true
+}
+
+// formatSuggestions rewrites GitLab suggestion fences as GitHub suggestions, or as plain code blocks if not applicable
+func formatSuggestions(body string, applicable bool) string {
+	if applicable {
+		return suggestionPattern.ReplaceAllString(body, "${1}```suggestion")
+	}
+	return suggestionPattern.ReplaceAllString(body, "${1}```")
+}
@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"testing"
+
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestSuggestions(t *testing.T) {
+	const (
+		plain      = "Use a constant.\n```suggestion\nconst limit = 10\n```"
+		ranged     = "Merge these lines.\n```suggestion:-1+2\nfoo()\n```"
+		sameRanges = "```suggestion:-1+2\nfoo()\n```\nor\n```suggestion:-1+2\nbar()\n```"
+		mixed      = "```suggestion\nfoo()\n```\nor\n```suggestion:-1+2\nbar()\n```"
+	)
+	tests := []struct {
+		name      string
+		body      string
+		position  *gitlablib.NotePosition
+		wantOK    bool
+		wantStart int
+		wantLast  int
+		wantBody  string
+	}{
+		{
+			name:      "plain suggestion",
+			body:      plain,
+			position:  &gitlablib.NotePosition{NewPath: "main.go", NewLine: 5},
+			wantOK:    true,
+			wantStart: 5,
+			wantLast:  5,
+			wantBody:  plain,
+		},
+		{
+			name:      "suggestion with a range",
+			body:      ranged,
+			position:  &gitlablib.NotePosition{NewPath: "main.go", NewLine: 5},
+			wantOK:    true,
+			wantStart: 4,
+			wantLast:  7,
+			wantBody:  "Merge these lines.\n```suggestion\nfoo()\n```",
+		},
+		{
+			name:      "several suggestions with the same range",
+			body:      sameRanges,
+			position:  &gitlablib.NotePosition{NewPath: "main.go", NewLine: 5},
+			wantOK:    true,
+			wantStart: 4,
+			wantLast:  7,
+			wantBody:  "```suggestion\nfoo()\n```\nor\n```suggestion\nbar()\n```",
+		},
+		{
+			name:     "several suggestions with different ranges",
+			body:     mixed,
+			position: &gitlablib.NotePosition{NewPath: "main.go", NewLine: 5},
+			wantBody: "```\nfoo()\n```\nor\n```\nbar()\n```",
+		},
+		{
+			name:     "note on a removed line",
+			body:     plain,
+			position: &gitlablib.NotePosition{OldPath: "main.go", OldLine: 5},
+			wantBody: "Use a constant.\n```\nconst limit = 10\n```",
+		},
+		{
+			name:     "range starting above the first line",
+			body:     "```suggestion:-3+0\nfoo()\n```",
+			position: &gitlablib.NotePosition{NewPath: "main.go", NewLine: 2},
+			wantBody: "```\nfoo()\n```",
+		},
+		{
+			name:     "note without position",
+			body:     plain,
+			wantBody: "Use a constant.\n```\nconst limit = 10\n```",
+		},
+		{
+			name:     "no suggestion",
+			body:     "```go\nfoo()\n```",
+			position: &gitlablib.NotePosition{NewPath: "main.go", NewLine: 5},
+			wantBody: "```go\nfoo()\n```",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note := &gitlablib.Note{Body: tt.body, Position: tt.position}
+			start, last, ok := resolveSuggestionLineRanges(note)
+			if ok != tt.wantOK {
+				t.Fatalf("resolveSuggestionLineRanges() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (*start != tt.wantStart || *last != tt.wantLast) {
+				t.Errorf("resolveSuggestionLineRanges() = (%d, %d), want (%d, %d)", *start, *last, tt.wantStart, tt.wantLast)
+			}
+			if got := formatSuggestions(tt.body, ok); got != tt.wantBody {
+				t.Errorf("formatSuggestions() = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/teammap"
+)
+
+// groupMentionPattern matches a GitLab user or group mention such as `@backend-team` or `@group/subgroup`
+// メールアドレスやinline codeの中の "@" は除く
+var groupMentionPattern = regexp.MustCompile("(^|[^A-Za-z0-9_.`])@([A-Za-z0-9][A-Za-z0-9_.-]*(?:/[A-Za-z0-9][A-Za-z0-9_.-]*)*)")
+
+// applyTeamMentions rewrites mentions of GitLab groups in the team map to mentions of the GitHub teams
+// 対応の無いsubgroupへのメンション ("@group/subgroup") はGitHubの別のorgのteamへのメンションとなり得るため、inline codeとする
+// "/" を含まないメンションはユーザーとgroupを区別できないため、対応が無い場合はそのまま残す
+func applyTeamMentions(cfg config.GlobalConfig, teamMap teammap.TeamMap, body string) string {
+	if len(teamMap) == 0 {
+		return body
+	}
+	return mapLinesOutsideCodeFences(body, func(line string) (string, bool) {
+		return groupMentionPattern.ReplaceAllStringFunc(line, func(match string) string {
+			m := groupMentionPattern.FindStringSubmatch(match)
+			prefix, group := m[1], strings.TrimRight(m[2], ".")
+			suffix := strings.TrimPrefix(m[2], group)
+			if team, ok := teamMap.Lookup(group); ok {
+				return prefix + "@" + cfg.GitHubOwner + "/" + team + suffix
+			}
+			if strings.Contains(group, "/") {
+				return prefix + "`@" + group + "`" + suffix
+			}
+			return match
+		}), true
+	})
+}
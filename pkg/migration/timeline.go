@@ -0,0 +1,82 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	"github.com/krrrr38/gitlab-2-github/pkg/utils"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// timelineSummary is the summary of the collapsible block of the MR timeline comment
+const timelineSummary = "GitLab MR Timeline"
+
+// skippedSystemNotes returns the system notes of the discussions not migrated as comments, in chronological order
+// 先頭が無視されるsystemコメントのdiscussionと、discussion内のreplyとしてのsystemコメントが対象となる
+func skippedSystemNotes(opts *MigrationOptions, discussions []*gitlablib.Discussion) []*gitlablib.Note {
+	var notes []*gitlablib.Note
+	for _, discussion := range discussions {
+		if len(discussion.Notes) == 0 {
+			continue
+		}
+		headNote := discussion.Notes[0]
+		if headNote.System && !opts.IncludeSystemComments && isIgnoredSystemNote(headNote.Body) {
+			notes = append(notes, headNote)
+		}
+		for _, note := range discussion.Notes[1:] {
+			if note.System {
+				notes = append(notes, note)
+			}
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].CreatedAt == nil || notes[j].CreatedAt == nil {
+			return false
+		}
+		return notes[i].CreatedAt.Before(*notes[j].CreatedAt)
+	})
+	return notes
+}
+
+// formatTimelineEntry renders the system note as a list item of the MR timeline
+// 移行済みとして扱われるよう、各項目にnoteのmarkerを付与する
+func formatTimelineEntry(note *gitlablib.Note) string {
+	date := ""
+	if note.CreatedAt != nil {
+		date = note.CreatedAt.Format("2006-01-02 15:04:05 MST")
+	}
+	// 複数行のsystemコメント (追加されたcommitの一覧など) もリスト項目内に収まるようインデントする
+	body := strings.ReplaceAll(strings.TrimSpace(note.Body), "\n", "\n  ")
+	return fmt.Sprintf("- `%s` `%s` %s %s\n", date, note.Author.Username, body, formatNoteMarker(int(note.ID)))
+}
+
+// createTimelineComments creates the collapsible issue comments listing the system notes not migrated as comments
+// 上限を超える場合は複数のコメントに分けて作成する
+func createTimelineComments(ctx context.Context, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.MergeRequest, pr *githublib.PullRequest, discussions []*gitlablib.Discussion) error {
+	notes := skippedSystemNotes(opts, discussions)
+	if len(notes) == 0 {
+		return nil
+	}
+	entries := make([]string, 0, len(notes))
+	for _, note := range notes {
+		entries = append(entries, formatTimelineEntry(note))
+	}
+	// <details>などで増える分を差し引いた長さで分割する
+	limit := cfg.Limits.Comment - len(utils.WrapComment(timelineSummary, ""))
+	for _, chunk := range chunkReplyBodies(entries, limit) {
+		body := utils.WrapComment(timelineSummary, utils.TruncateTextWithOptions(strings.Join(chunk, ""), limit, cfg.Limits.Truncate))
+		comment, err := githubClient.CreateIssueComment(ctx, cfg.GitHubOwner, cfg.GitHubRepo, pr.GetNumber(), body, false)
+		if err != nil {
+			return fmt.Errorf("failed to create timeline comment: %w", err)
+		}
+		recordNotes(ctx, opts, int(mr.IID), comment.GetHTMLURL(), parseNoteMarkers(body)...)
+	}
+	logger.FromContext(ctx).Debug("Created MR timeline", "mr_id", mr.IID, "notes", len(notes))
+	return nil
+}
@@ -0,0 +1,171 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	githublib "github.com/google/go-github/v70/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/config"
+	"github.com/krrrr38/gitlab-2-github/pkg/github"
+	"github.com/krrrr38/gitlab-2-github/pkg/logger"
+	gitlablib "gitlab.com/gitlab-org/api/client-go"
+)
+
+// VerifyReport holds the result of comparing a GitLab project with the migrated GitHub repository
+type VerifyReport struct {
+	MergeRequests        int                          `json:"merge_requests"`         // 移行対象のMRの数
+	PullRequests         int                          `json:"pull_requests"`          // 移行済みのPRの数
+	MissingMergeRequests []int                        `json:"missing_merge_requests"` // PRが見つからないMRのIID
+	UnderMigrated        []*UnderMigratedMergeRequest `json:"under_migrated"`         // noteが不足しているMR
+}
+
+// UnderMigratedMergeRequest holds a merge request whose notes were not all migrated
+type UnderMigratedMergeRequest struct {
+	IID            int   `json:"iid"`
+	PRNumber       int   `json:"pr_number"`
+	ExpectedNotes  int   `json:"expected_notes"`
+	MigratedNotes  int   `json:"migrated_notes"`
+	MissingNoteIDs []int `json:"missing_note_ids"`
+}
+
+// Discrepancies returns the number of merge requests not fully migrated
+func (r *VerifyReport) Discrepancies() int {
+	return len(r.MissingMergeRequests) + len(r.UnderMigrated)
+}
+
+// WriteFile writes the report as JSON
+func (r *VerifyReport) WriteFile(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify report: %w", err)
+	}
+	if err := writeFileAtomic(path, b); err != nil {
+		return fmt.Errorf("failed to write verify report: %w", err)
+	}
+	return nil
+}
+
+// VerifyMigration compares GitLab merge requests and notes with the migrated GitHub pull requests and comments
+// GitHubやGitLabへの書き込みは行わない
+func VerifyMigration(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions) (*VerifyReport, error) {
+	report := &VerifyReport{MissingMergeRequests: []int{}, UnderMigrated: []*UnderMigratedMergeRequest{}}
+
+	closedPRs, err := githubClient.GetClosedPullRequests(ctx, cfg.GitHubOwner, cfg.GitHubRepo)
+	if err != nil {
+		return nil, err
+	}
+	migratedPRs := make(map[int]*githublib.PullRequest)
+	for _, pr := range closedPRs {
+		if _, owned := parseOwnedMRIID(opts, pr); !owned {
+			continue
+		}
+		if mrIID, ok := parseMigratedMRIID(opts, pr); ok {
+			migratedPRs[mrIID] = pr
+		}
+	}
+	report.PullRequests = len(migratedPRs)
+
+	for page := 1; ; page++ {
+		mrs, err := source.GetMergeRequests(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get merge requests: %w", err)
+		}
+		if len(mrs) == 0 {
+			break
+		}
+		for _, mr := range mrs {
+			// --mr-statesに含まれない状態のMRは移行対象外
+			if !opts.migratesState(mr.State) {
+				continue
+			}
+			report.MergeRequests++
+
+			pr, ok := migratedPRs[int(mr.IID)]
+			if !ok {
+				logger.FromContext(ctx).Warn("MR is not migrated", "id", mr.IID, "title", mr.Title)
+				report.MissingMergeRequests = append(report.MissingMergeRequests, int(mr.IID))
+				continue
+			}
+			underMigrated, err := verifyMergeRequestNotes(ctx, source, githubClient, cfg, opts, mr, pr)
+			if err != nil {
+				return nil, err
+			}
+			if underMigrated != nil {
+				logger.FromContext(ctx).Warn("MR is under-migrated",
+					"id", mr.IID,
+					"pr", pr.GetNumber(),
+					"expected_notes", underMigrated.ExpectedNotes,
+					"migrated_notes", underMigrated.MigratedNotes)
+				report.UnderMigrated = append(report.UnderMigrated, underMigrated)
+			}
+		}
+	}
+
+	logger.FromContext(ctx).Info("Verification completed",
+		"merge_requests", report.MergeRequests,
+		"pull_requests", report.PullRequests,
+		"missing", len(report.MissingMergeRequests),
+		"under_migrated", len(report.UnderMigrated))
+	return report, nil
+}
+
+// verifyMergeRequestNotes compares the migratable notes of the merge request with the note markers of the PR comments
+// すべてのnoteが移行されている場合はnilを返す
+func verifyMergeRequestNotes(ctx context.Context, source GitLabSource, githubClient *github.Client, cfg config.GlobalConfig, opts *MigrationOptions, mr *gitlablib.BasicMergeRequest, pr *githublib.PullRequest) (*UnderMigratedMergeRequest, error) {
+	discussions, err := source.GetMergeRequestDiscussions(ctx, int(mr.IID), opts.MaxDiscussions, opts.DiscussionOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discussions: %w on mr.IID=%d", err, mr.IID)
+	}
+	migrated, err := migratedNoteIDs(ctx, githubClient, cfg, pr.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UnderMigratedMergeRequest{IID: int(mr.IID), PRNumber: pr.GetNumber(), MissingNoteIDs: []int{}}
+	for _, discussion := range discussions {
+		discussion, _ := filterDiscussionSince(opts, discussion)
+		if discussion == nil {
+			continue
+		}
+		discussion, _ = filterDiscussion(opts, discussion)
+		if discussion == nil {
+			continue
+		}
+		for _, noteID := range migratableNoteIDs(opts, discussion) {
+			result.ExpectedNotes++
+			if migrated[noteID] {
+				result.MigratedNotes++
+			} else {
+				result.MissingNoteIDs = append(result.MissingNoteIDs, noteID)
+			}
+		}
+	}
+	if len(result.MissingNoteIDs) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// migratableNoteIDs returns the IDs of the notes createGitHubDiscussion migrates as comments
+func migratableNoteIDs(opts *MigrationOptions, discussion *gitlablib.Discussion) []int {
+	if len(discussion.Notes) == 0 {
+		return nil
+	}
+	headNote := discussion.Notes[0]
+	if headNote.System {
+		if !opts.IncludeSystemComments && isIgnoredSystemNote(headNote.Body) {
+			return nil
+		}
+		return []int{int(headNote.ID)}
+	}
+
+	noteIDs := []int{int(headNote.ID)}
+	for _, note := range discussion.Notes[1:] {
+		if note.System {
+			continue
+		}
+		noteIDs = append(noteIDs, int(note.ID))
+	}
+	return noteIDs
+}
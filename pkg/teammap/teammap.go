@@ -0,0 +1,76 @@
+package teammap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TeamMap maps GitLab group paths to GitHub team slugs
+type TeamMap map[string]string
+
+// Load reads a team map CSV file
+// 各行は "gitlab_group,github_team" の形式とし、空行や "#" で始まる行は無視する
+// GitHubのteamは "org/team" 形式でも指定でき、orgの部分は無視する
+func Load(path string) (TeamMap, error) {
+	m := TeamMap{}
+	if path == "" {
+		return m, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open team map: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read team map: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid team map line: %v", record)
+		}
+		gitlabGroup := strings.TrimPrefix(strings.TrimSpace(record[0]), "@")
+		githubTeam := strings.TrimPrefix(strings.TrimSpace(record[1]), "@")
+		if _, slug, ok := strings.Cut(githubTeam, "/"); ok {
+			githubTeam = slug
+		}
+		if gitlabGroup == "" || githubTeam == "" {
+			continue
+		}
+		m[gitlabGroup] = githubTeam
+	}
+	return m, nil
+}
+
+// Lookup returns the GitHub team slug mapped to the GitLab group path
+func (m TeamMap) Lookup(gitlabGroup string) (string, bool) {
+	githubTeam, ok := m[gitlabGroup]
+	return githubTeam, ok
+}
+
+// Teams returns the mapped GitHub team slugs without duplicates, sorted
+func (m TeamMap) Teams() []string {
+	seen := map[string]bool{}
+	var teams []string
+	for _, team := range m {
+		if !seen[team] {
+			seen[team] = true
+			teams = append(teams, team)
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
@@ -0,0 +1,126 @@
+package usermap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// MatchedByEmail is a match of the same email, which is considered certain
+	MatchedByEmail = "email"
+	// MatchedByUsername is a match of the same GitLab username and GitHub login
+	MatchedByUsername = "username"
+	// MatchedByName is a match of the same display name
+	MatchedByName = "name"
+)
+
+// Member is a GitLab or GitHub user considered when generating a user map
+type Member struct {
+	Username string // GitLabのユーザー名もしくはGitHubのlogin
+	Name     string
+	Email    string
+}
+
+// Match is a guessed GitHub login of a GitLab user
+type Match struct {
+	GitLab    Member
+	GitHub    string // 見つからない場合は空
+	MatchedBy string // 一致した項目 (email, username, name)
+	Note      string // 確認が必要な理由
+}
+
+// Uncertain checks if the match should be reviewed by hand
+func (m Match) Uncertain() bool {
+	return m.GitHub == "" || m.MatchedBy != MatchedByEmail
+}
+
+// Generate guesses the GitHub login of each GitLab member by email, username and then display name
+// 同じ名前のGitHubユーザーが複数いる場合は、誤って割り当てないよう一致なしとする
+func Generate(gitlabMembers, githubMembers []Member) []Match {
+	byEmail := map[string][]string{}
+	byLogin := map[string][]string{}
+	byName := map[string][]string{}
+	for _, member := range githubMembers {
+		if email := normalize(member.Email); email != "" {
+			byEmail[email] = append(byEmail[email], member.Username)
+		}
+		byLogin[normalize(member.Username)] = append(byLogin[normalize(member.Username)], member.Username)
+		if name := normalize(member.Name); name != "" {
+			byName[name] = append(byName[name], member.Username)
+		}
+	}
+
+	matches := make([]Match, 0, len(gitlabMembers))
+	for _, member := range gitlabMembers {
+		match := Match{GitLab: member}
+		candidates := []struct {
+			matchedBy string
+			logins    []string
+		}{
+			{MatchedByEmail, byEmail[normalize(member.Email)]},
+			{MatchedByUsername, byLogin[normalize(member.Username)]},
+			{MatchedByName, byName[normalize(member.Name)]},
+		}
+		for _, c := range candidates {
+			if len(c.logins) == 1 {
+				match.GitHub = c.logins[0]
+				match.MatchedBy = c.matchedBy
+				break
+			}
+			if len(c.logins) > 1 && match.Note == "" {
+				match.Note = fmt.Sprintf("ambiguous %s: %s", c.matchedBy, strings.Join(c.logins, " "))
+			}
+		}
+		if match.GitHub != "" && match.MatchedBy != MatchedByEmail {
+			match.Note = "matched by " + match.MatchedBy
+		} else if match.GitHub == "" && match.Note == "" {
+			match.Note = "no match"
+		}
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].GitLab.Username < matches[j].GitLab.Username
+	})
+	return matches
+}
+
+// WriteFile writes the matches as a user map CSV
+// 確認が必要な行は3列目に "review" を付与し、一致しなかったユーザーはコメントとして残す
+func WriteFile(path string, matches []Match) error {
+	var b bytes.Buffer
+	b.WriteString("# gitlab_username,github_login,review\n")
+	b.WriteString("# Generated from GitLab project members and GitHub organization members.\n")
+	b.WriteString("# Check the lines marked \"review\" and fill in the commented out users before the migration.\n")
+	w := csv.NewWriter(&b)
+	for _, match := range matches {
+		if match.GitHub == "" {
+			w.Flush()
+			fmt.Fprintf(&b, "# %s,,%s\n", match.GitLab.Username, match.Note)
+			continue
+		}
+		record := []string{match.GitLab.Username, match.GitHub}
+		if match.Uncertain() {
+			record = append(record, "review: "+match.Note)
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write user map: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write user map: %w", err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write user map: %w", err)
+	}
+	return nil
+}
+
+// normalize makes names and emails comparable ignoring case and surrounding spaces
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,65 @@
+package usermap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// UserMap maps GitLab usernames to GitHub logins
+type UserMap map[string]string
+
+// Load reads a user map CSV file
+// 各行は "gitlab_username,github_login" の形式とし、空行や "#" で始まる行は無視する
+func Load(path string) (UserMap, error) {
+	m := UserMap{}
+	if path == "" {
+		return m, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user map: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user map: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid user map line: %v", record)
+		}
+		gitlabUser := strings.TrimPrefix(strings.TrimSpace(record[0]), "@")
+		githubUser := strings.TrimPrefix(strings.TrimSpace(record[1]), "@")
+		if gitlabUser == "" || githubUser == "" {
+			continue
+		}
+		m[gitlabUser] = githubUser
+	}
+	return m, nil
+}
+
+// Lookup returns the GitHub login mapped to the GitLab username
+func (m UserMap) Lookup(gitlabUsername string) (string, bool) {
+	githubUser, ok := m[gitlabUsername]
+	return githubUser, ok
+}
+
+// Resolve returns the GitHub login mapped to the GitLab username, or the GitLab username itself if unmapped
+func (m UserMap) Resolve(gitlabUsername string) string {
+	if githubUser, ok := m.Lookup(gitlabUsername); ok {
+		return githubUser
+	}
+	return gitlabUsername
+}
@@ -0,0 +1,71 @@
+package usertokens
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// UserTokens maps GitHub logins to the API tokens authenticating as the user
+type UserTokens map[string]string
+
+// Load reads a user token CSV file
+// 各行は "github_login,token_file" の形式とし、空行や "#" で始まる行は無視する
+// tokenがshellの履歴やプロセス一覧に残らないよう、CSVにはtokenそのものではなくtokenを記載したファイルのパスを指定する
+func Load(path string) (UserTokens, error) {
+	m := UserTokens{}
+	if path == "" {
+		return m, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user tokens: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user tokens: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid user tokens line: %v", record)
+		}
+		githubUser := strings.TrimPrefix(strings.TrimSpace(record[0]), "@")
+		tokenFile := strings.TrimSpace(record[1])
+		if githubUser == "" || tokenFile == "" {
+			continue
+		}
+		b, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file of %s: %w", githubUser, err)
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return nil, fmt.Errorf("token file of %s is empty: %s", githubUser, tokenFile)
+		}
+		m[githubUser] = token
+	}
+	return m, nil
+}
+
+// Users returns the GitHub logins with a token, sorted
+func (m UserTokens) Users() []string {
+	users := make([]string, 0, len(m))
+	for user := range m {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
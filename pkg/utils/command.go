@@ -1,17 +1,20 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"github.com/krrrr38/gitlab-2-github/pkg/logger"
 	"os"
 	"os/exec"
+	"time"
 )
 
-// ExecuteCommand executes a shell command
-func ExecuteCommand(cmd string) error {
-	logger.Debug("Executing command", "cmd", cmd)
+// ExecuteCommand executes a shell command, killing it when the context is done
+// 実行したコマンドは、contextのscoped loggerで出力する
+func ExecuteCommand(ctx context.Context, cmd string) error {
+	logger.FromContext(ctx).Debug("Executing command", "cmd", cmd)
 
-	c := exec.Command("bash", "-c", cmd)
+	c := exec.CommandContext(ctx, "bash", "-c", cmd)
 	output, err := c.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("command failed: %s\nOutput: %s", err, output)
@@ -19,11 +22,34 @@ func ExecuteCommand(cmd string) error {
 	return nil
 }
 
-// ExecuteCommandoutput executes a shell command
-func ExecuteCommandOutput(cmd string) (string, error) {
-	logger.Debug("Executing command with output", "cmd", cmd)
+// RetryCommand executes a shell command, retrying it with exponential backoff when it fails
+// attemptsは最初の実行を含めた実行回数で、失敗するごとに待機時間を倍にする
+func RetryCommand(ctx context.Context, cmd string, attempts int, initialDelay time.Duration) error {
+	delay := initialDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = ExecuteCommand(ctx, cmd); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		logger.FromContext(ctx).Warn("Command failed, retrying", "cmd", cmd, "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+// ExecuteCommandoutput executes a shell command, killing it when the context is done
+func ExecuteCommandOutput(ctx context.Context, cmd string) (string, error) {
+	logger.FromContext(ctx).Debug("Executing command with output", "cmd", cmd)
 
-	c := exec.Command("bash", "-c", cmd)
+	c := exec.CommandContext(ctx, "bash", "-c", cmd)
 	output, err := c.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("command failed: %s\nOutput: %s", err, output)
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // 成功するまでに失敗する回数
+		attempts     int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"succeeds first", 0, 3, false, 1},
+		{"succeeds on the last attempt", 2, 3, false, 3},
+		{"runs out of attempts", 5, 3, true, 3},
+		{"single attempt", 1, 1, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 実行回数をファイルに記録し、failures回目までは失敗するコマンド
+			counter := filepath.Join(t.TempDir(), "count")
+			cmd := fmt.Sprintf(`n=$(( $(cat %[1]s 2>/dev/null || echo 0) + 1 )); echo $n > %[1]s; echo "attempt $n"; [ $n -gt %[2]d ]`, counter, tt.failures)
+
+			err := RetryCommand(context.Background(), cmd, tt.attempts, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RetryCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// 再試行を使い切った場合は、最後の実行のエラーを返す
+			if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("attempt %d", tt.wantAttempts)) {
+				t.Errorf("RetryCommand() error = %v, want the error of attempt %d", err, tt.wantAttempts)
+			}
+			b, err := os.ReadFile(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(b)); got != fmt.Sprint(tt.wantAttempts) {
+				t.Errorf("attempts = %s, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryCommandCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := RetryCommand(ctx, "false", 3, time.Hour); err == nil {
+		t.Error("RetryCommand() error = nil, want an error for the canceled context")
+	}
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -11,27 +14,105 @@ const (
 	MaxPRTitleLength       = 256   // Pull Requestのタイトル最大長
 	MaxPRDescriptionLength = 65536 // Pull Requestの説明文最大長（64KB）
 	MaxCommentLength       = 65536 // コメントの最大長（64KB）
+	MinBodyLength          = 256   // 説明文やコメントに指定できる最大長の下限 (移行元の情報やmarkerを付与するため)
 
 	// 切り詰め表示用のサフィックス
 	TruncateSuffix = "... [truncated]"
 )
 
-// TruncateText は指定された最大長に基づいてテキストを切り詰めます
-func TruncateText(text string, maxLength int) string {
+// Limits はGitHubの各種テキスト長制限を保持する構造体
+type Limits struct {
+	PRTitle       int             // Pull Requestのタイトル最大長
+	PRDescription int             // Pull Requestの説明文最大長
+	Comment       int             // コメントの最大長
+	Truncate      TruncateOptions // 最大長を超えた場合の切り詰め方法
+}
+
+// DefaultLimits はGitHubのドキュメントに記載されているテキスト長制限を返します
+func DefaultLimits() Limits {
+	return Limits{
+		PRTitle:       MaxPRTitleLength,
+		PRDescription: MaxPRDescriptionLength,
+		Comment:       MaxCommentLength,
+		Truncate:      TruncateOptions{Suffix: TruncateSuffix},
+	}
+}
+
+// TruncateOptions はテキストの切り詰め方法を指定する構造体
+type TruncateOptions struct {
+	Suffix         string // 切り詰めた際に末尾に付与するサフィックス
+	AtBoundary     bool   // 最大長以前の最後の改行もしくは空白で切り詰める
+	CloseCodeFence bool   // 切り詰めによって閉じられていないコードブロックを閉じる
+}
+
+// TruncateTextWithOptions は指定された最大長と切り詰め設定に基づいてテキストを切り詰めます
+func TruncateTextWithOptions(text string, maxLength int, opts TruncateOptions) string {
+	if maxLength <= 0 {
+		return ""
+	}
 	if utf8.RuneCountInString(text) <= maxLength {
 		return text
 	}
 
-	// 最大長からサフィックス長を引いた長さまで切り詰める
-	availableLength := maxLength - utf8.RuneCountInString(TruncateSuffix)
+	// 最大長からサフィックス長(とコードブロックを閉じるための長さ)を引いた長さまで切り詰める
+	reserved := utf8.RuneCountInString(opts.Suffix)
+	if opts.CloseCodeFence {
+		reserved += utf8.RuneCountInString(codeFenceCloser)
+	}
+	availableLength := maxLength - reserved
+	runes := []rune(text)
 	if availableLength <= 0 {
 		// 極端に短い場合は単にmaxLengthまで切る
-		runes := []rune(text)
 		return string(runes[:maxLength])
 	}
 
-	runes := []rune(text)
-	return string(runes[:availableLength]) + TruncateSuffix
+	truncated := string(runes[:availableLength])
+	if opts.AtBoundary {
+		truncated = cutAtBoundary(truncated)
+	}
+	if opts.CloseCodeFence && hasOpenCodeFence(truncated) {
+		truncated += codeFenceCloser
+	}
+	return truncated + opts.Suffix
+}
+
+// Truncate はログ表示用に、文字単位で最大長まで切り詰めて "..." を付与します
+func Truncate(text string, maxLength int) string {
+	if maxLength <= 0 {
+		return "..."
+	}
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	return string([]rune(text)[:maxLength]) + "..."
+}
+
+// codeFenceCloser は閉じられていないコードブロックを閉じるための文字列
+const codeFenceCloser = "\n```\n"
+
+// cutAtBoundary はテキストを最後の改行、なければ最後の空白で切り詰めます
+func cutAtBoundary(text string) string {
+	// 境界が極端に手前にある場合は、内容を失いすぎないように境界での切り詰めは行わない
+	minLength := len(text) / 2
+	if i := strings.LastIndex(text, "\n"); i > minLength {
+		return text[:i+1]
+	}
+	if i := strings.LastIndexFunc(text, unicode.IsSpace); i > minLength {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		return text[:i+size]
+	}
+	return text
+}
+
+// hasOpenCodeFence はテキスト中に閉じられていないコードブロックがあるかを判定します
+func hasOpenCodeFence(text string) bool {
+	open := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			open = !open
+		}
+	}
+	return open
 }
 
 // WrapComment はコメントを適切にラップします
@@ -41,9 +122,17 @@ func WrapComment(summary, detail string) string {
 		summary, detail)
 }
 
-// WrapComment はコメントを適切にラップします
-func WrapCommentAsResolved(detail string) string {
+// WrapCommentAsResolved は解決済みのコメントを折りたたみ表示にラップします
+func WrapCommentAsResolved(detail string, maxLength int, opts TruncateOptions) string {
 	// GitHubではコメントを折りたたむための専用Markdownフォーマット
 	return fmt.Sprintf("<details><summary>Resolved</summary>\n\n%s\n</details>",
-		TruncateText(detail, MaxCommentLength-35))
+		TruncateTextWithOptions(detail, maxLength-35, opts))
+}
+
+// mentionPattern は "@username" 形式のメンションにマッチします (メールアドレスなどの単語途中の "@" は除く)
+var mentionPattern = regexp.MustCompile(`(^|[^A-Za-z0-9_.\x60])@([A-Za-z0-9][A-Za-z0-9-]*)`)
+
+// SuppressMentions はメンションによる通知が発生しないよう、"@" の直後にゼロ幅スペースを挿入します
+func SuppressMentions(text string) string {
+	return mentionPattern.ReplaceAllString(text, "${1}@\u200b${2}")
 }
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"shorter than the limit", "Fix login", 20, "Fix login"},
+		{"exactly the limit", "Fix login", 9, "Fix login"},
+		{"ascii title", "Fix login page", 9, "Fix login..."},
+		{"japanese title", "ログイン画面の修正を行う", 6, "ログイン画面..."},
+		{"japanese title within the limit", "ログイン画面", 6, "ログイン画面"},
+		{"emoji title", "🐛🐛🐛 Fix crash", 2, "🐛🐛..."},
+		{"mixed title", "Fix ログイン", 6, "Fix ログ..."},
+		{"zero limit", "ログイン画面", 0, "..."},
+		{"negative limit", "ログイン画面", -1, "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.text, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+			}
+			// バイト単位で切り詰めると、マルチバイト文字の途中で切れて不正なUTF-8になる
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) = %q, not valid UTF-8", tt.text, tt.maxLength, got)
+			}
+		})
+	}
+}